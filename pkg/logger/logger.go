@@ -0,0 +1,58 @@
+// Package logger defines a small logging interface used by "rest" and "ns" packages,
+// so the library doesn't depend on a particular logging library.
+//
+// Adapters are provided for logrus (NewLogrus), log/slog (NewSlog, Go 1.21+)
+// and zap (NewZap), Noop() logger is used if no logger is set:
+//
+//	nsProvider, err := ns.NewProvider(ns.ProviderArgs{
+//		Address: "https://10.3.199.252:8443",
+//		Log:     logger.NewLogrus(logrus.New().WithField("cmp", "csi-driver")),
+//	})
+package logger
+
+// Fields - structured log fields
+type Fields map[string]interface{}
+
+// Logger - leveled logger with structured fields
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+
+	WithField(key string, value interface{}) Logger
+	WithFields(fields Fields) Logger
+}
+
+// debugEnabler is implemented by loggers which can report if debug level is enabled,
+// it allows to skip building of expensive debug messages
+type debugEnabler interface {
+	IsDebugEnabled() bool
+}
+
+// IsDebugEnabled checks if the logger writes debug messages,
+// true is returned if the logger can't report its level
+func IsDebugEnabled(l Logger) bool {
+	if e, ok := l.(debugEnabler); ok {
+		return e.IsDebugEnabled()
+	}
+	return true
+}
+
+// noop - logger that discards all messages
+type noop struct{}
+
+// Noop returns a logger that discards all messages
+func Noop() Logger {
+	return noop{}
+}
+
+func (noop) Debugf(format string, args ...interface{}) {}
+func (noop) Infof(format string, args ...interface{})  {}
+func (noop) Warnf(format string, args ...interface{})  {}
+func (noop) Errorf(format string, args ...interface{}) {}
+
+func (l noop) WithField(key string, value interface{}) Logger { return l }
+func (l noop) WithFields(fields Fields) Logger                { return l }
+
+func (noop) IsDebugEnabled() bool { return false }
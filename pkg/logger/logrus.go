@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// logrusLogger - adapter for logrus
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+// NewLogrus returns a logger that writes to logrus entry or logger (*logrus.Entry or *logrus.Logger)
+func NewLogrus(l logrus.FieldLogger) Logger {
+	switch v := l.(type) {
+	case *logrus.Entry:
+		return logrusLogger{v}
+	case *logrus.Logger:
+		return logrusLogger{logrus.NewEntry(v)}
+	case nil:
+		return Noop()
+	}
+	return logrusLogger{l.WithFields(logrus.Fields{})}
+}
+
+func (l logrusLogger) Debugf(format string, args ...interface{}) {
+	l.entry.Debugf(format, args...)
+}
+
+func (l logrusLogger) Infof(format string, args ...interface{}) {
+	l.entry.Infof(format, args...)
+}
+
+func (l logrusLogger) Warnf(format string, args ...interface{}) {
+	l.entry.Warnf(format, args...)
+}
+
+func (l logrusLogger) Errorf(format string, args ...interface{}) {
+	l.entry.Errorf(format, args...)
+}
+
+func (l logrusLogger) WithField(key string, value interface{}) Logger {
+	return logrusLogger{l.entry.WithField(key, value)}
+}
+
+func (l logrusLogger) WithFields(fields Fields) Logger {
+	return logrusLogger{l.entry.WithFields(logrus.Fields(fields))}
+}
+
+func (l logrusLogger) IsDebugEnabled() bool {
+	return l.entry.Logger.IsLevelEnabled(logrus.DebugLevel)
+}
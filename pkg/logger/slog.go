@@ -0,0 +1,62 @@
+//go:build go1.21
+// +build go1.21
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// slogLogger - adapter for log/slog
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlog returns a logger that writes to slog logger, slog.Default() is used if nil
+func NewSlog(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return slogLogger{l}
+}
+
+func (l slogLogger) Debugf(format string, args ...interface{}) {
+	l.log(slog.LevelDebug, format, args...)
+}
+
+func (l slogLogger) Infof(format string, args ...interface{}) {
+	l.log(slog.LevelInfo, format, args...)
+}
+
+func (l slogLogger) Warnf(format string, args ...interface{}) {
+	l.log(slog.LevelWarn, format, args...)
+}
+
+func (l slogLogger) Errorf(format string, args ...interface{}) {
+	l.log(slog.LevelError, format, args...)
+}
+
+func (l slogLogger) log(level slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	if l.logger.Enabled(ctx, level) {
+		l.logger.Log(ctx, level, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l slogLogger) WithField(key string, value interface{}) Logger {
+	return slogLogger{l.logger.With(key, value)}
+}
+
+func (l slogLogger) WithFields(fields Fields) Logger {
+	args := make([]interface{}, 0, 2*len(fields))
+	for key, value := range fields {
+		args = append(args, key, value)
+	}
+	return slogLogger{l.logger.With(args...)}
+}
+
+func (l slogLogger) IsDebugEnabled() bool {
+	return l.logger.Enabled(context.Background(), slog.LevelDebug)
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// zapDebugLevel - value of zapcore.DebugLevel
+const zapDebugLevel = -1
+
+// ZapSugaredLogger - subset of *zap.SugaredLogger methods used by the adapter,
+// so the library doesn't depend on zap
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// zapLogger - adapter for zap, fields are passed as key-value pairs
+type zapLogger struct {
+	sugared       ZapSugaredLogger
+	keysAndValues []interface{}
+}
+
+// NewZap returns a logger that writes to zap sugared logger, no-op logger is returned if nil:
+//
+//	log := logger.NewZap(zapLogger.Sugar())
+func NewZap(l ZapSugaredLogger) Logger {
+	if l == nil {
+		return Noop()
+	}
+	// nil *zap.SugaredLogger passed as the interface is not equal to nil
+	if v := reflect.ValueOf(l); v.Kind() == reflect.Ptr && v.IsNil() {
+		return Noop()
+	}
+	return zapLogger{sugared: l}
+}
+
+func (l zapLogger) Debugf(format string, args ...interface{}) {
+	l.sugared.Debugw(fmt.Sprintf(format, args...), l.keysAndValues...)
+}
+
+func (l zapLogger) Infof(format string, args ...interface{}) {
+	l.sugared.Infow(fmt.Sprintf(format, args...), l.keysAndValues...)
+}
+
+func (l zapLogger) Warnf(format string, args ...interface{}) {
+	l.sugared.Warnw(fmt.Sprintf(format, args...), l.keysAndValues...)
+}
+
+func (l zapLogger) Errorf(format string, args ...interface{}) {
+	l.sugared.Errorw(fmt.Sprintf(format, args...), l.keysAndValues...)
+}
+
+func (l zapLogger) WithField(key string, value interface{}) Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+func (l zapLogger) WithFields(fields Fields) Logger {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	keysAndValues := make([]interface{}, len(l.keysAndValues), len(l.keysAndValues)+2*len(fields))
+	copy(keysAndValues, l.keysAndValues)
+	for _, key := range keys {
+		keysAndValues = append(keysAndValues, key, fields[key])
+	}
+
+	return zapLogger{sugared: l.sugared, keysAndValues: keysAndValues}
+}
+
+// IsDebugEnabled checks level of the logger, *zap.SugaredLogger reports it by Level() method
+// (zap v1.24+), the level type is zapcore.Level, so the method is called by reflection.
+// True is returned if the logger doesn't report its level.
+func (l zapLogger) IsDebugEnabled() bool {
+	method := reflect.ValueOf(l.sugared).MethodByName("Level")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return true
+	}
+
+	switch level := method.Call(nil)[0]; level.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return level.Int() <= zapDebugLevel
+	default:
+		return true
+	}
+}
@@ -0,0 +1,486 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ACEType - NFSv4 ACL entry type
+type ACEType string
+
+const (
+	// ACETypeAllow - entry grants permissions
+	ACETypeAllow ACEType = "allow"
+
+	// ACETypeDeny - entry denies permissions
+	ACETypeDeny ACEType = "deny"
+)
+
+// ACLPrincipal - user or group the ACL entry applies to, see ACLUser(), ACLGroup() and special principals
+type ACLPrincipal string
+
+const (
+	// ACLPrincipalOwner - owner of the file
+	ACLPrincipalOwner ACLPrincipal = "owner@"
+
+	// ACLPrincipalGroup - owning group of the file
+	ACLPrincipalGroup ACLPrincipal = "group@"
+
+	// ACLPrincipalEveryone - all users, including owner and group
+	ACLPrincipalEveryone ACLPrincipal = "everyone@"
+)
+
+// ACLUser returns principal for a user name or uid
+func ACLUser(name string) ACLPrincipal {
+	return ACLPrincipal("user:" + name)
+}
+
+// ACLGroup returns principal for a group name or gid
+func ACLGroup(name string) ACLPrincipal {
+	return ACLPrincipal("group:" + name)
+}
+
+// ACLPermission - NFSv4 ACL permission or a set of permissions
+type ACLPermission string
+
+// NFSv4 ACL permissions
+const (
+	ACLPermissionReadData        ACLPermission = "read_data"
+	ACLPermissionWriteData       ACLPermission = "write_data"
+	ACLPermissionAppendData      ACLPermission = "append_data"
+	ACLPermissionReadXattr       ACLPermission = "read_xattr"
+	ACLPermissionWriteXattr      ACLPermission = "write_xattr"
+	ACLPermissionExecute         ACLPermission = "execute"
+	ACLPermissionDeleteChild     ACLPermission = "delete_child"
+	ACLPermissionReadAttributes  ACLPermission = "read_attributes"
+	ACLPermissionWriteAttributes ACLPermission = "write_attributes"
+	ACLPermissionDelete          ACLPermission = "delete"
+	ACLPermissionReadACL         ACLPermission = "read_acl"
+	ACLPermissionWriteACL        ACLPermission = "write_acl"
+	ACLPermissionWriteOwner      ACLPermission = "write_owner"
+	ACLPermissionSynchronize     ACLPermission = "synchronize"
+
+	// ACLPermissionFullSet - all permissions
+	ACLPermissionFullSet ACLPermission = "full_set"
+
+	// ACLPermissionModifySet - all permissions except write_acl and write_owner
+	ACLPermissionModifySet ACLPermission = "modify_set"
+
+	// ACLPermissionReadSet - read_data, read_xattr, read_attributes and read_acl
+	ACLPermissionReadSet ACLPermission = "read_set"
+
+	// ACLPermissionWriteSet - write_data, append_data, write_xattr and write_attributes
+	ACLPermissionWriteSet ACLPermission = "write_set"
+)
+
+var aclPermissions = []ACLPermission{
+	ACLPermissionReadData,
+	ACLPermissionWriteData,
+	ACLPermissionAppendData,
+	ACLPermissionReadXattr,
+	ACLPermissionWriteXattr,
+	ACLPermissionExecute,
+	ACLPermissionDeleteChild,
+	ACLPermissionReadAttributes,
+	ACLPermissionWriteAttributes,
+	ACLPermissionDelete,
+	ACLPermissionReadACL,
+	ACLPermissionWriteACL,
+	ACLPermissionWriteOwner,
+	ACLPermissionSynchronize,
+	ACLPermissionFullSet,
+	ACLPermissionModifySet,
+	ACLPermissionReadSet,
+	ACLPermissionWriteSet,
+}
+
+// ACLFlag - NFSv4 ACL entry inheritance flag
+type ACLFlag string
+
+const (
+	// ACLFlagFileInherit - entry is inherited by new files
+	ACLFlagFileInherit ACLFlag = "file_inherit"
+
+	// ACLFlagDirInherit - entry is inherited by new directories
+	ACLFlagDirInherit ACLFlag = "dir_inherit"
+
+	// ACLFlagInheritOnly - entry is used for inheritance only and doesn't apply to the directory itself
+	ACLFlagInheritOnly ACLFlag = "inherit_only"
+
+	// ACLFlagNoPropagate - entry is inherited by direct children only
+	ACLFlagNoPropagate ACLFlag = "no_propagate"
+
+	// ACLFlagInherited - entry was inherited from the parent directory, set by NexentaStor
+	ACLFlagInherited ACLFlag = "inherited"
+)
+
+var aclFlags = []ACLFlag{
+	ACLFlagFileInherit,
+	ACLFlagDirInherit,
+	ACLFlagInheritOnly,
+	ACLFlagNoPropagate,
+	ACLFlagInherited,
+}
+
+// ACE - NFSv4 ACL entry of a filesystem, entries are evaluated in order
+type ACE struct {
+	// Index - position of the entry in ACL, set by GetFilesystemACL()
+	Index int `json:"index"`
+
+	Type        ACEType         `json:"type"`
+	Principal   ACLPrincipal    `json:"principal"`
+	Permissions []ACLPermission `json:"permissions"`
+	Flags       []ACLFlag       `json:"flags"`
+}
+
+func (ace ACE) String() string {
+	permissions := make([]string, len(ace.Permissions))
+	for i, permission := range ace.Permissions {
+		permissions[i] = string(permission)
+	}
+	flags := make([]string, len(ace.Flags))
+	for i, flag := range ace.Flags {
+		flags[i] = string(flag)
+	}
+	return fmt.Sprintf("%s:%s:%s:%s", ace.Principal, strings.Join(permissions, "/"), strings.Join(flags, "/"), ace.Type)
+}
+
+// Equal reports whether entries grant or deny the same permissions to the same principal,
+// Index and order of permissions and flags are ignored
+func (ace ACE) Equal(other ACE) bool {
+	return ace.Type == other.Type &&
+		ace.Principal == other.Principal &&
+		reflect.DeepEqual(sortedACLPermissions(ace.Permissions), sortedACLPermissions(other.Permissions)) &&
+		reflect.DeepEqual(sortedACLFlags(ace.Flags), sortedACLFlags(other.Flags))
+}
+
+func (ace ACE) hasFlag(flag ACLFlag) bool {
+	for _, f := range ace.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedACLPermissions(permissions []ACLPermission) []ACLPermission {
+	sorted := append([]ACLPermission{}, permissions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+func sortedACLFlags(flags []ACLFlag) []ACLFlag {
+	sorted := append([]ACLFlag{}, flags...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// validate checks ACL entry before sending it to NexentaStor
+func (ace ACE) validate(field string) error {
+	if ace.Type != ACETypeAllow && ace.Type != ACETypeDeny {
+		return newBadArgError("%s.Type must be one of '%s', '%s', got: '%s'", field, ACETypeAllow, ACETypeDeny, ace.Type)
+	}
+
+	switch principal := string(ace.Principal); {
+	case ace.Principal == ACLPrincipalOwner, ace.Principal == ACLPrincipalGroup, ace.Principal == ACLPrincipalEveryone:
+	case strings.HasPrefix(principal, "user:") && len(principal) > len("user:"):
+	case strings.HasPrefix(principal, "group:") && len(principal) > len("group:"):
+	default:
+		return newBadArgError(
+			"%s.Principal must be 'user:NAME', 'group:NAME', '%s', '%s' or '%s', got: '%s'",
+			field,
+			ACLPrincipalOwner,
+			ACLPrincipalGroup,
+			ACLPrincipalEveryone,
+			ace.Principal,
+		)
+	}
+
+	if len(ace.Permissions) == 0 {
+		return newBadArgError("%s.Permissions must not be empty", field)
+	}
+	for _, permission := range ace.Permissions {
+		if !containsACLPermission(aclPermissions, permission) {
+			return newBadArgError("%s.Permissions contains unknown permission '%s'", field, permission)
+		}
+	}
+
+	for _, flag := range ace.Flags {
+		if !containsACLFlag(aclFlags, flag) {
+			return newBadArgError("%s.Flags contains unknown flag '%s'", field, flag)
+		}
+	}
+
+	return nil
+}
+
+func containsACLPermission(permissions []ACLPermission, permission ACLPermission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func containsACLFlag(flags []ACLFlag, flag ACLFlag) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFilesystemACL returns ACL entries of the filesystem root directory in evaluation order
+func (p *Provider) GetFilesystemACL(ctx context.Context, path string) ([]ACE, error) {
+	if path == "" {
+		return nil, fmt.Errorf("Filesystem path is required")
+	}
+
+	uri := fmt.Sprintf("storage/filesystems/%s/acl", url.PathEscape(path))
+
+	response := nefStorageFilesystemsACLResponse{}
+	if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
+
+// AddFilesystemACE appends the entry to filesystem ACL
+func (p *Provider) AddFilesystemACE(ctx context.Context, path string, ace ACE) error {
+	return p.addFilesystemACE(ctx, path, nil, ace)
+}
+
+// InsertFilesystemACE inserts the entry to filesystem ACL at the index, following entries are shifted
+func (p *Provider) InsertFilesystemACE(ctx context.Context, path string, index int, ace ACE) error {
+	if index < 0 {
+		return newBadArgError("ACL entry index must not be negative, got: %d", index)
+	}
+	return p.addFilesystemACE(ctx, path, &index, ace)
+}
+
+func (p *Provider) addFilesystemACE(ctx context.Context, path string, index *int, ace ACE) error {
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	} else if err := ace.validate("ACE"); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("storage/filesystems/%s/acl", url.PathEscape(path))
+	data := newNefStorageFilesystemsACLRequest(ace)
+	data.Index = index
+
+	return p.sendRequest(ctx, http.MethodPost, uri, data)
+}
+
+// ReplaceFilesystemACE replaces filesystem ACL entry at the index
+func (p *Provider) ReplaceFilesystemACE(ctx context.Context, path string, index int, ace ACE) error {
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	} else if index < 0 {
+		return newBadArgError("ACL entry index must not be negative, got: %d", index)
+	} else if err := ace.validate("ACE"); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("storage/filesystems/%s/acl/%d", url.PathEscape(path), index)
+
+	return p.sendRequest(ctx, http.MethodPut, uri, newNefStorageFilesystemsACLRequest(ace))
+}
+
+// RemoveFilesystemACE removes filesystem ACL entry at the index, following entries are shifted
+func (p *Provider) RemoveFilesystemACE(ctx context.Context, path string, index int) error {
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	} else if index < 0 {
+		return newBadArgError("ACL entry index must not be negative, got: %d", index)
+	}
+
+	uri := fmt.Sprintf("storage/filesystems/%s/acl/%d", url.PathEscape(path), index)
+
+	return p.sendRequest(ctx, http.MethodDelete, uri, nil)
+}
+
+func newNefStorageFilesystemsACLRequest(ace ACE) *nefStorageFilesystemsACLRequest {
+	data := &nefStorageFilesystemsACLRequest{
+		Type:        string(ace.Type),
+		Principal:   string(ace.Principal),
+		Flags:       []string{},
+		Permissions: []string{},
+	}
+	for _, flag := range ace.Flags {
+		data.Flags = append(data.Flags, string(flag))
+	}
+	for _, permission := range ace.Permissions {
+		data.Permissions = append(data.Permissions, string(permission))
+	}
+	return data
+}
+
+// ACLDiff - changes to turn actual ACL into desired one
+type ACLDiff struct {
+	// Remove - entries of actual ACL to remove, Index is a position in actual ACL
+	Remove []ACE
+
+	// Add - entries of desired ACL to insert, Index is a position in desired ACL
+	Add []ACE
+}
+
+// IsEmpty returns true if ACLs are equal
+func (diff ACLDiff) IsEmpty() bool {
+	return len(diff.Remove) == 0 && len(diff.Add) == 0
+}
+
+// DiffACL compares actual ACL with desired one, entries are compared with ACE.Equal().
+// Entries which are in the same order in both ACLs are kept, so if Remove entries are removed
+// in reverse order and then Add entries are inserted in direct order, actual ACL matches desired one.
+// Entries with ACLFlagInherited flag should be excluded from both ACLs if they are not managed.
+func DiffACL(actual, desired []ACE) ACLDiff {
+	// longest common subsequence of entries, lengths[i][j] - for actual[i:] and desired[j:]
+	lengths := make([][]int, len(actual)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(desired)+1)
+	}
+	for i := len(actual) - 1; i >= 0; i-- {
+		for j := len(desired) - 1; j >= 0; j-- {
+			if actual[i].Equal(desired[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	diff := ACLDiff{Remove: []ACE{}, Add: []ACE{}}
+	i, j := 0, 0
+	for i < len(actual) || j < len(desired) {
+		switch {
+		case i < len(actual) && j < len(desired) && actual[i].Equal(desired[j]):
+			i++
+			j++
+		case j == len(desired) || (i < len(actual) && lengths[i+1][j] >= lengths[i][j+1]):
+			ace := actual[i]
+			ace.Index = i
+			diff.Remove = append(diff.Remove, ace)
+			i++
+		default:
+			ace := desired[j]
+			ace.Index = j
+			diff.Add = append(diff.Add, ace)
+			j++
+		}
+	}
+
+	return diff
+}
+
+// ReconcileFilesystemACL changes filesystem ACL to match desired entries, entries which are already set
+// are not changed. Entries inherited from parent directory (ACLFlagInherited) are not managed and kept.
+// Changed entries are replaced in place and new entries are added before extra ones are removed,
+// so principals don't lose access while ACL is being changed.
+// Returns applied changes, ACL may be changed partially if an error is returned.
+func (p *Provider) ReconcileFilesystemACL(ctx context.Context, path string, desired []ACE) (ACLDiff, error) {
+	for i, ace := range desired {
+		if err := ace.validate(fmt.Sprintf("desired[%d]", i)); err != nil {
+			return ACLDiff{}, err
+		}
+	}
+
+	l := p.Log.WithField("func", "ReconcileFilesystemACL()")
+
+	acl, err := p.GetFilesystemACL(ctx, path)
+	if err != nil {
+		return ACLDiff{}, err
+	}
+
+	// managed entries and their positions in actual ACL
+	managed := []ACE{}
+	positions := []int{}
+	for i, ace := range acl {
+		if !ace.hasFlag(ACLFlagInherited) {
+			managed = append(managed, ace)
+			positions = append(positions, i)
+		}
+	}
+
+	diff := DiffACL(managed, desired)
+	removed := map[int]bool{}
+	for i := range diff.Remove {
+		removed[diff.Remove[i].Index] = true
+		diff.Remove[i].Index = positions[diff.Remove[i].Index]
+	}
+	added := map[int]bool{}
+	for _, ace := range diff.Add {
+		added[ace.Index] = true
+	}
+
+	// changes are applied one by one, so their jobs are waited in non-blocking mode (see StartJob)
+	stepCtx := waitJobs(ctx)
+
+	// acl is kept in sync with changes, pos - position to apply the next change at
+	pos := 0
+	i, j := 0, 0
+	for i < len(managed) || j < len(desired) {
+		// changes between entries which are kept
+		removeCount := 0
+		for ; i < len(managed) && removed[i]; i++ {
+			removeCount++
+		}
+		adds := []ACE{}
+		for ; j < len(desired) && added[j]; j++ {
+			adds = append(adds, desired[j])
+		}
+
+		for k, ace := range adds {
+			if k < removeCount {
+				pos = skipInheritedACEs(acl, pos)
+				l.Debugf("replace '%s' ACL entry %d: %s -> %s", path, pos, acl[pos], ace)
+				if err := p.ReplaceFilesystemACE(stepCtx, path, pos, ace); err != nil {
+					return diff, err
+				}
+				acl[pos] = ace
+			} else {
+				l.Debugf("insert '%s' ACL entry %d: %s", path, pos, ace)
+				if err := p.InsertFilesystemACE(stepCtx, path, pos, ace); err != nil {
+					return diff, err
+				}
+				acl = append(acl[:pos], append([]ACE{ace}, acl[pos:]...)...)
+			}
+			pos++
+		}
+		for k := len(adds); k < removeCount; k++ {
+			pos = skipInheritedACEs(acl, pos)
+			l.Debugf("remove '%s' ACL entry %d: %s", path, pos, acl[pos])
+			if err := p.RemoveFilesystemACE(stepCtx, path, pos); err != nil {
+				return diff, err
+			}
+			acl = append(acl[:pos], acl[pos+1:]...)
+		}
+
+		// entry is in both ACLs
+		if i < len(managed) && j < len(desired) {
+			pos = skipInheritedACEs(acl, pos) + 1
+			i++
+			j++
+		}
+	}
+
+	return diff, nil
+}
+
+// skipInheritedACEs returns position of the first not inherited entry starting from pos
+func skipInheritedACEs(acl []ACE, pos int) int {
+	for pos < len(acl) && acl[pos].hasFlag(ACLFlagInherited) {
+		pos++
+	}
+	return pos
+}
@@ -1,6 +1,7 @@
 package ns
 
 import (
+    "context"
     "encoding/json"
     "fmt"
     "net/http"
@@ -13,7 +14,7 @@ import (
 const nsFilesystemListLimit = 100
 
 // LogIn logs in to NexentaStor API and get auth token
-func (p *Provider) LogIn() error {
+func (p *Provider) LogIn(ctx context.Context) error {
     l := p.Log.WithField("func", "LogIn()")
 
     data := nefAuthLoginRequest{
@@ -21,9 +22,10 @@ func (p *Provider) LogIn() error {
         Password: p.Password,
     }
 
-    p.RestClient.SetAuthToken("")
-    _, bodyBytes, err := p.RestClient.Send(http.MethodPost, "auth/login", data)
+    statusCode, bodyBytes, err := p.RestClient.SendContext(ctx, http.MethodPost, "auth/login", data)
     if err != nil {
+        return fmt.Errorf("Login request: failed, error: %w", err)
+    } else if statusCode >= 300 {
         // try to parse error from rest response
         nefError := p.parseNefError(bodyBytes, "Login request")
         if nefError != nil {
@@ -37,7 +39,7 @@ func (p *Provider) LogIn() error {
             return nefError
         }
 
-        return fmt.Errorf("Login request: failed, response: %s; error: %s", bodyBytes, err)
+        return fmt.Errorf("Login request: failed with %d code, response: %s", statusCode, bodyBytes)
     }
 
     response := nefAuthLoginResponse{}
@@ -47,25 +49,26 @@ func (p *Provider) LogIn() error {
         return fmt.Errorf("Login request: token not found in response: '%s'", bodyBytes)
     }
 
-    p.RestClient.SetAuthToken(response.Token)
+    // current token is kept until the new one is received, so concurrent requests don't fail
+    p.setAuthToken(response.Token)
     l.Debugf("login token has been updated")
     return nil
 }
 
 // GetLicense returns NexentaStor license
-func (p *Provider) GetLicense() (license License, err error) {
-    err = p.sendRequestWithStruct(http.MethodGet, "settings/license", nil, &license)
+func (p *Provider) GetLicense(ctx context.Context) (license License, err error) {
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, "settings/license", nil, &license)
     return license, err
 }
 
 // GetPools returns NexentaStor pools
-func (p *Provider) GetPools() ([]Pool, error) {
+func (p *Provider) GetPools(ctx context.Context) ([]Pool, error) {
     uri := p.RestClient.BuildURI("storage/pools", map[string]string{
         "fields": "poolName,health,status",
     })
 
     response := nefStoragePoolsResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
         return nil, err
     }
@@ -74,14 +77,14 @@ func (p *Provider) GetPools() ([]Pool, error) {
 }
 
 // GetFilesystemAvailableCapacity returns NexentaStor filesystem available size by its path
-func (p *Provider) GetFilesystemAvailableCapacity(path string) (int64, error) {
+func (p *Provider) GetFilesystemAvailableCapacity(ctx context.Context, path string) (int64, error) {
     uri := p.RestClient.BuildURI("storage/filesystems", map[string]string{
         "path":   path,
         "fields": "bytesAvailable",
     })
 
     response := nefStorageFilesystemsResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
         return 0, err
     }
@@ -95,24 +98,24 @@ func (p *Provider) GetFilesystemAvailableCapacity(path string) (int64, error) {
 }
 
 // GetFilesystem returns NexentaStor filesystem by its path
-func (p *Provider) GetFilesystem(path string) (filesystem Filesystem, err error) {
+func (p *Provider) GetFilesystem(ctx context.Context, path string) (filesystem Filesystem, err error) {
     if path == "" {
         return filesystem, fmt.Errorf("Filesystem path is empty")
     }
 
     uri := p.RestClient.BuildURI("storage/filesystems", map[string]string{
         "path":   path,
-        "fields": "path,mountPoint,bytesAvailable,bytesUsed,sharedOverNfs,sharedOverSmb",
+        "fields": nefFilesystemFields,
     })
 
     response := nefStorageFilesystemsResponse{}
-    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
         return filesystem, err
     }
 
     if len(response.Data) == 0 {
-        return filesystem, &NefError{Code: "ENOENT", Err: fmt.Errorf("Filesystem '%s' not found", path)}
+        return filesystem, p.notExistError(ctx, path, fmt.Errorf("Filesystem '%s' not found", path))
     }
 
     return response.Data[0], nil
@@ -123,83 +126,53 @@ func (p *Provider) GetFilesystem(path string) (filesystem Filesystem, err error)
 // startingToken - a path to a specific volume to start AFTER this token
 // limit - the maximum count of volumes to return in the list
 // Function may return nextToken if there is more volumes than limit value
-func (p *Provider) GetVolumesWithStartingToken(parent string, startingToken string, limit int) (
+func (p *Provider) GetVolumesWithStartingToken(ctx context.Context, parent string, startingToken string, limit int) (
     volumes []Volume,
     nextToken string,
     err error,
 ) {
-    startingTokenFound := false
-    if startingToken == "" {
-        // if no startingToken set then filesystem list should starts with the first one
-        startingTokenFound = true
-    }
-
-    // if no limit set then all filesystem after startingToken should be in the response
-    noLimit := limit == 0
-
-    // load volumes using slice requests
-    offset := 0
-    lastResultCount := nsFilesystemListLimit
-    for (noLimit || len(volumes) < limit) && lastResultCount >= nsFilesystemListLimit {
-        volumesSlice, err := p.GetVolumesSlice(parent, nsFilesystemListLimit-1, offset)
-        if err != nil {
-            return nil, "", err
-        }
-        for _, fs := range volumesSlice {
-            if startingTokenFound {
-                volumes = append(volumes, fs)
-                if len(volumes) == limit {
-                    nextToken = fs.Path
-                    break
-                }
-            } else if fs.Path == startingToken {
-                startingTokenFound = true
-            }
+    volumes = []Volume{}
+
+    it := p.IterateVolumes(ctx, parent, IteratorParams{StartingToken: startingToken})
+    // if no limit set then all volumes after startingToken should be in the response
+    for (limit == 0 || len(volumes) < limit) && it.Next() {
+        volumes = append(volumes, it.Volume())
+        if len(volumes) == limit {
+            nextToken = it.NextToken()
         }
-        lastResultCount = len(volumesSlice)
-        offset += lastResultCount
+    }
+    if err := it.Err(); err != nil {
+        return nil, "", err
     }
 
     return volumes, nextToken, nil
 }
 
 // GetVolumes returns all NexentaStor volumes by parent volumeGroup
-func (p *Provider) GetVolumes(parent string) ([]Volume, error) {
+func (p *Provider) GetVolumes(ctx context.Context, parent string) ([]Volume, error) {
     volumes := []Volume{}
 
-    offset := 0
-    lastResultCount := nsFilesystemListLimit
-    for lastResultCount >= nsFilesystemListLimit {
-        volumesSlice, err := p.GetVolumesSlice(parent, nsFilesystemListLimit-1, offset)
-        if err != nil {
-            return nil, err
-        }
-        for _, vol := range volumesSlice {
-            volumes = append(volumes, vol)
-        }
-        lastResultCount = len(volumesSlice)
-        offset += lastResultCount
+    it := p.IterateVolumes(ctx, parent, IteratorParams{})
+    for it.Next() {
+        volumes = append(volumes, it.Volume())
+    }
+    if err := it.Err(); err != nil {
+        return nil, err
     }
 
     return volumes, nil
 }
 
 // GetFilesystems returns all NexentaStor filesystems by parent filesystem
-func (p *Provider) GetFilesystems(parent string) ([]Filesystem, error) {
+func (p *Provider) GetFilesystems(ctx context.Context, parent string) ([]Filesystem, error) {
     filesystems := []Filesystem{}
 
-    offset := 1
-    lastResultCount := nsFilesystemListLimit
-    for lastResultCount >= nsFilesystemListLimit {
-        filesystemsSlice, err := p.GetFilesystemsSlice(parent, nsFilesystemListLimit-1, offset)
-        if err != nil {
-            return nil, err
-        }
-        for _, fs := range filesystemsSlice {
-            filesystems = append(filesystems, fs)
-        }
-        lastResultCount = len(filesystemsSlice)
-        offset += lastResultCount
+    it := p.IterateFilesystems(ctx, parent, IteratorParams{})
+    for it.Next() {
+        filesystems = append(filesystems, it.Filesystem())
+    }
+    if err := it.Err(); err != nil {
+        return nil, err
     }
 
     return filesystems, nil
@@ -210,41 +183,23 @@ func (p *Provider) GetFilesystems(parent string) ([]Filesystem, error) {
 // startingToken - a path to a specific filesystem to start AFTER this token
 // limit - the maximum count of filesystems to return in the list
 // Function may return nextToken if there is more filesystems than limit value
-func (p *Provider) GetFilesystemsWithStartingToken(parent string, startingToken string, limit int) (
+func (p *Provider) GetFilesystemsWithStartingToken(ctx context.Context, parent string, startingToken string, limit int) (
     filesystems []Filesystem,
     nextToken string,
     err error,
 ) {
-    startingTokenFound := false
-    if startingToken == "" {
-        // if no startingToken set then filesystem list should starts with the first one
-        startingTokenFound = true
-    }
-
-    // if no limit set then all filesystem after startingToken should be in the response
-    noLimit := limit == 0
-
-    // load filesystems using slice requests
-    offset := 1
-    lastResultCount := nsFilesystemListLimit
-    for (noLimit || len(filesystems) < limit) && lastResultCount >= nsFilesystemListLimit {
-        filesystemsSlice, err := p.GetFilesystemsSlice(parent, nsFilesystemListLimit-1, offset)
-        if err != nil {
-            return nil, "", err
+    filesystems = []Filesystem{}
+
+    it := p.IterateFilesystems(ctx, parent, IteratorParams{StartingToken: startingToken})
+    // if no limit set then all filesystems after startingToken should be in the response
+    for (limit == 0 || len(filesystems) < limit) && it.Next() {
+        filesystems = append(filesystems, it.Filesystem())
+        if len(filesystems) == limit {
+            nextToken = it.NextToken()
         }
-        for _, fs := range filesystemsSlice {
-            if startingTokenFound {
-                filesystems = append(filesystems, fs)
-                if len(filesystems) == limit {
-                    nextToken = fs.Path
-                    break
-                }
-            } else if fs.Path == startingToken {
-                startingTokenFound = true
-            }
-        }
-        lastResultCount = len(filesystemsSlice)
-        offset += lastResultCount
+    }
+    if err := it.Err(); err != nil {
+        return nil, "", err
     }
 
     return filesystems, nextToken, nil
@@ -252,7 +207,7 @@ func (p *Provider) GetFilesystemsWithStartingToken(parent string, startingToken
 
 // GetFilesystemsSlice returns a slice of filesystems by parent filesystem with specified limit and offset
 // offset - the first record number of collection, that would be included in result
-func (p *Provider) GetFilesystemsSlice(parent string, limit, offset int) ([]Filesystem, error) {
+func (p *Provider) GetFilesystemsSlice(ctx context.Context, parent string, limit, offset int) ([]Filesystem, error) {
     if limit <= 0 || limit >= nsFilesystemListLimit {
         return nil, fmt.Errorf(
             "GetFilesystemsSlice(): parameter 'limit' must be greater that 0 and less than %d, got: %d",
@@ -270,11 +225,11 @@ func (p *Provider) GetFilesystemsSlice(parent string, limit, offset int) ([]File
         "parent": parent,
         "limit":  fmt.Sprint(limit + 1), // the result includes parent itself
         "offset": fmt.Sprint(offset),
-        "fields": "path,mountPoint,bytesAvailable,bytesUsed,sharedOverNfs,sharedOverSmb",
+        "fields": nefFilesystemFields,
     })
 
     response := nefStorageFilesystemsResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
         return nil, err
     }
@@ -291,7 +246,7 @@ func (p *Provider) GetFilesystemsSlice(parent string, limit, offset int) ([]File
 
 // GetVolumesSlice returns a slice of volumes by parent volumeGroup with specified limit and offset
 // offset - the first record number of collection, that would be included in result
-func (p *Provider) GetVolumesSlice(parent string, limit, offset int) ([]Volume, error) {
+func (p *Provider) GetVolumesSlice(ctx context.Context, parent string, limit, offset int) ([]Volume, error) {
     if limit <= 0 || limit >= nsFilesystemListLimit {
         return nil, fmt.Errorf(
             "GetVolumesSlice(): parameter 'limit' must be greater that 0 and less than %d, got: %d",
@@ -312,7 +267,7 @@ func (p *Provider) GetVolumesSlice(parent string, limit, offset int) ([]Volume,
     })
 
     response := nefStorageVolumesResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
         return nil, err
     }
@@ -325,39 +280,81 @@ func (p *Provider) GetVolumesSlice(parent string, limit, offset int) ([]Volume,
     return volumes, nil
 }
 
-// CreateFilesystemParams - params to create filesystem
+// CreateFilesystemParams - params to create filesystem, properties which are not set are inherited
 type CreateFilesystemParams struct {
     // filesystem path w/o leading slash
     Path string `json:"path"`
     // filesystem referenced quota size in bytes
     ReferencedQuotaSize int64 `json:"referencedQuotaSize,omitempty"`
+    // filesystem quota size in bytes, including descendants and snapshots
+    QuotaSize int64 `json:"quotaSize,omitempty"`
+    // guaranteed space in bytes, including descendants and snapshots
+    ReservationSize int64 `json:"reservationSize,omitempty"`
+    // guaranteed space in bytes for the filesystem itself
+    ReferencedReservationSize int64 `json:"referencedReservationSize,omitempty"`
+    // compression algorithm, e.g. CompressionModeLZ4
+    CompressionMode CompressionMode `json:"compressionMode,omitempty"`
+    // record size in bytes, power of two from 512 to 1M
+    RecordSize int64 `json:"recordSize,omitempty"`
+    // update access time on read ("atime"), use ns.Bool() to set
+    AccessTime *bool `json:"accessTime,omitempty"`
+    // synchronous requests behavior
+    SyncMode SyncMode `json:"syncMode,omitempty"`
+    // synchronous requests optimization
+    LogBias LogBias `json:"logBias,omitempty"`
+    // mount filesystem in read only mode, use ns.Bool() to set
+    ReadOnly *bool `json:"readOnly,omitempty"`
+    // non-blocking mandatory locks ("nbmand"), use ns.Bool() to set
+    NonBlockingMandatoryMode *bool `json:"nonBlockingMandatoryMode,omitempty"`
+    // file name matching, can't be changed after creation
+    CaseSensitivity CaseSensitivity `json:"caseSensitivity,omitempty"`
+    // ZFS user properties, names must be in "module:property" format
+    UserProperties map[string]string `json:"userProperties,omitempty"`
 }
 
 // CreateFilesystem creates filesystem by path
-func (p *Provider) CreateFilesystem(params CreateFilesystemParams) error {
+func (p *Provider) CreateFilesystem(ctx context.Context, params CreateFilesystemParams) error {
     if params.Path == "" {
         return fmt.Errorf("Parameter 'CreateFilesystemParams.Path' is required")
+    } else if err := params.validate(); err != nil {
+        return err
     }
 
     //TODO consider to add option https://jira.nexenta.com/browse/NEX-17476?focusedCommentId=154590
 
-    return p.sendRequest(http.MethodPost, "storage/filesystems", params)
+    return p.sendRequest(ctx, http.MethodPost, "storage/filesystems", params)
 }
 
-// UpdateFilesystemParams - params to update filesystem
+// UpdateFilesystemParams - params to update filesystem, only set properties are changed
+// (see CreateFilesystemParams for properties description)
 type UpdateFilesystemParams struct {
     // filesystem referenced quota size in bytes
-    ReferencedQuotaSize int64 `json:"referencedQuotaSize,omitempty"`
+    ReferencedQuotaSize       int64             `json:"referencedQuotaSize,omitempty"`
+    // quota and reservations in bytes, set to 0 to remove them
+    QuotaSize                 *int64            `json:"quotaSize,omitempty"`
+    ReservationSize           *int64            `json:"reservationSize,omitempty"`
+    ReferencedReservationSize *int64            `json:"referencedReservationSize,omitempty"`
+    CompressionMode           CompressionMode   `json:"compressionMode,omitempty"`
+    RecordSize                int64             `json:"recordSize,omitempty"`
+    AccessTime                *bool             `json:"accessTime,omitempty"`
+    SyncMode                  SyncMode          `json:"syncMode,omitempty"`
+    LogBias                   LogBias           `json:"logBias,omitempty"`
+    ReadOnly                  *bool             `json:"readOnly,omitempty"`
+    NonBlockingMandatoryMode  *bool             `json:"nonBlockingMandatoryMode,omitempty"`
+    // ZFS user properties to set, other user properties are kept
+    UserProperties            map[string]string `json:"userProperties,omitempty"`
 }
 
 // UpdateFilesystem updates filesystem by path
-func (p *Provider) UpdateFilesystem(path string, params UpdateFilesystemParams) error {
+func (p *Provider) UpdateFilesystem(ctx context.Context, path string, params UpdateFilesystemParams) error {
     if path == "" {
         return fmt.Errorf("Parameter 'path' is required")
+    } else if err := params.validate(); err != nil {
+        return err
     }
 
     uri :=  fmt.Sprintf("storage/filesystems/%s", url.PathEscape(path))
-    return p.sendRequest(http.MethodPut, uri, params)
+    return p.sendRequest(ctx, http.MethodPut, uri, params)
 }
 
 // DestroyFilesystemParams - filesystem deletion parameters
@@ -393,8 +390,15 @@ type DestroyFilesystemParams struct {
 
 // DestroyFilesystem destroys filesystem on NS, may destroy snapshots and promote clones (see DestroyFilesystemParams)
 // Path format: 'pool/dataset/filesystem'
-func (p *Provider) DestroyFilesystem(path string, params DestroyFilesystemParams) error {
-    err := p.destroyFilesystem(path, params.DestroySnapshots)
+func (p *Provider) DestroyFilesystem(ctx context.Context, path string, params DestroyFilesystemParams) error {
+    // clones are promoted and destroy is repeated if the first one fails, steps before the final destroy
+    // are waited in non-blocking mode (see StartJob)
+    stepCtx := ctx
+    if params.PromoteMostRecentCloneIfExists {
+        stepCtx = waitJobs(ctx)
+    }
+
+    err := p.destroyFilesystem(stepCtx, path, params.DestroySnapshots)
     if err == nil {
         return nil
     } else if !params.PromoteMostRecentCloneIfExists || !IsAlreadyExistNefError(err) {
@@ -411,7 +415,7 @@ func (p *Provider) DestroyFilesystem(path string, params DestroyFilesystemParams
     for i := 0; i < maxAttemptCount; i++ {
         mostRecentError = nil
 
-        snapshots, err := p.GetSnapshots(path, true)
+        snapshots, err := p.GetSnapshots(ctx, path, true)
         if err != nil {
             mostRecentError = fmt.Errorf("failed to get snapshot list: %s", err)
             break
@@ -421,7 +425,7 @@ func (p *Provider) DestroyFilesystem(path string, params DestroyFilesystemParams
         var mostRecentClone string
         for _, s := range snapshots {
             // to get "clones" and "creationTxg" fields that are not presented in the list response
-            snapshot, err := p.GetSnapshot(s.Path)
+            snapshot, err := p.GetSnapshot(ctx, s.Path)
             if err != nil {
                 mostRecentError = fmt.Errorf("failed to get '%s' snapshost's info: %s", s.Path, err)
                 break
@@ -449,14 +453,14 @@ func (p *Provider) DestroyFilesystem(path string, params DestroyFilesystemParams
         }
 
         if mostRecentClone != "" {
-            err := p.PromoteFilesystem(mostRecentClone)
+            err := p.PromoteFilesystem(stepCtx, mostRecentClone)
             if err != nil {
                 mostRecentError = fmt.Errorf("failed to promote clone '%s': %s", mostRecentClone, err)
                 continue
             }
         }
 
-        mostRecentError = p.destroyFilesystem(path, params.DestroySnapshots)
+        mostRecentError = p.destroyFilesystem(ctx, path, params.DestroySnapshots)
         if mostRecentError == nil {
             return nil
         } else if !IsAlreadyExistNefError(mostRecentError) { // if EEXIST code - filesystem still has dependent clones
@@ -472,7 +476,7 @@ func (p *Provider) DestroyFilesystem(path string, params DestroyFilesystemParams
     return mostRecentError
 }
 
-func (p *Provider) destroyFilesystem(path string, destroySnapshots bool) error {
+func (p *Provider) destroyFilesystem(ctx context.Context, path string, destroySnapshots bool) error {
     if path == "" {
         return fmt.Errorf("Filesystem path is required")
     }
@@ -485,37 +489,54 @@ func (p *Provider) destroyFilesystem(path string, destroySnapshots bool) error {
         },
     )
 
-    return p.sendRequest(http.MethodDelete, uri, nil)
+    return p.sendRequest(ctx, http.MethodDelete, uri, nil)
 }
 
 // PromoteFilesystem promotes a cloned filesystem to be no longer dependent on its original snapshot
-func (p *Provider) PromoteFilesystem(path string) error {
+func (p *Provider) PromoteFilesystem(ctx context.Context, path string) error {
     if path == "" {
         return fmt.Errorf("Filesystem path is required")
     }
 
     uri := fmt.Sprintf("storage/filesystems/%s/promote", url.PathEscape(path))
 
-    return p.sendRequest(http.MethodPost, uri, nil)
+    return p.sendRequest(ctx, http.MethodPost, uri, nil)
 }
 
 // PromoteVolume promotes a cloned volume to be no longer dependent on its original snapshot
-func (p *Provider) PromoteVolume(path string) error {
+func (p *Provider) PromoteVolume(ctx context.Context, path string) error {
     if path == "" {
         return fmt.Errorf("Volume path is required")
     }
 
     uri := fmt.Sprintf("storage/volumes/%s/promote", url.PathEscape(path))
 
-    return p.sendRequest(http.MethodPost, uri, nil)
+    return p.sendRequest(ctx, http.MethodPost, uri, nil)
 }
 
 // CreateNfsShareParams - params to create NFS share
 type CreateNfsShareParams struct {
     // filesystem path w/o leading slash
     Filesystem          string              `json:"filesystem"`
+    // rules of the default security context, "*" gets read-write access if both lists are empty
     ReadWriteList       []NfsRuleList       `json:"readWriteList"`
     ReadOnlyList        []NfsRuleList       `json:"readOnlyList"`
+    // clients which root users keep root access in the default security context
+    RootList            []NfsRuleList
+    // security modes of the default security context, default: [NfsSecurityModeSys]
+    SecurityModes       []NfsSecurityMode
+    // security contexts to use instead of the default one, lists and modes above must not be set then
+    SecurityContexts    []NfsSecurityContext
+    // user (name or uid) which unknown users are mapped to, default: "root"
+    Anon                string
+    // user (name or uid) which root users of clients w/o root access are mapped to
+    RootMapping         string
+    // disallow set-uid/set-gid programs on clients
+    Nosuid              bool
+    // make child filesystems visible to NFSv3 clients w/o mounting them
+    Nohide              bool
+    // NFS versions allowed for the share, all versions enabled on NexentaStor if not set
+    Versions            []NfsVersion
 }
 
 // CreateNfsShare creates NFS share on specified filesystem
@@ -523,67 +544,45 @@ type CreateNfsShareParams struct {
 //   showmount -e HOST
 //   mkdir -p /mnt/test && sudo mount -v -t nfs HOST:/pool/fs /mnt/test
 //   findmnt /mnt/test
-func (p *Provider) CreateNfsShare(params CreateNfsShareParams) error {
+func (p *Provider) CreateNfsShare(ctx context.Context, params CreateNfsShareParams) error {
     if params.Filesystem == "" {
         return fmt.Errorf("CreateNfsShareParams.Filesystem is required")
+    } else if err := params.validate(); err != nil {
+        return err
     }
 
-    defaultEtype := "fqdn"
-    if len(params.ReadWriteList) == 0 {
-        if len(params.ReadOnlyList) == 0 {
-            params.ReadOnlyList = []NfsRuleList{
-                {
-                    Entity: "none",
-                    Etype: defaultEtype,
-                },
-            }
-            params.ReadWriteList = []NfsRuleList{
-                {
-                    Entity: "*",
-                    Etype: defaultEtype,
-                },
-            }
-        } else {
-            params.ReadWriteList = []NfsRuleList{
-                {
-                    Entity: "none",
-                    Etype: defaultEtype,
-                },
-            }
-        }
-    } else if len(params.ReadOnlyList) == 0 {
-        params.ReadOnlyList = []NfsRuleList{
-            {
-                Entity: "none",
-                Etype: defaultEtype,
-            },
-        }
+    anon := params.Anon
+    if anon == "" {
+        anon = "root"
+    }
+
+    securityContexts := params.SecurityContexts
+    if len(securityContexts) == 0 {
+        securityContexts = []NfsSecurityContext{params.defaultSecurityContext()}
     }
 
     data := nefNasNfsRequest{
-        Filesystem: params.Filesystem,
-        Anon:       "root",
-        SecurityContexts: []nefNasNfsRequestSecurityContext{
-            {
-                SecurityModes: []string{"sys"},
-                ReadWriteList: params.ReadWriteList,
-                ReadOnlyList: params.ReadOnlyList,
-            },
-        },
+        Filesystem:       params.Filesystem,
+        Anon:             anon,
+        RootMapping:      params.RootMapping,
+        Nosuid:           params.Nosuid,
+        Nohide:           params.Nohide,
+        Versions:         params.Versions,
+        SecurityContexts: securityContexts,
     }
 
-    return p.sendRequest(http.MethodPost, "nas/nfs", data)
+    return p.sendRequest(ctx, http.MethodPost, "nas/nfs", data)
 }
 
 // DeleteNfsShare destroys NFS chare by filesystem path
-func (p *Provider) DeleteNfsShare(path string) error {
+func (p *Provider) DeleteNfsShare(ctx context.Context, path string) error {
     if path == "" {
         return fmt.Errorf("Filesystem path is empty")
     }
 
     uri := fmt.Sprintf("nas/nfs/%s", url.PathEscape(path))
 
-    return p.sendRequest(http.MethodDelete, uri, nil)
+    return p.sendRequest(ctx, http.MethodDelete, uri, nil)
 }
 
 // CreateSmbShareParams - params to create SMB share
@@ -599,47 +598,56 @@ type CreateSmbShareParams struct {
 // CLI test:
 //   mkdir -p /mnt/test && sudo mount -v -t cifs -o username=admin,password=Nexenta@1 //HOST//pool_fs /mnt/test
 //   findmnt /mnt/test
-func (p *Provider) CreateSmbShare(params CreateSmbShareParams) error {
+func (p *Provider) CreateSmbShare(ctx context.Context, params CreateSmbShareParams) error {
     if params.Filesystem == "" {
         return fmt.Errorf("CreateSmbShareParams.Filesystem is required")
     }
 
-    return p.sendRequest(http.MethodPost, "nas/smb", params)
+    return p.sendRequest(ctx, http.MethodPost, "nas/smb", params)
 }
 
-// GetSmbShareName returns share name for filesystem that shared over SMB
-func (p *Provider) GetSmbShareName(path string) (string, error) {
+// GetSmbShareName returns share name for filesystem that shared over SMB,
+// EBUSY NefError is returned if the share state is reported and it's not online, so clients cannot connect to it
+func (p *Provider) GetSmbShareName(ctx context.Context, path string) (string, error) {
     if path == "" {
         return "", fmt.Errorf("Filesystem path is required")
     }
 
     uri := p.RestClient.BuildURI(
         fmt.Sprintf("nas/smb/%s", url.PathEscape(path)),
-        map[string]string{"fields": "shareName,shareState"}, //TODO check shareState value?
+        map[string]string{"fields": "shareName,shareState"},
     )
 
     response := nefNasSmbResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
         return "", err
     }
 
+    // NexentaStor versions w/o share state don't return it, such shares are treated as online
+    if response.ShareState != "" && response.ShareState != SmbShareStateOnline {
+        return "", &NefError{
+            Code: NefCodeBusy,
+            Err:  fmt.Errorf("SMB share '%s' of '%s' is %s", response.ShareName, path, response.ShareState),
+        }
+    }
+
     return response.ShareName, nil
 }
 
 // DeleteSmbShare destroys SMB share by filesystem path
-func (p *Provider) DeleteSmbShare(path string) error {
+func (p *Provider) DeleteSmbShare(ctx context.Context, path string) error {
     if path == "" {
         return fmt.Errorf("Filesystem path is empty")
     }
 
     uri := fmt.Sprintf("nas/smb/%s", url.PathEscape(path))
 
-    return p.sendRequest(http.MethodDelete, uri, nil)
+    return p.sendRequest(ctx, http.MethodDelete, uri, nil)
 }
 
 // SetFilesystemACL sets filesystem ACL, so NFS share can allow user to write w/o checking UNIX user uid
-func (p *Provider) SetFilesystemACL(path string, aclRuleSet ACLRuleSet) error {
+func (p *Provider) SetFilesystemACL(ctx context.Context, path string, aclRuleSet ACLRuleSet) error {
     if path == "" {
         return fmt.Errorf("Filesystem path is required")
     }
@@ -663,82 +671,85 @@ func (p *Provider) SetFilesystemACL(path string, aclRuleSet ACLRuleSet) error {
         Permissions: permissions,
     }
 
-    return p.sendRequest(http.MethodPost, uri, data)
+    return p.sendRequest(ctx, http.MethodPost, uri, data)
 }
 
 // CreateSnapshotParams - params to create snapshot
 type CreateSnapshotParams struct {
     // snapshot path w/o leading slash
     Path string `json:"path"`
+    // ZFS user properties, names must be in "module:property" format
+    UserProperties map[string]string `json:"userProperties,omitempty"`
 }
 
 // CreateSnapshot creates snapshot by filesystem path
-func (p *Provider) CreateSnapshot(params CreateSnapshotParams) error {
+func (p *Provider) CreateSnapshot(ctx context.Context, params CreateSnapshotParams) error {
     if params.Path == "" {
         return fmt.Errorf("Parameter 'CreateSnapshotParams.Path' is required")
     }
 
-    return p.sendRequest(http.MethodPost, "storage/snapshots", params)
+    err := datasetProperties{userProperties: params.UserProperties}.validate("CreateSnapshotParams")
+    if err != nil {
+        return err
+    }
+
+    return p.sendRequest(ctx, http.MethodPost, "storage/snapshots", params)
 }
 
 // GetSnapshot returns snapshot by its path
 // path - full path to snapshot w/o leading slash (e.g. "p/d/fs@s")
-func (p *Provider) GetSnapshot(path string) (snapshot Snapshot, err error) {
+func (p *Provider) GetSnapshot(ctx context.Context, path string) (snapshot Snapshot, err error) {
     if path == "" {
         return snapshot, fmt.Errorf("Snapshot path is empty")
     }
 
     uri := p.RestClient.BuildURI(fmt.Sprintf("storage/snapshots/%s", url.PathEscape(path)), map[string]string{
-        "fields": "path,name,parent,creationTime,clones,creationTxg",
-        //TODO return "bytesReferenced" and check on volume creation
+        "fields": nefSnapshotFields + ",clones,creationTxg",
     })
 
-    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &snapshot)
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &snapshot)
+    if IsNotExistNefError(err) {
+        p.notifyErrorObservers(ctx, path, err)
+    }
 
     return snapshot, err
 }
 
 // GetSnapshots returns snapshots by volume path
-func (p *Provider) GetSnapshots(volumePath string, recursive bool) ([]Snapshot, error) {
-    if volumePath == "" {
-        return []Snapshot{}, fmt.Errorf("Snapshots volume path is empty")
-    }
-
-    uri := p.RestClient.BuildURI("storage/snapshots", map[string]string{
-        "parent":    volumePath,
-        "fields":    "path,name,parent,creationTime",
-        "recursive": strconv.FormatBool(recursive),
-    })
+func (p *Provider) GetSnapshots(ctx context.Context, volumePath string, recursive bool) ([]Snapshot, error) {
+    snapshots := []Snapshot{}
 
-    response := nefStorageSnapshotsResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
-    if err != nil {
+    it := p.IterateSnapshots(ctx, volumePath, recursive, IteratorParams{})
+    for it.Next() {
+        snapshots = append(snapshots, it.Snapshot())
+    }
+    if err := it.Err(); err != nil {
         return []Snapshot{}, err
     }
 
-    return response.Data, nil
+    return snapshots, nil
 }
 
 // DestroySnapshot destroys snapshot by path
-func (p *Provider) DestroySnapshot(path string) error {
+func (p *Provider) DestroySnapshot(ctx context.Context, path string) error {
     if path == "" {
         return fmt.Errorf("Snapshot path is required")
     }
 
     uri := fmt.Sprintf("storage/snapshots/%s", url.PathEscape(path))
 
-    return p.sendRequest(http.MethodDelete, uri, nil)
+    return p.sendRequest(ctx, http.MethodDelete, uri, nil)
 }
 
-// CloneSnapshotParams - params to clone snapshot to filesystem
+// CloneSnapshotParams - params to clone snapshot to filesystem, see CloneVolumeSnapshotParams for volumes
 type CloneSnapshotParams struct {
     // filesystem path w/o leading slash
     TargetPath string `json:"targetPath"`
     ReferencedQuotaSize int64 `json:"referencedQuotaSize,omitempty"`
 }
 
-// CloneSnapshot clones snapshot to FS
-func (p *Provider) CloneSnapshot(path string, params CloneSnapshotParams) error {
+// CloneSnapshot clones snapshot to FS, use CloneVolumeSnapshot() to clone volume snapshot to a new volume
+func (p *Provider) CloneSnapshot(ctx context.Context, path string, params CloneSnapshotParams) error {
     if path == "" {
         return fmt.Errorf("Snapshot path is required")
     }
@@ -749,17 +760,17 @@ func (p *Provider) CloneSnapshot(path string, params CloneSnapshotParams) error
 
     uri := fmt.Sprintf("storage/snapshots/%s/clone", url.PathEscape(path))
 
-    return p.sendRequest(http.MethodPost, uri, params)
+    return p.sendRequest(ctx, http.MethodPost, uri, params)
 }
 
 // GetRSFClusters returns RSF clusters from NS
-func (p *Provider) GetRSFClusters() ([]RSFCluster, error) {
+func (p *Provider) GetRSFClusters(ctx context.Context) ([]RSFCluster, error) {
     uri := p.RestClient.BuildURI("rsf/clusters", map[string]string{
         "fields": "clusterName,nodes",
     })
 
     response := nefRsfClustersResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
         return nil, err
     }
@@ -767,36 +778,20 @@ func (p *Provider) GetRSFClusters() ([]RSFCluster, error) {
     return response.Data, nil
 }
 
-// IsJobDone checks if job is done by jobId
-func (p *Provider) IsJobDone(jobID string) (bool, error) {
-    uri := fmt.Sprintf("jobStatus/%s", jobID)
-
-    statusCode, bodyBytes, err := p.RestClient.Send(http.MethodGet, uri, nil)
+// IsJobDone checks if job is done by jobId, job failure is returned as NefError
+func (p *Provider) IsJobDone(ctx context.Context, jobID string) (bool, error) {
+    status, err := p.getJobStatus(ctx, jobID)
     if err != nil { // request failed
         return false, err
-    } else if statusCode == http.StatusOK || statusCode == http.StatusCreated { // job is completed
-        return true, nil
-    } else if statusCode == http.StatusAccepted { // job is in progress (202)
-        return false, nil
+    } else if status.State == JobStateFailed {
+        return false, status.Err
     }
 
-    // job is failed
-    nefError := p.parseNefError(bodyBytes, "Job was finished with error")
-    if nefError != nil {
-        err = nefError
-    } else {
-        err = fmt.Errorf(
-            "Job request returned %d code, but response body doesn't contain explanation: %s",
-            statusCode,
-            bodyBytes,
-        )
-    }
-
-    return false, err
+    return status.State == JobStateDone, nil
 }
 
 // GetVolume - returns NexentaStor volume properties
-func (p *Provider) GetVolume(path string) (volume Volume, err error) {
+func (p *Provider) GetVolume(ctx context.Context, path string) (volume Volume, err error) {
     if path == "" {
         return volume, fmt.Errorf("Volume path is empty")
     }
@@ -806,20 +801,20 @@ func (p *Provider) GetVolume(path string) (volume Volume, err error) {
     })
 
     response := nefStorageVolumesResponse{}
-    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
-        return response.Data[0], err
+        return volume, err
     }
 
     if len(response.Data) == 0 {
-        return volume, &NefError{Code: "ENOENT", Err: fmt.Errorf("VolumeGroup '%s' not found", path)}
+        return volume, p.notExistError(ctx, path, fmt.Errorf("Volume '%s' not found", path))
     }
 
     return response.Data[0], nil
 }
 
 // GetVolumeGroup returns NexentaStor volumeGroup by its path
-func (p *Provider) GetVolumeGroup(path string) (volumeGroup VolumeGroup,err error) {
+func (p *Provider) GetVolumeGroup(ctx context.Context, path string) (volumeGroup VolumeGroup,err error) {
     if path == "" {
         return volumeGroup, fmt.Errorf("VolumeGroup path is empty")
     }
@@ -829,50 +824,98 @@ func (p *Provider) GetVolumeGroup(path string) (volumeGroup VolumeGroup,err erro
     })
 
     response := nefStorageVolumeGroupsResponse{}
-    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
         return volumeGroup, err
     }
 
     if len(response.Data) == 0 {
-        return volumeGroup, &NefError{Code: "ENOENT", Err: fmt.Errorf("VolumeGroup '%s' not found", path)}
+        return volumeGroup, p.notExistError(ctx, path, fmt.Errorf("VolumeGroup '%s' not found", path))
     }
 
     return response.Data[0], nil
 }
 
-// CreateVolumeParams - params to create a volume
+// CreateVolumeParams - params to create a volume, properties which are not set are inherited
 type CreateVolumeParams struct {
     // volume path w/o leading slash
     Path                string `json:"path"`
     VolumeSize          int64  `json:"volumeSize"`
     SparseVolume        bool   `json:"sparseVolume"` 
+    // volume block size in bytes, power of two from 512 to 128K, can't be changed after creation
+    VolumeBlockSize int64 `json:"volumeBlockSize,omitempty"`
+    // compression algorithm, e.g. CompressionModeLZ4
+    CompressionMode CompressionMode `json:"compressionMode,omitempty"`
+    // synchronous requests behavior
+    SyncMode SyncMode `json:"syncMode,omitempty"`
+    // enable LU write back cache, use ns.Bool() to set
+    WriteBackCache *bool `json:"writeBackCache,omitempty"`
+    // deduplication mode, e.g. DedupModeOff
+    DedupMode DedupMode `json:"dedupMode,omitempty"`
+    // guaranteed space in bytes, must not be set for sparse volumes
+    ReservationSize int64 `json:"reservationSize,omitempty"`
+    // make volume read only, use ns.Bool() to set
+    ReadOnly *bool `json:"readOnly,omitempty"`
+    // ZFS user properties, names must be in "module:property" format
+    UserProperties map[string]string `json:"userProperties,omitempty"`
 }
 
 // CreateVolume creates volume by path and size
-func (p *Provider) CreateVolume(params CreateVolumeParams) error {
+func (p *Provider) CreateVolume(ctx context.Context, params CreateVolumeParams) error {
     if params.Path == "" {
         return fmt.Errorf(
             "Parameters 'Volume.Path' is required, received %+v", params)
+    } else if err := params.validate(); err != nil {
+        return err
     }
 
-    return p.sendRequest(http.MethodPost, "storage/volumes", params)
+    return p.sendRequest(ctx, http.MethodPost, "storage/volumes", params)
 }
 
-// UpdateVolumeParams - params to update volume
+// UpdateVolumeParams - params to update volume, only set properties are changed
+// (see CreateVolumeParams for properties description)
 type UpdateVolumeParams struct {
     // volume referenced quota size in bytes
     VolumeSize int64 `json:"volumeSize,omitempty"`
-}
-
-// UpdateVolume updates volume by path
-func (p *Provider) UpdateVolume(path string, params UpdateVolumeParams) error {
+    // allow to set VolumeSize less than current size, data beyond the new size is lost
+    AllowShrink bool `json:"-"`
+
+    CompressionMode CompressionMode   `json:"compressionMode,omitempty"`
+    SyncMode        SyncMode          `json:"syncMode,omitempty"`
+    WriteBackCache  *bool             `json:"writeBackCache,omitempty"`
+    DedupMode       DedupMode         `json:"dedupMode,omitempty"`
+    // reservation in bytes, set to 0 to remove it
+    ReservationSize *int64            `json:"reservationSize,omitempty"`
+    ReadOnly        *bool             `json:"readOnly,omitempty"`
+    // ZFS user properties to set, other user properties are kept
+    UserProperties  map[string]string `json:"userProperties,omitempty"`
+}
+
+// UpdateVolume updates volume by path, EBADARG NefError is returned if new VolumeSize is less than
+// current one and UpdateVolumeParams.AllowShrink is not set
+func (p *Provider) UpdateVolume(ctx context.Context, path string, params UpdateVolumeParams) error {
     if path == "" {
         return fmt.Errorf("Parameter 'path' is required")
+    } else if err := params.validate(); err != nil {
+        return err
+    }
+
+    if params.VolumeSize > 0 && !params.AllowShrink {
+        volume, err := p.GetVolume(ctx, path)
+        if err != nil {
+            return err
+        } else if params.VolumeSize < volume.VolumeSize {
+            return newBadArgError(
+                "Cannot shrink volume '%s' from %d to %d bytes, set UpdateVolumeParams.AllowShrink to force it",
+                path,
+                volume.VolumeSize,
+                params.VolumeSize,
+            )
+        }
     }
 
     uri :=  fmt.Sprintf("storage/volumes/%s", url.PathEscape(path))
-    return p.sendRequest(http.MethodPut, uri, params)
+    return p.sendRequest(ctx, http.MethodPut, uri, params)
 }
 
 type GetLunMappingsParams struct {
@@ -882,31 +925,22 @@ type GetLunMappingsParams struct {
 }
 
 // GetLunMappings returns NexentaStor lunmappings for given parameters
-func (p *Provider) GetLunMappings(params GetLunMappingsParams) (lunMappings []LunMapping, err error) {
-    reqParams := map[string]string{
-        "fields": "id,volume,targetGroup,hostGroup,lun",
-    }
-    if params.TargetGroup != "" {
-        reqParams["targetGroup"] = params.TargetGroup
-    }
-    if params.Volume != "" {
-        reqParams["volume"] = params.Volume
-    }
-    if params.HostGroup != "" {
-        reqParams["hostGroup"] = params.HostGroup
+func (p *Provider) GetLunMappings(ctx context.Context, params GetLunMappingsParams) (lunMappings []LunMapping, err error) {
+    lunMappings = []LunMapping{}
+
+    it := p.IterateLunMappings(ctx, params, IteratorParams{})
+    for it.Next() {
+        lunMappings = append(lunMappings, it.LunMapping())
     }
-    uri := p.RestClient.BuildURI("san/lunMappings", reqParams)
-    response := nefLunMappingsResponse{}
-    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
-    if err != nil {
-        return lunMappings, err
+    if err := it.Err(); err != nil {
+        return nil, err
     }
 
-    return response.Data, nil
+    return lunMappings, nil
 }
 
 // GetLunMapping returns NexentaStor lunmapping for a volume
-func (p *Provider) GetLunMapping(path string) (lunMapping LunMapping, err error) {
+func (p *Provider) GetLunMapping(ctx context.Context, path string) (lunMapping LunMapping, err error) {
     if path == "" {
             return lunMapping, fmt.Errorf("Volume path is empty")
     }
@@ -915,12 +949,12 @@ func (p *Provider) GetLunMapping(path string) (lunMapping LunMapping, err error)
         "fields": "id,volume,targetGroup,hostGroup,lun",
     })
     response := nefLunMappingsResponse{}
-    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
     if err != nil {
         return lunMapping, err
     }
    if len(response.Data) == 0 {
-        return lunMapping, &NefError{Code: "ENOENT", Err: fmt.Errorf("lunMapping '%s' not found", path)}
+        return lunMapping, p.notExistError(ctx, path, fmt.Errorf("lunMapping '%s' not found", path))
     }
 
     return response.Data[0], nil
@@ -934,12 +968,12 @@ type CreateRemoteInitiatorParams struct {
 }
 
 // CreateRemoteInitiator - create new remote initiator in NexentaStor
-func (p *Provider) CreateRemoteInitiator(params CreateRemoteInitiatorParams) error {
+func (p *Provider) CreateRemoteInitiator(ctx context.Context, params CreateRemoteInitiatorParams) error {
     if params.Name == "" || params.ChapSecret == "" {
         return fmt.Errorf(
             "Parameters 'Name' and 'ChapSecret' are required, received: %+v", params)
     }
-    err := p.sendRequest(http.MethodPost, "v1.2.6/san/iscsi/remoteInitiators", params)
+    err := p.sendRequest(ctx, http.MethodPost, "v1.2.6/san/iscsi/remoteInitiators", params)
     if err != nil {
         return err
     }
@@ -953,26 +987,26 @@ type UpdateRemoteInitiatorParams struct {
 }
 
 // UpdateRemoteInitiator updates remote initiator for given name
-func (p *Provider) UpdateRemoteInitiator(name string, params UpdateRemoteInitiatorParams) error {
+func (p *Provider) UpdateRemoteInitiator(ctx context.Context, name string, params UpdateRemoteInitiatorParams) error {
     if name == "" {
         return fmt.Errorf("Parameter 'name' is required, received: %+v", name)
     }
 
     uri :=  fmt.Sprintf("v1.2.6/san/iscsi/remoteInitiators/%s", url.PathEscape(name))
-    return p.sendRequest(http.MethodPut, uri, params)
+    return p.sendRequest(ctx, http.MethodPut, uri, params)
 }
 
 // GetRemoteInitiator - returns remote initiator object for given name
-func (p *Provider) GetRemoteInitiator(name string) (remoteInitiator RemoteInitiator, err error) {
+func (p *Provider) GetRemoteInitiator(ctx context.Context, name string) (remoteInitiator RemoteInitiator, err error) {
     if name == "" {
         return remoteInitiator, fmt.Errorf("Remote Initiator name is empty")
     }
     uri := p.RestClient.BuildURI(fmt.Sprintf("v1.2.6/san/iscsi/remoteInitiators/%s", url.PathEscape(name)), map[string]string{})
-    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &remoteInitiator)
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &remoteInitiator)
     return remoteInitiator, err
 }
 
-func (p *Provider) GetISCSITarget(name string) (target ISCSITarget, err error) {
+func (p *Provider) GetISCSITarget(ctx context.Context, name string) (target ISCSITarget, err error) {
     if name == "" {
         return target, fmt.Errorf("iSCSI target name is empty")
     }
@@ -982,14 +1016,14 @@ func (p *Provider) GetISCSITarget(name string) (target ISCSITarget, err error) {
         "fields": "name,state,authentication,alias,chapSecretSet,chapUser,portals",
     })
     response := nefTargetsResponse{}
-    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &response)
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response)
 
     if err != nil {
         return target, err
     }
 
     if len(response.Data) == 0 {
-        return target, &NefError{Code: "ENOENT", Err: fmt.Errorf("iSCSI target '%s' not found", name)}
+        return target, p.notExistError(ctx, name, fmt.Errorf("iSCSI target '%s' not found", name))
     }
 
     return response.Data[0], nil
@@ -1003,11 +1037,11 @@ type CreateISCSITargetParams struct {
 }
 
 // CreateISCSITarget - create new iSCSI target on NexentaStor
-func (p *Provider) CreateISCSITarget (params CreateISCSITargetParams) error {
+func (p *Provider) CreateISCSITarget (ctx context.Context, params CreateISCSITargetParams) error {
     if params.Name == "" {
         return fmt.Errorf("Parameters 'Name' and 'Portal' are required, received: %+v", params)
     }
-    err := p.sendRequest(http.MethodPost, "san/iscsi/targets", params)
+    err := p.sendRequest(ctx, http.MethodPost, "san/iscsi/targets", params)
     if !IsAlreadyExistNefError(err) {
         return err
     }
@@ -1020,19 +1054,19 @@ type UpdateISCSITargetParams struct {
 }
 
 // UpdateISCSITarget - update existing iSCSI target
-func (p *Provider) UpdateISCSITarget(name string, params UpdateISCSITargetParams) (err error) {
+func (p *Provider) UpdateISCSITarget(ctx context.Context, name string, params UpdateISCSITargetParams) (err error) {
     if name == "" {
         return fmt.Errorf("iSCSI target name must not be empty.")
     }
 
     uri :=  fmt.Sprintf("san/iscsi/targets/%s", url.PathEscape(name))
-    return p.sendRequest(http.MethodPut, uri, params)
+    return p.sendRequest(ctx, http.MethodPut, uri, params)
 }
 
 // GetTargetGroups - returns the list of targetGroups on NexentaStor
-func (p* Provider) GetTargetGroups() ([]TargetGroup, error) {
+func (p* Provider) GetTargetGroups(ctx context.Context) ([]TargetGroup, error) {
     response := nefTargetGroupsResponse{}
-    err := p.sendRequestWithStruct(http.MethodGet, "san/targetgroups", nil, &response)
+    err := p.sendRequestWithStruct(ctx, http.MethodGet, "san/targetgroups", nil, &response)
     if err != nil {
         return nil, err
     }
@@ -1041,7 +1075,7 @@ func (p* Provider) GetTargetGroups() ([]TargetGroup, error) {
 }
 
 // GetTargetGroup returns TargetGroup by its name
-func (p *Provider) GetTargetGroup(name string) (targetGroup TargetGroup, err error) {
+func (p *Provider) GetTargetGroup(ctx context.Context, name string) (targetGroup TargetGroup, err error) {
     if name == "" {
         return targetGroup, fmt.Errorf("targetGroup name is empty")
     }
@@ -1050,7 +1084,7 @@ func (p *Provider) GetTargetGroup(name string) (targetGroup TargetGroup, err err
         "fields": "name,members",
     })
 
-    err = p.sendRequestWithStruct(http.MethodGet, uri, nil, &targetGroup)
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &targetGroup)
 
     return targetGroup, err
 }
@@ -1067,18 +1101,18 @@ type UpdateTargetGroupParams struct {
 }
 
 // CreateUpdateTargetGroup - create new target group on NexentaStor
-func (p *Provider) CreateUpdateTargetGroup(params CreateTargetGroupParams) error {
+func (p *Provider) CreateUpdateTargetGroup(ctx context.Context, params CreateTargetGroupParams) error {
     if params.Name == "" || len(params.Members) == 0 {
         return fmt.Errorf(
             "Parameters 'Name' and 'Members' are required, received: %+v", params)
     }
-    err := p.sendRequest(http.MethodPost, "san/targetgroups", params)
+    err := p.sendRequest(ctx, http.MethodPost, "san/targetgroups", params)
     if err != nil {
         if !IsAlreadyExistNefError(err) {
             return err
         } else {
             uri :=  fmt.Sprintf("san/targetgroups/%s", url.PathEscape(params.Name))
-            err = p.sendRequest(http.MethodPut, uri, UpdateTargetGroupParams{
+            err = p.sendRequest(ctx, http.MethodPut, uri, UpdateTargetGroupParams{
                 Members: params.Members,
             })
             if err != nil {
@@ -1097,12 +1131,12 @@ type CreateLunMappingParams struct {
 }
 
 // CreateLunMapping - creates lun for given volume
-func (p *Provider) CreateLunMapping(params CreateLunMappingParams) error {
+func (p *Provider) CreateLunMapping(ctx context.Context, params CreateLunMappingParams) error {
     if params.HostGroup == "" || params.Volume == "" || params.TargetGroup == "" {
         return fmt.Errorf(
             "Parameters 'HostGroup', 'Target' and 'TargetGroup' are required, received: %+v", params)
     }
-    err := p.sendRequest(http.MethodPost, "san/lunMappings", params)
+    err := p.sendRequest(ctx, http.MethodPost, "san/lunMappings", params)
     if !IsAlreadyExistNefError(err) {
         return err
     }
@@ -1114,18 +1148,25 @@ type DestroyVolumeParams struct {
     PromoteMostRecentCloneIfExists bool
 }
 
-func (p *Provider) DestroyLunMapping(id string) error {
+func (p *Provider) DestroyLunMapping(ctx context.Context, id string) error {
     if id == "" {
         return fmt.Errorf("LunMapping id is required")
     }
 
     uri := fmt.Sprintf("san/lunMappings/%s", id)
 
-    return p.sendRequest(http.MethodDelete, uri, nil)
+    return p.sendRequest(ctx, http.MethodDelete, uri, nil)
 }
 
-func (p *Provider) DestroyVolume(path string, params DestroyVolumeParams) error {
-    err := p.destroyVolume(path, params.DestroySnapshots)
+func (p *Provider) DestroyVolume(ctx context.Context, path string, params DestroyVolumeParams) error {
+    // clones are promoted and destroy is repeated if the first one fails, steps before the final destroy
+    // are waited in non-blocking mode (see StartJob)
+    stepCtx := ctx
+    if params.PromoteMostRecentCloneIfExists {
+        stepCtx = waitJobs(ctx)
+    }
+
+    err := p.destroyVolume(stepCtx, path, params.DestroySnapshots)
     if err == nil {
         return nil
     } else if !params.PromoteMostRecentCloneIfExists || !IsAlreadyExistNefError(err) {
@@ -1142,7 +1183,7 @@ func (p *Provider) DestroyVolume(path string, params DestroyVolumeParams) error
     for i := 0; i < maxAttemptCount; i++ {
         mostRecentError = nil
 
-        snapshots, err := p.GetSnapshots(path, true)
+        snapshots, err := p.GetSnapshots(ctx, path, true)
         if err != nil {
             mostRecentError = fmt.Errorf("failed to get snapshot list: %s", err)
             break
@@ -1152,7 +1193,7 @@ func (p *Provider) DestroyVolume(path string, params DestroyVolumeParams) error
         var mostRecentClone string
         for _, s := range snapshots {
             // to get "clones" and "creationTxg" fields that are not presented in the list response
-            snapshot, err := p.GetSnapshot(s.Path)
+            snapshot, err := p.GetSnapshot(ctx, s.Path)
             if err != nil {
                 mostRecentError = fmt.Errorf("failed to get '%s' snapshost's info: %s", s.Path, err)
                 break
@@ -1180,14 +1221,14 @@ func (p *Provider) DestroyVolume(path string, params DestroyVolumeParams) error
         }
 
         if mostRecentClone != "" {
-            err := p.PromoteVolume(mostRecentClone)
+            err := p.PromoteVolume(stepCtx, mostRecentClone)
             if err != nil {
                 mostRecentError = fmt.Errorf("failed to promote clone '%s': %s", mostRecentClone, err)
                 continue
             }
         }
 
-        mostRecentError = p.destroyVolume(path, params.DestroySnapshots)
+        mostRecentError = p.destroyVolume(ctx, path, params.DestroySnapshots)
         if mostRecentError == nil {
             return nil
         } else if !IsAlreadyExistNefError(mostRecentError) { // if EEXIST code - volume still has dependent clones
@@ -1203,7 +1244,7 @@ func (p *Provider) DestroyVolume(path string, params DestroyVolumeParams) error
     return mostRecentError
 }
 
-func (p *Provider) destroyVolume(path string, destroySnapshots bool) error {
+func (p *Provider) destroyVolume(ctx context.Context, path string, destroySnapshots bool) error {
     if path == "" {
         return fmt.Errorf("Filesystem path is required")
     }
@@ -1215,7 +1256,7 @@ func (p *Provider) destroyVolume(path string, destroySnapshots bool) error {
         },
     )
 
-    return p.sendRequest(http.MethodDelete, uri, nil)
+    return p.sendRequest(ctx, http.MethodDelete, uri, nil)
 }
 
 // CreateHostGroupParams - params to create a hostGroup
@@ -1226,21 +1267,21 @@ type CreateHostGroupParams struct {
     Name string `json:"name"`
 }
 
-func (p *Provider) CreateHostGroup(params CreateHostGroupParams) error {
+func (p *Provider) CreateHostGroup(ctx context.Context, params CreateHostGroupParams) error {
     if params.Name == "" || len(params.Members) == 0 {
         return fmt.Errorf("HostGroup name and members cannot be empty, got %+v", params)
     }
 
-    err := p.sendRequest(http.MethodPost, "san/hostgroups", params)
+    err := p.sendRequest(ctx, http.MethodPost, "san/hostgroups", params)
     if !IsAlreadyExistNefError(err) {
         return err
     }
     return nil
 }
 
-func (p *Provider) GetHostGroups() (hostGroups []nefHostGroup, err error) {
+func (p *Provider) GetHostGroups(ctx context.Context) (hostGroups []nefHostGroup, err error) {
     response := nefHostGroupsResponse{}
-    err = p.sendRequestWithStruct(http.MethodGet, "san/hostgroups", nil, &response)
+    err = p.sendRequestWithStruct(ctx, http.MethodGet, "san/hostgroups", nil, &response)
     if err != nil {
         return hostGroups, err
     }
@@ -1254,11 +1295,11 @@ type UpdateHostGroupParams struct {
     Members []string `json:"members"`
 }
 
-func (p *Provider) UpdateHostGroup(path string, params UpdateHostGroupParams) error {
+func (p *Provider) UpdateHostGroup(ctx context.Context, path string, params UpdateHostGroupParams) error {
     if path == "" {
         return fmt.Errorf("Parameter 'path' is required to update hostGroup")
     }
 
     uri :=  fmt.Sprintf("storage/hostgroups/%s", url.PathEscape(path))
-    return p.sendRequest(http.MethodPut, uri, params)
+    return p.sendRequest(ctx, http.MethodPut, uri, params)
 }
@@ -0,0 +1,150 @@
+package ns
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// DefaultTokenRefreshWindow - default time before token expiration to log in again
+const DefaultTokenRefreshWindow = time.Minute
+
+// loginTimeout - maximum time of shared login including retries, it doesn't depend on request contexts
+const loginTimeout = time.Minute
+
+// loginCall - login in progress, concurrent requests failed with EAUTH wait for it instead of logging in
+type loginCall struct {
+	done chan struct{}
+	err  error
+}
+
+// setAuthToken updates token used by rest client, expiration time is read from JWT "exp" claim if present
+func (p *Provider) setAuthToken(token string) {
+	p.tokenMux.Lock()
+	defer p.tokenMux.Unlock()
+
+	p.RestClient.SetAuthToken(token)
+	p.tokenGeneration++
+	p.tokenExpires = parseTokenExpiration(token)
+}
+
+// getTokenGeneration returns a number of token updates, it's used to detect if the token
+// was updated by another request while this one was in flight
+func (p *Provider) getTokenGeneration() uint64 {
+	p.tokenMux.Lock()
+	defer p.tokenMux.Unlock()
+	return p.tokenGeneration
+}
+
+// reLogIn logs in again if the token wasn't updated since the generation,
+// concurrent calls share the same login request (single-flight).
+// Shared login runs on its own context, so cancellation of the request started it doesn't fail others.
+func (p *Provider) reLogIn(ctx context.Context, generation uint64) error {
+	p.tokenMux.Lock()
+	if p.tokenGeneration != generation {
+		// token was already updated by another request
+		p.tokenMux.Unlock()
+		return nil
+	}
+	call := p.loginCall
+	if call == nil {
+		call = &loginCall{done: make(chan struct{})}
+		p.loginCall = call
+		go p.runLoginCall(call)
+	}
+	p.tokenMux.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runLoginCall logs in and notifies requests waiting for the login call
+func (p *Provider) runLoginCall(call *loginCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+
+	call.err = p.logInWithRetries(ctx)
+
+	p.tokenMux.Lock()
+	p.loginCall = nil
+	p.tokenMux.Unlock()
+	close(call.done)
+}
+
+// logInWithRetries logs in, login requests failed due to network errors are retried according to retry policy
+func (p *Provider) logInWithRetries(ctx context.Context) error {
+	l := p.Log.WithField("func", "reLogIn()")
+	l.Debugf("log in as '%s'...", p.Username)
+
+	maxAttempts := 1
+	if p.RetryPolicy != nil && p.RetryPolicy.MaxAttempts > 1 {
+		maxAttempts = p.RetryPolicy.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := p.LogIn(ctx)
+		if err == nil || IsNefError(err) || attempt >= maxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		l.WithField("attempt", attempt).Debugf("login failed (attempt %d of %d): %s, retrying...",
+			attempt, maxAttempts, err)
+		if waitErr := p.RetryPolicy.Wait(ctx, attempt); waitErr != nil {
+			return err
+		}
+	}
+}
+
+// refreshAuthToken logs in again if the token expires soon, so requests don't fail with EAUTH
+func (p *Provider) refreshAuthToken(ctx context.Context) {
+	window := p.TokenRefreshWindow
+	if window < 0 {
+		return
+	} else if window == 0 {
+		window = DefaultTokenRefreshWindow
+	}
+
+	p.tokenMux.Lock()
+	expires := p.tokenExpires
+	generation := p.tokenGeneration
+	p.tokenMux.Unlock()
+
+	if expires.IsZero() || time.Until(expires) > window {
+		return
+	}
+
+	l := p.Log.WithField("func", "refreshAuthToken()")
+	l.Debugf("token expires at %s, refreshing...", expires.Format(time.RFC3339))
+	if err := p.reLogIn(ctx, generation); err != nil {
+		// the request will be sent with current token, it's still valid or the request gets EAUTH
+		l.Warnf("failed to refresh token: %s", err)
+	}
+}
+
+// parseTokenExpiration returns JWT "exp" claim value, zero time if the token is not JWT or has no "exp"
+func parseTokenExpiration(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp <= 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,149 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CloneVolumeSnapshotParams - params to clone volume snapshot to a new volume,
+// properties which are not set are inherited by the clone
+type CloneVolumeSnapshotParams struct {
+	// TargetPath - new volume path w/o leading slash (required)
+	TargetPath string `json:"targetPath"`
+
+	// VolumeSize of the clone in bytes, default: size of the source volume.
+	// The clone is expanded after creation, so it must not be less than the source volume size.
+	VolumeSize int64 `json:"-"`
+
+	CompressionMode CompressionMode `json:"compressionMode,omitempty"`
+	SyncMode        SyncMode        `json:"syncMode,omitempty"`
+	WriteBackCache  *bool           `json:"writeBackCache,omitempty"`
+	DedupMode       DedupMode       `json:"dedupMode,omitempty"`
+	ReadOnly        *bool           `json:"readOnly,omitempty"`
+
+	// ZFS user properties, names must be in "module:property" format
+	UserProperties map[string]string `json:"userProperties,omitempty"`
+}
+
+func (params CloneVolumeSnapshotParams) validate() error {
+	if params.VolumeSize < 0 {
+		return newBadArgError("CloneVolumeSnapshotParams.VolumeSize must not be negative, got: %d", params.VolumeSize)
+	} else if err := validateDedupMode("CloneVolumeSnapshotParams", params.DedupMode); err != nil {
+		return err
+	}
+
+	return datasetProperties{
+		compressionMode: params.CompressionMode,
+		syncMode:        params.SyncMode,
+		userProperties:  params.UserProperties,
+	}.validate("CloneVolumeSnapshotParams")
+}
+
+// CloneVolumeSnapshot clones volume snapshot to a new volume and expands it to VolumeSize if it's set.
+// The clone is destroyed if it cannot be expanded, the returned error includes destroy error if it fails too.
+// snapshotPath - "pool/volumeGroup/volume@snapshot"
+func (p *Provider) CloneVolumeSnapshot(ctx context.Context, snapshotPath string, params CloneVolumeSnapshotParams) error {
+	if snapshotPath == "" {
+		return fmt.Errorf("Snapshot path is required")
+	} else if params.TargetPath == "" {
+		return fmt.Errorf("Parameter 'CloneVolumeSnapshotParams.TargetPath' is required")
+	} else if err := params.validate(); err != nil {
+		return err
+	}
+
+	source, _, err := splitSnapshotPath(snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	// checks that the snapshot belongs to a volume and the new size fits it before creating the clone
+	sourceVolume, err := p.GetVolume(ctx, source)
+	if err != nil {
+		return err
+	} else if params.VolumeSize > 0 && params.VolumeSize < sourceVolume.VolumeSize {
+		return newBadArgError(
+			"CloneVolumeSnapshotParams.VolumeSize (%d) must not be less than source volume '%s' size (%d)",
+			params.VolumeSize,
+			source,
+			sourceVolume.VolumeSize,
+		)
+	} else if blockSize := sourceVolume.VolumeBlockSize; params.VolumeSize > 0 && blockSize > 0 &&
+		params.VolumeSize%blockSize != 0 {
+		return newBadArgError(
+			"CloneVolumeSnapshotParams.VolumeSize (%d) must be a multiple of source volume '%s' block size (%d)",
+			params.VolumeSize,
+			source,
+			sourceVolume.VolumeBlockSize,
+		)
+	}
+
+	// the clone is resized after its creation, so the clone job is waited in non-blocking mode
+	resize := params.VolumeSize > 0 && params.VolumeSize != sourceVolume.VolumeSize
+	cloneCtx := ctx
+	if resize {
+		cloneCtx = waitJobs(ctx)
+	}
+
+	uri := fmt.Sprintf("storage/snapshots/%s/clone", url.PathEscape(snapshotPath))
+	if err := p.sendRequest(cloneCtx, http.MethodPost, uri, params); err != nil {
+		return err
+	}
+
+	if !resize {
+		return nil
+	}
+
+	err = p.UpdateVolume(ctx, params.TargetPath, UpdateVolumeParams{VolumeSize: params.VolumeSize})
+	if err != nil {
+		l := p.Log.WithField("func", "CloneVolumeSnapshot()")
+		cleanupCtx, cancel := newCleanupContext()
+		defer cancel()
+		if destroyErr := p.DestroyVolume(cleanupCtx, params.TargetPath, DestroyVolumeParams{}); destroyErr != nil {
+			l.Errorf("failed to destroy clone '%s' after failed resize: %s", params.TargetPath, destroyErr)
+			return fmt.Errorf("%w (failed to destroy clone '%s' after failed resize: %s)",
+				err, params.TargetPath, destroyErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// CloneVolume clones volume to a new volume via implicit snapshot "<sourcePath>@clone-<target volume name>".
+// The snapshot stays as the clone origin, it's reused if it already exists, so failed calls can be retried.
+func (p *Provider) CloneVolume(ctx context.Context, sourcePath string, params CloneVolumeSnapshotParams) error {
+	if sourcePath == "" {
+		return fmt.Errorf("Source volume path is required")
+	} else if params.TargetPath == "" {
+		return fmt.Errorf("Parameter 'CloneVolumeSnapshotParams.TargetPath' is required")
+	} else if err := params.validate(); err != nil {
+		return err
+	}
+
+	snapshotPath := fmt.Sprintf("%s@clone-%s", sourcePath, params.TargetPath[strings.LastIndex(params.TargetPath, "/")+1:])
+
+	createdSnapshot := true
+	err := p.CreateSnapshot(waitJobs(ctx), CreateSnapshotParams{Path: snapshotPath})
+	if IsAlreadyExistNefError(err) {
+		createdSnapshot = false
+	} else if err != nil {
+		return err
+	}
+
+	err = p.CloneVolumeSnapshot(ctx, snapshotPath, params)
+	if err != nil && createdSnapshot {
+		l := p.Log.WithField("func", "CloneVolume()")
+		cleanupCtx, cancel := newCleanupContext()
+		defer cancel()
+		if destroyErr := p.DestroySnapshot(cleanupCtx, snapshotPath); destroyErr != nil {
+			l.Errorf("failed to destroy implicit snapshot '%s' after failed clone: %s", snapshotPath, destroyErr)
+			return fmt.Errorf("%w (failed to destroy implicit snapshot '%s' after failed clone: %s)",
+				err, snapshotPath, destroyErr)
+		}
+	}
+
+	return err
+}
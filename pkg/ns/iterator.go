@@ -0,0 +1,372 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// defaultIteratorPageSize - count of items requested per page by default
+const defaultIteratorPageSize = nsFilesystemListLimit - 1
+
+// IteratorParams - params of iterators over NexentaStor collections
+type IteratorParams struct {
+	// PageSize - maximum count of items requested at once, default and maximum: 99
+	// (NexentaStor returns up to 100 items per request, greater values are reduced)
+	PageSize int
+
+	// StartingToken - a path (or an id for LUN mappings) of the item to start AFTER,
+	// an empty iterator is returned if the item doesn't exist
+	StartingToken string
+}
+
+// pageFetcher requests a page of items, returns count of received items to detect the last page
+type pageFetcher func(ctx context.Context, limit, offset int) (items []interface{}, received int, err error)
+
+// pageIterator lazily loads collection pages and iterates over their items
+type pageIterator struct {
+	ctx      context.Context
+	fetch    pageFetcher
+	tokenOf  func(item interface{}) string
+	pageSize int
+	offset   int
+
+	startingToken      string
+	startingTokenFound bool
+
+	page     []interface{}
+	lastPage bool
+	item     interface{}
+	err      error
+}
+
+func newPageIterator(
+	ctx context.Context,
+	params IteratorParams,
+	offset int,
+	fetch pageFetcher,
+	tokenOf func(item interface{}) string,
+) *pageIterator {
+	it := &pageIterator{
+		ctx:                ctx,
+		fetch:              fetch,
+		tokenOf:            tokenOf,
+		pageSize:           params.PageSize,
+		offset:             offset,
+		startingToken:      params.StartingToken,
+		startingTokenFound: params.StartingToken == "",
+	}
+
+	if it.pageSize < 0 {
+		it.err = fmt.Errorf("Iterator page size must be greater than 0, got: %d", params.PageSize)
+	} else if it.pageSize == 0 || it.pageSize > defaultIteratorPageSize {
+		// a short page is treated as the last one, so page size must not exceed NS limit
+		it.pageSize = defaultIteratorPageSize
+	}
+
+	return it
+}
+
+// next advances the iterator, loads the next page if needed
+func (it *pageIterator) next() bool {
+	for it.err == nil {
+		if len(it.page) == 0 {
+			if it.lastPage {
+				break
+			}
+
+			page, received, err := it.fetch(it.ctx, it.pageSize, it.offset)
+			if err != nil {
+				it.err = err
+				break
+			}
+			it.page = page
+			it.offset += received
+			it.lastPage = received < it.pageSize
+			continue
+		}
+
+		item := it.page[0]
+		it.page = it.page[1:]
+
+		if !it.startingTokenFound {
+			it.startingTokenFound = it.tokenOf(item) == it.startingToken
+			continue
+		}
+
+		it.item = item
+		return true
+	}
+
+	it.item = nil
+	return false
+}
+
+// nextToken returns token of the current item, it can be used as a starting token to continue iteration
+func (it *pageIterator) nextToken() string {
+	if it.item == nil {
+		return ""
+	}
+	return it.tokenOf(it.item)
+}
+
+// FilesystemIterator iterates over filesystems, pages are requested lazily:
+//
+//	it := nsProvider.IterateFilesystems(ctx, "pool/dataset", ns.IteratorParams{})
+//	for it.Next() {
+//		fs := it.Filesystem()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type FilesystemIterator struct {
+	it *pageIterator
+}
+
+// Next advances the iterator to the next filesystem, returns false at the end or on error
+func (i *FilesystemIterator) Next() bool {
+	return i.it.next()
+}
+
+// Filesystem returns current filesystem
+func (i *FilesystemIterator) Filesystem() Filesystem {
+	fs, _ := i.it.item.(Filesystem)
+	return fs
+}
+
+// NextToken returns path of current filesystem to continue iteration after it
+func (i *FilesystemIterator) NextToken() string {
+	return i.it.nextToken()
+}
+
+// Err returns an error occurred during iteration
+func (i *FilesystemIterator) Err() error {
+	return i.it.err
+}
+
+// IterateFilesystems returns an iterator over child filesystems of the parent filesystem
+func (p *Provider) IterateFilesystems(ctx context.Context, parent string, params IteratorParams) *FilesystemIterator {
+	// offset starts from 1 because the list includes parent itself
+	return &FilesystemIterator{newPageIterator(ctx, params, 1, func(ctx context.Context, limit, offset int) (
+		[]interface{},
+		int,
+		error,
+	) {
+		uri := p.RestClient.BuildURI("storage/filesystems", map[string]string{
+			"parent": parent,
+			"limit":  fmt.Sprint(limit),
+			"offset": fmt.Sprint(offset),
+			"fields": nefFilesystemFields,
+		})
+
+		response := nefStorageFilesystemsResponse{}
+		if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+			return nil, 0, err
+		}
+
+		items := []interface{}{}
+		for _, fs := range response.Data {
+			if fs.Path != parent { // exclude parent filesystem from the list
+				items = append(items, fs)
+			}
+		}
+
+		return items, len(response.Data), nil
+	}, func(item interface{}) string {
+		return item.(Filesystem).Path
+	})}
+}
+
+// VolumeIterator iterates over volumes, pages are requested lazily
+type VolumeIterator struct {
+	it *pageIterator
+}
+
+// Next advances the iterator to the next volume, returns false at the end or on error
+func (i *VolumeIterator) Next() bool {
+	return i.it.next()
+}
+
+// Volume returns current volume
+func (i *VolumeIterator) Volume() Volume {
+	volume, _ := i.it.item.(Volume)
+	return volume
+}
+
+// NextToken returns path of current volume to continue iteration after it
+func (i *VolumeIterator) NextToken() string {
+	return i.it.nextToken()
+}
+
+// Err returns an error occurred during iteration
+func (i *VolumeIterator) Err() error {
+	return i.it.err
+}
+
+// IterateVolumes returns an iterator over volumes of the parent volumeGroup
+func (p *Provider) IterateVolumes(ctx context.Context, parent string, params IteratorParams) *VolumeIterator {
+	return &VolumeIterator{newPageIterator(ctx, params, 0, func(ctx context.Context, limit, offset int) (
+		[]interface{},
+		int,
+		error,
+	) {
+		uri := p.RestClient.BuildURI("storage/volumes", map[string]string{
+			"parent": parent,
+			"limit":  fmt.Sprint(limit),
+			"offset": fmt.Sprint(offset),
+		})
+
+		response := nefStorageVolumesResponse{}
+		if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+			return nil, 0, err
+		}
+
+		items := make([]interface{}, 0, len(response.Data))
+		for _, volume := range response.Data {
+			items = append(items, volume)
+		}
+
+		return items, len(response.Data), nil
+	}, func(item interface{}) string {
+		return item.(Volume).Path
+	})}
+}
+
+// SnapshotIterator iterates over snapshots, pages are requested lazily
+type SnapshotIterator struct {
+	it *pageIterator
+}
+
+// Next advances the iterator to the next snapshot, returns false at the end or on error
+func (i *SnapshotIterator) Next() bool {
+	return i.it.next()
+}
+
+// Snapshot returns current snapshot
+func (i *SnapshotIterator) Snapshot() Snapshot {
+	snapshot, _ := i.it.item.(Snapshot)
+	return snapshot
+}
+
+// NextToken returns path of current snapshot to continue iteration after it
+func (i *SnapshotIterator) NextToken() string {
+	return i.it.nextToken()
+}
+
+// Err returns an error occurred during iteration
+func (i *SnapshotIterator) Err() error {
+	return i.it.err
+}
+
+// IterateSnapshots returns an iterator over snapshots of the volume (filesystem or volume),
+// recursive - include snapshots of child datasets
+func (p *Provider) IterateSnapshots(
+	ctx context.Context,
+	volumePath string,
+	recursive bool,
+	params IteratorParams,
+) *SnapshotIterator {
+	it := newPageIterator(ctx, params, 0, func(ctx context.Context, limit, offset int) (
+		[]interface{},
+		int,
+		error,
+	) {
+		uri := p.RestClient.BuildURI("storage/snapshots", map[string]string{
+			"parent":    volumePath,
+			"fields":    nefSnapshotFields,
+			"recursive": strconv.FormatBool(recursive),
+			"limit":     fmt.Sprint(limit),
+			"offset":    fmt.Sprint(offset),
+		})
+
+		response := nefStorageSnapshotsResponse{}
+		if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+			return nil, 0, err
+		}
+
+		items := make([]interface{}, 0, len(response.Data))
+		for _, snapshot := range response.Data {
+			items = append(items, snapshot)
+		}
+
+		return items, len(response.Data), nil
+	}, func(item interface{}) string {
+		return item.(Snapshot).Path
+	})
+
+	if volumePath == "" && it.err == nil {
+		it.err = fmt.Errorf("Snapshots volume path is empty")
+	}
+
+	return &SnapshotIterator{it}
+}
+
+// LunMappingIterator iterates over LUN mappings, pages are requested lazily
+type LunMappingIterator struct {
+	it *pageIterator
+}
+
+// Next advances the iterator to the next LUN mapping, returns false at the end or on error
+func (i *LunMappingIterator) Next() bool {
+	return i.it.next()
+}
+
+// LunMapping returns current LUN mapping
+func (i *LunMappingIterator) LunMapping() LunMapping {
+	lunMapping, _ := i.it.item.(LunMapping)
+	return lunMapping
+}
+
+// NextToken returns id of current LUN mapping to continue iteration after it
+func (i *LunMappingIterator) NextToken() string {
+	return i.it.nextToken()
+}
+
+// Err returns an error occurred during iteration
+func (i *LunMappingIterator) Err() error {
+	return i.it.err
+}
+
+// IterateLunMappings returns an iterator over LUN mappings filtered by params
+func (p *Provider) IterateLunMappings(
+	ctx context.Context,
+	filter GetLunMappingsParams,
+	params IteratorParams,
+) *LunMappingIterator {
+	return &LunMappingIterator{newPageIterator(ctx, params, 0, func(ctx context.Context, limit, offset int) (
+		[]interface{},
+		int,
+		error,
+	) {
+		reqParams := map[string]string{
+			"fields": "id,volume,targetGroup,hostGroup,lun",
+			"limit":  fmt.Sprint(limit),
+			"offset": fmt.Sprint(offset),
+		}
+		if filter.TargetGroup != "" {
+			reqParams["targetGroup"] = filter.TargetGroup
+		}
+		if filter.Volume != "" {
+			reqParams["volume"] = filter.Volume
+		}
+		if filter.HostGroup != "" {
+			reqParams["hostGroup"] = filter.HostGroup
+		}
+
+		response := nefLunMappingsResponse{}
+		uri := p.RestClient.BuildURI("san/lunMappings", reqParams)
+		if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+			return nil, 0, err
+		}
+
+		items := make([]interface{}, 0, len(response.Data))
+		for _, lunMapping := range response.Data {
+			items = append(items, lunMapping)
+		}
+
+		return items, len(response.Data), nil
+	}, func(item interface{}) string {
+		return item.(LunMapping).Id
+	})}
+}
@@ -0,0 +1,263 @@
+package ns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultJobPollInterval - default interval between async job status requests
+	DefaultJobPollInterval = 3 * time.Second
+
+	// DefaultJobTimeout - default time to wait for async job completion
+	DefaultJobTimeout = 60 * time.Second
+)
+
+// JobState - NexentaStor async job state
+type JobState string
+
+// async job states
+const (
+	JobStateRunning JobState = "running"
+	JobStateDone    JobState = "done"
+	JobStateFailed  JobState = "failed"
+)
+
+// JobStatus - NexentaStor async job status
+type JobStatus struct {
+	State JobState
+
+	// Progress - job completion percentage, 0 if NexentaStor doesn't report it
+	Progress int
+
+	// Description - job description reported by NexentaStor
+	Description string
+
+	// Err - job error (always NefError) if State is JobStateFailed
+	Err error
+}
+
+// Job - handle of NexentaStor async job started by a modifying request, see Provider.StartJob()
+type Job struct {
+	// ID - NexentaStor job ID, empty if the request was completed synchronously
+	ID string
+
+	// Method and Path of the request that started the job
+	Method string
+	Path   string
+
+	provider *Provider
+}
+
+func (j *Job) String() string {
+	if j.ID == "" {
+		return fmt.Sprintf("completed job of '%s %s'", j.Method, j.Path)
+	}
+	return fmt.Sprintf("job '%s' of '%s %s'", j.ID, j.Method, j.Path)
+}
+
+// Wait waits for job completion, job timeout is set by ProviderArgs.JobTimeout.
+// If the job is failed or timeout is exceeded, NefError is returned.
+func (j *Job) Wait(ctx context.Context) error {
+	if j.ID == "" {
+		return nil
+	}
+
+	err := j.provider.waitForAsyncJob(ctx, j.ID)
+	setNefErrorRequest(err, j.Method, j.Path)
+
+	return err
+}
+
+// Status returns current job status, an error is returned if status request is failed
+func (j *Job) Status(ctx context.Context) (JobStatus, error) {
+	if j.ID == "" {
+		return JobStatus{State: JobStateDone, Progress: 100}, nil
+	}
+	return j.provider.getJobStatus(ctx, j.ID)
+}
+
+// Progress returns job completion percentage, 0 if NexentaStor doesn't report it
+func (j *Job) Progress(ctx context.Context) (int, error) {
+	status, err := j.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return status.Progress, nil
+}
+
+// Cancel asks NexentaStor to abort the job, changes made by the job so far may be kept
+func (j *Job) Cancel(ctx context.Context) error {
+	if j.ID == "" {
+		return fmt.Errorf("Cannot cancel %s", j)
+	}
+	return j.provider.sendRequest(ctx, http.MethodDelete, fmt.Sprintf("jobStatus/%s", j.ID), nil)
+}
+
+// jobCaptureKey - context key to make first async job of a call non-blocking
+type jobCaptureKey struct{}
+
+// jobCapture - first job started in non-blocking mode, fn passed to StartJob() may make concurrent calls
+type jobCapture struct {
+	mux sync.Mutex
+	job *Job
+}
+
+// StartJob runs fn in non-blocking mode: the first async job started by a provider call in fn
+// is not waited for, its handle is returned instead. The context passed to fn must be used
+// for provider calls. If no job was started (the request was completed synchronously),
+// returned job is already completed. Other jobs started by fn are waited as usual,
+// if fn makes concurrent calls, the job which is started first is returned.
+// Composite calls (e.g. CloneVolume(), RenameFilesystem()) wait for jobs of their internal steps,
+// only the job of the final request is returned.
+//
+//	job, err := nsProvider.StartJob(ctx, func(ctx context.Context) error {
+//		return nsProvider.DestroyFilesystem(ctx, path, ns.DestroyFilesystemParams{DestroySnapshots: true})
+//	})
+//	if err == nil {
+//		err = job.Wait(ctx)
+//	}
+func (p *Provider) StartJob(ctx context.Context, fn func(ctx context.Context) error) (*Job, error) {
+	capture := &jobCapture{}
+	if err := fn(context.WithValue(ctx, jobCaptureKey{}, capture)); err != nil {
+		return nil, err
+	}
+
+	capture.mux.Lock()
+	defer capture.mux.Unlock()
+
+	if capture.job == nil {
+		return &Job{provider: p}, nil
+	}
+	return capture.job, nil
+}
+
+// waitJobs returns context for internal steps of composite calls, their jobs are waited in non-blocking mode,
+// so the next step doesn't start before the previous one is completed (see StartJob)
+func waitJobs(ctx context.Context) context.Context {
+	if ctx.Value(jobCaptureKey{}) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, jobCaptureKey{}, (*jobCapture)(nil))
+}
+
+// captureJob stores the job in the context if the call was made in non-blocking mode (see StartJob),
+// returns false if the job should be waited
+func (p *Provider) captureJob(ctx context.Context, jobID, method, path string) bool {
+	capture, ok := ctx.Value(jobCaptureKey{}).(*jobCapture)
+	if !ok || capture == nil {
+		return false
+	}
+
+	capture.mux.Lock()
+	defer capture.mux.Unlock()
+
+	if capture.job != nil {
+		return false
+	}
+
+	capture.job = &Job{
+		ID:       jobID,
+		Method:   method,
+		Path:     path,
+		provider: p,
+	}
+
+	return true
+}
+
+// getJobStatus requests job status, job failures are returned in JobStatus.Err.
+// Auth token is refreshed as for other requests, so long jobs don't fail with EAUTH.
+func (p *Provider) getJobStatus(ctx context.Context, jobID string) (JobStatus, error) {
+	uri := fmt.Sprintf("jobStatus/%s", jobID)
+
+	statusCode, bodyBytes, err := p.sendWithAuth(ctx, http.MethodGet, uri, nil)
+	if err != nil { // request failed
+		return JobStatus{}, err
+	}
+
+	switch statusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent: // job is completed
+		return JobStatus{State: JobStateDone, Progress: 100}, nil
+	case http.StatusAccepted: // job is in progress
+		response := nefJobStatusResponse{}
+		if err := json.Unmarshal(bodyBytes, &response); err != nil {
+			return JobStatus{}, fmt.Errorf("Cannot parse NS response '%s' to '%+v': %s", bodyBytes, response, err)
+		}
+		return JobStatus{
+			State:       JobStateRunning,
+			Progress:    response.Progress,
+			Description: response.Description,
+		}, nil
+	case http.StatusUnauthorized:
+		if nefError := p.parseNefError(bodyBytes, "checking job status"); IsAuthNefError(nefError) {
+			return JobStatus{}, nefError
+		}
+	}
+
+	// job is failed
+	nefError := parseNefErrorResponse(bodyBytes, "Job was finished with error")
+	if nefError == nil {
+		nefError = &NefError{
+			Err: fmt.Errorf(
+				"Job request returned %d code, but response body doesn't contain explanation: %s",
+				statusCode,
+				bodyBytes,
+			),
+			Code: NefCodeFailed,
+		}
+	}
+	nefError.StatusCode = statusCode
+
+	return JobStatus{State: JobStateFailed, Err: nefError}, nil
+}
+
+// waitForAsyncJob - keep asking for job status while it's not completed,
+// return NefError if the job is failed or timeout exceeded, ctx error if ctx is done
+func (p *Provider) waitForAsyncJob(ctx context.Context, jobID string) error {
+	l := p.Log.WithField("job", jobID)
+
+	pollInterval := p.JobPollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultJobPollInterval
+	}
+	jobTimeout := p.JobTimeout
+	if jobTimeout <= 0 {
+		jobTimeout = DefaultJobTimeout
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	timeout := time.NewTimer(jobTimeout)
+	defer timeout.Stop()
+	startTime := time.Now()
+
+	for {
+		select {
+		case <-timer.C:
+			jobDone, err := p.IsJobDone(ctx, jobID)
+			if err != nil { // request or job failed
+				return err
+			} else if jobDone { // job is completed
+				return nil
+			}
+
+			waitingTime := time.Since(startTime)
+			if waitingTime >= pollInterval {
+				l.Warnf("waiting job for %.0fs...", waitingTime.Seconds())
+			}
+			timer.Reset(pollInterval)
+		case <-timeout.C:
+			return &NefError{
+				Err:  fmt.Errorf("Checking job '%s' status timeout exceeded (%s)", jobID, jobTimeout),
+				Code: NefCodeTimeout,
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -1,28 +1,159 @@
 package ns
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+)
+
+// NexentaStor error codes
+const (
+	NefCodeAlreadyExist = "EEXIST"
+	NefCodeNotExist     = "ENOENT"
+	NefCodeBusy         = "EBUSY"
+	NefCodeAuth         = "EAUTH"
+	NefCodeBadArg       = "EBADARG"
+	NefCodeAccess       = "EACCES"
+	NefCodeNoSpace      = "ENOSPC"
+	NefCodeTimeout      = "ETIMEDOUT"
+	NefCodeCanceled     = "ECANCELED"
+	NefCodeNotSupported = "ENOTSUP"
+	NefCodeFailed       = "EFAILED"
+)
+
+// Sentinel errors to match NefError by code using errors.Is(), e.g. errors.Is(err, ns.ErrNotExist)
+var (
+	ErrAlreadyExist = &NefError{Code: NefCodeAlreadyExist}
+	ErrNotExist     = &NefError{Code: NefCodeNotExist}
+	ErrBusy         = &NefError{Code: NefCodeBusy}
+	ErrAuth         = &NefError{Code: NefCodeAuth}
+	ErrBadArg       = &NefError{Code: NefCodeBadArg}
+	ErrAccess       = &NefError{Code: NefCodeAccess}
+	ErrNoSpace      = &NefError{Code: NefCodeNoSpace}
+	ErrTimeout      = &NefError{Code: NefCodeTimeout}
+	ErrCanceled     = &NefError{Code: NefCodeCanceled}
+	ErrNotSupported = &NefError{Code: NefCodeNotSupported}
+	ErrFailed       = &NefError{Code: NefCodeFailed}
 )
 
 // NefError - nef error format
 type NefError struct {
-	Err  error
+	// Err - underlying error or explanation if the error is not returned by NexentaStor
+	Err error
+
+	// Code - NEF error code, e.g. "ENOENT"
 	Code string
+
+	// Name, Message and Errors - NEF error response fields
+	Name    string
+	Message string
+	Errors  json.RawMessage
+
+	// StatusCode, Method and Path of the failed request if known
+	StatusCode int
+	Method     string
+	Path       string
+
+	// prefix describes an operation that failed
+	prefix string
 }
 
 func (e *NefError) Error() string {
-	return fmt.Sprintf("%s [code: %s]", e.Err, e.Code)
+	parts := []string{}
+	if e.prefix != "" {
+		parts = append(parts, e.prefix)
+	}
+	if e.Method != "" {
+		request := fmt.Sprintf("'%s %s'", e.Method, e.Path)
+		if e.StatusCode != 0 {
+			request = fmt.Sprintf("%s (%d)", request, e.StatusCode)
+		}
+		parts = append(parts, request)
+	}
+	if e.Err != nil {
+		parts = append(parts, e.Err.Error())
+	}
+	if e.Name != "" {
+		parts = append(parts, e.Name)
+	}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+
+	message := strings.Join(parts, ": ")
+	if message == "" {
+		message = "NexentaStor error"
+	}
+	if len(e.Errors) > 0 && string(e.Errors) != "null" {
+		message = fmt.Sprintf("%s, errors: %s", message, e.Errors)
+	}
+
+	return fmt.Sprintf("%s [code: %s]", message, e.Code)
+}
+
+// Unwrap returns underlying error
+func (e *NefError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error has the same code as target NefError (see sentinel errors)
+func (e *NefError) Is(target error) bool {
+	t, ok := target.(*NefError)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// newBadArgError returns NefError with "EBADARG" code for params rejected before sending a request
+func newBadArgError(format string, args ...interface{}) *NefError {
+	return &NefError{
+		Err:  fmt.Errorf(format, args...),
+		Code: NefCodeBadArg,
+	}
+}
+
+// parseNefErrorResponse parses NEF error response body, returns nil if the body is not an error
+func parseNefErrorResponse(bodyBytes []byte, prefix string) *NefError {
+	response := struct {
+		Name    string          `json:"name"`
+		Message string          `json:"message"`
+		Errors  json.RawMessage `json:"errors"`
+		Code    string          `json:"code"`
+	}{}
+
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil
+	} else if response.Name == "" && response.Message == "" && len(response.Errors) == 0 {
+		return nil
+	}
+
+	return &NefError{
+		Code:    response.Code,
+		Name:    response.Name,
+		Message: response.Message,
+		Errors:  response.Errors,
+		prefix:  prefix,
+	}
+}
+
+// setNefErrorRequest sets request method and path of NefError if they are not set yet
+func setNefErrorRequest(err error, method, path string) {
+	var nefErr *NefError
+	if errors.As(err, &nefErr) && nefErr.Method == "" {
+		nefErr.Method = method
+		nefErr.Path = path
+	}
 }
 
-// IsNefError - checks if an error is an NefError
+// IsNefError - checks if an error is an NefError or wraps it
 func IsNefError(err error) bool {
-	_, ok := err.(*NefError)
-	return ok
+	var nefErr *NefError
+	return errors.As(err, &nefErr)
 }
 
 // GetNefErrorCode - treats an error as NefError and returns its code in case of success
 func GetNefErrorCode(err error) string {
-	if nefErr, ok := err.(*NefError); ok {
+	var nefErr *NefError
+	if errors.As(err, &nefErr) {
 		return nefErr.Code
 	}
 	return ""
@@ -30,26 +161,26 @@ func GetNefErrorCode(err error) string {
 
 // IsAlreadyExistNefError treats an error as NefError and returns true if its code is "EEXIST"
 func IsAlreadyExistNefError(err error) bool {
-	return GetNefErrorCode(err) == "EEXIST"
+	return errors.Is(err, ErrAlreadyExist)
 }
 
 // IsNotExistNefError treats an error as NefError and returns true if its code is "ENOENT"
 func IsNotExistNefError(err error) bool {
-	return GetNefErrorCode(err) == "ENOENT"
+	return errors.Is(err, ErrNotExist)
 }
 
 // IsBusyNefError treats an error as NefError and returns true if its code is "EBUSY"
 // Example: filesystem cannot be deleted because it has snapshots
 func IsBusyNefError(err error) bool {
-	return GetNefErrorCode(err) == "EBUSY"
+	return errors.Is(err, ErrBusy)
 }
 
 // IsAuthNefError treats an error as NefError and returns true if its code is "EAUTH"
 func IsAuthNefError(err error) bool {
-	return GetNefErrorCode(err) == "EAUTH" //TODO use constants
+	return errors.Is(err, ErrAuth)
 }
 
 // IsBadArgNefError treats an error as NefError and returns true if its code is "EBADARG"
 func IsBadArgNefError(err error) bool {
-	return GetNefErrorCode(err) == "EBADARG"
+	return errors.Is(err, ErrBadArg)
 }
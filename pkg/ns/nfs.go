@@ -0,0 +1,291 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// NfsSecurityMode - NFS security flavor of a security context
+type NfsSecurityMode string
+
+const (
+	// NfsSecurityModeSys - AUTH_SYS, UNIX uid/gid provided by clients are trusted
+	NfsSecurityModeSys NfsSecurityMode = "sys"
+
+	// NfsSecurityModeNone - AUTH_NONE, all clients are mapped to Anon user
+	NfsSecurityModeNone NfsSecurityMode = "none"
+
+	// NfsSecurityModeKrb5 - Kerberos v5 authentication
+	NfsSecurityModeKrb5 NfsSecurityMode = "krb5"
+
+	// NfsSecurityModeKrb5i - Kerberos v5 authentication with integrity checks
+	NfsSecurityModeKrb5i NfsSecurityMode = "krb5i"
+
+	// NfsSecurityModeKrb5p - Kerberos v5 authentication with integrity checks and privacy (encryption)
+	NfsSecurityModeKrb5p NfsSecurityMode = "krb5p"
+)
+
+// NfsVersion - NFS protocol version
+type NfsVersion string
+
+const (
+	// NfsVersion3 - NFSv3
+	NfsVersion3 NfsVersion = "3"
+
+	// NfsVersion4 - NFSv4
+	NfsVersion4 NfsVersion = "4"
+)
+
+// defaultNfsRuleEtype - entity type of default NFS rules
+const defaultNfsRuleEtype = "fqdn"
+
+// NfsSecurityContext - NFS share access rules applied to clients using one of the security modes
+type NfsSecurityContext struct {
+	SecurityModes []NfsSecurityMode `json:"securityModes"`
+
+	// ReadWriteList and ReadOnlyList - clients with read-write and read-only access
+	ReadWriteList []NfsRuleList `json:"readWriteList"`
+	ReadOnlyList  []NfsRuleList `json:"readOnlyList"`
+
+	// RootList - clients which root users keep root access (are not mapped to RootMapping user)
+	RootList []NfsRuleList `json:"root,omitempty"`
+
+	// NoneList - clients without access
+	NoneList []NfsRuleList `json:"none,omitempty"`
+}
+
+// NfsShare - NexentaStor NFS share of a filesystem
+type NfsShare struct {
+	Filesystem string `json:"filesystem"`
+	MountPoint string `json:"mountPoint"`
+	ShareState string `json:"shareState"`
+
+	// Anon - user (name or uid) which unknown users are mapped to
+	Anon string `json:"anon"`
+
+	// RootMapping - user (name or uid) which root users of clients w/o root access are mapped to
+	RootMapping string `json:"rootMapping,omitempty"`
+
+	// Nosuid - set-uid/set-gid programs are disallowed on clients
+	Nosuid bool `json:"nosuid"`
+
+	// Nohide - child filesystems are visible to NFSv3 clients w/o mounting them
+	Nohide bool `json:"nohide"`
+
+	// Versions - NFS versions allowed for the share, empty if all enabled versions are allowed
+	Versions []NfsVersion `json:"versions,omitempty"`
+
+	SecurityContexts []NfsSecurityContext `json:"securityContexts"`
+}
+
+func (share *NfsShare) String() string {
+	return share.Filesystem
+}
+
+// GetNfsShare returns NFS share of the filesystem
+func (p *Provider) GetNfsShare(ctx context.Context, path string) (NfsShare, error) {
+	if path == "" {
+		return NfsShare{}, fmt.Errorf("Filesystem path is required")
+	}
+
+	uri := fmt.Sprintf("nas/nfs/%s", url.PathEscape(path))
+
+	share := NfsShare{}
+	if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &share); err != nil {
+		return NfsShare{}, err
+	}
+
+	return share, nil
+}
+
+// ListNfsShares returns NFS shares of the parent filesystem and its descendants, all shares if parent is empty
+func (p *Provider) ListNfsShares(ctx context.Context, parent string) ([]NfsShare, error) {
+	it := newPageIterator(ctx, IteratorParams{}, 0, func(ctx context.Context, limit, offset int) (
+		[]interface{},
+		int,
+		error,
+	) {
+		query := map[string]string{
+			"limit":  fmt.Sprint(limit),
+			"offset": fmt.Sprint(offset),
+		}
+		if parent != "" {
+			query["parent"] = parent
+		}
+		uri := p.RestClient.BuildURI("nas/nfs", query)
+
+		response := nefNasNfsResponse{}
+		if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+			return nil, 0, err
+		}
+
+		items := make([]interface{}, 0, len(response.Data))
+		for _, share := range response.Data {
+			items = append(items, share)
+		}
+
+		return items, len(response.Data), nil
+	}, func(item interface{}) string {
+		return item.(NfsShare).Filesystem
+	})
+
+	shares := []NfsShare{}
+	for it.next() {
+		shares = append(shares, it.item.(NfsShare))
+	}
+	if it.err != nil {
+		return nil, it.err
+	}
+
+	return shares, nil
+}
+
+// UpdateNfsShareParams - params to update NFS share, only set fields are changed
+type UpdateNfsShareParams struct {
+	Anon        string       `json:"anon,omitempty"`
+	RootMapping string       `json:"rootMapping,omitempty"`
+	Nosuid      *bool        `json:"nosuid,omitempty"`
+	Nohide      *bool        `json:"nohide,omitempty"`
+	Versions    []NfsVersion `json:"versions,omitempty"`
+
+	// SecurityContexts replace all contexts of the share if set
+	SecurityContexts []NfsSecurityContext `json:"securityContexts,omitempty"`
+}
+
+// UpdateNfsShare updates NFS share of the filesystem in place, clients keep their mounts
+func (p *Provider) UpdateNfsShare(ctx context.Context, path string, params UpdateNfsShareParams) error {
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	}
+
+	if err := validateNfsSecurityContexts("UpdateNfsShareParams", params.SecurityContexts); err != nil {
+		return err
+	} else if err := validateNfsVersions("UpdateNfsShareParams", params.Versions); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("nas/nfs/%s", url.PathEscape(path))
+
+	return p.sendRequest(ctx, http.MethodPut, uri, params)
+}
+
+// validate checks NFS share params before sending them to NexentaStor
+func (params CreateNfsShareParams) validate() error {
+	if len(params.SecurityContexts) > 0 {
+		if len(params.ReadWriteList) > 0 || len(params.ReadOnlyList) > 0 || len(params.RootList) > 0 ||
+			len(params.SecurityModes) > 0 {
+			return newBadArgError(
+				"CreateNfsShareParams.SecurityContexts cannot be used with ReadWriteList, ReadOnlyList, " +
+					"RootList or SecurityModes",
+			)
+		}
+		if err := validateNfsSecurityContexts("CreateNfsShareParams", params.SecurityContexts); err != nil {
+			return err
+		}
+	} else if err := validateNfsSecurityModes("CreateNfsShareParams.SecurityModes", params.SecurityModes); err != nil {
+		return err
+	} else if err := validateNfsRules("CreateNfsShareParams.ReadWriteList", params.ReadWriteList); err != nil {
+		return err
+	} else if err := validateNfsRules("CreateNfsShareParams.ReadOnlyList", params.ReadOnlyList); err != nil {
+		return err
+	} else if err := validateNfsRules("CreateNfsShareParams.RootList", params.RootList); err != nil {
+		return err
+	}
+
+	return validateNfsVersions("CreateNfsShareParams", params.Versions)
+}
+
+// defaultSecurityContext returns the only security context of the share created w/o SecurityContexts,
+// "*" gets read-write access if no rules are set, empty lists are filled with "none"
+func (params CreateNfsShareParams) defaultSecurityContext() NfsSecurityContext {
+	securityModes := params.SecurityModes
+	if len(securityModes) == 0 {
+		securityModes = []NfsSecurityMode{NfsSecurityModeSys}
+	}
+
+	readWriteList := params.ReadWriteList
+	readOnlyList := params.ReadOnlyList
+	if len(readWriteList) == 0 && len(readOnlyList) == 0 {
+		readWriteList = []NfsRuleList{{Entity: "*", Etype: defaultNfsRuleEtype}}
+	} else if len(readWriteList) == 0 {
+		readWriteList = []NfsRuleList{{Entity: "none", Etype: defaultNfsRuleEtype}}
+	}
+	if len(readOnlyList) == 0 {
+		readOnlyList = []NfsRuleList{{Entity: "none", Etype: defaultNfsRuleEtype}}
+	}
+
+	return NfsSecurityContext{
+		SecurityModes: securityModes,
+		ReadWriteList: readWriteList,
+		ReadOnlyList:  readOnlyList,
+		RootList:      params.RootList,
+	}
+}
+
+// validateNfsSecurityContexts checks that contexts have valid security modes, each mode is used once
+func validateNfsSecurityContexts(prefix string, securityContexts []NfsSecurityContext) error {
+	used := map[NfsSecurityMode]bool{}
+	for i, securityContext := range securityContexts {
+		field := fmt.Sprintf("%s.SecurityContexts[%d].SecurityModes", prefix, i)
+		if len(securityContext.SecurityModes) == 0 {
+			return newBadArgError("%s must not be empty", field)
+		} else if err := validateNfsSecurityModes(field, securityContext.SecurityModes); err != nil {
+			return err
+		}
+		lists := map[string][]NfsRuleList{
+			"ReadWriteList": securityContext.ReadWriteList,
+			"ReadOnlyList":  securityContext.ReadOnlyList,
+			"RootList":      securityContext.RootList,
+			"NoneList":      securityContext.NoneList,
+		}
+		for _, name := range []string{"ReadWriteList", "ReadOnlyList", "RootList", "NoneList"} {
+			if err := validateNfsRules(fmt.Sprintf("%s.SecurityContexts[%d].%s", prefix, i, name), lists[name]); err != nil {
+				return err
+			}
+		}
+		for _, mode := range securityContext.SecurityModes {
+			if used[mode] {
+				return newBadArgError("%s: security mode '%s' is used in several contexts", field, mode)
+			}
+			used[mode] = true
+		}
+	}
+	return nil
+}
+
+func validateNfsSecurityModes(field string, modes []NfsSecurityMode) error {
+	for _, mode := range modes {
+		switch mode {
+		case NfsSecurityModeSys, NfsSecurityModeNone, NfsSecurityModeKrb5, NfsSecurityModeKrb5i, NfsSecurityModeKrb5p:
+		default:
+			return newBadArgError(
+				"%s must contain only '%s', '%s', '%s', '%s', '%s', got: '%s'",
+				field,
+				NfsSecurityModeSys,
+				NfsSecurityModeNone,
+				NfsSecurityModeKrb5,
+				NfsSecurityModeKrb5i,
+				NfsSecurityModeKrb5p,
+				mode,
+			)
+		}
+	}
+	return nil
+}
+
+func validateNfsVersions(prefix string, versions []NfsVersion) error {
+	for _, version := range versions {
+		if version != NfsVersion3 && version != NfsVersion4 {
+			return newBadArgError(
+				"%s.Versions must contain only '%s', '%s', got: '%s'",
+				prefix,
+				NfsVersion3,
+				NfsVersion4,
+				version,
+			)
+		}
+	}
+	return nil
+}
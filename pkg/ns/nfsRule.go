@@ -0,0 +1,226 @@
+package ns
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// NFS rule entity types
+const (
+	// NfsRuleEtypeFqdn - a host name or an IP address, "*" for all clients and "none" for no clients
+	NfsRuleEtypeFqdn = "fqdn"
+
+	// NfsRuleEtypeNetwork - an IP network, Entity is a network address and Mask is a prefix length
+	NfsRuleEtypeNetwork = "network"
+
+	// NfsRuleEtypeDomain - all hosts of a DNS domain
+	NfsRuleEtypeDomain = "domain"
+
+	// NfsRuleEtypeNetgroup - an NIS/LDAP netgroup
+	NfsRuleEtypeNetgroup = "netgroup"
+)
+
+// hostnameRegexp - RFC 1123 host name, labels are separated by dots
+var hostnameRegexp = regexp.MustCompile(
+	`^([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]{0,61}[a-zA-Z0-9])(\.([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]{0,61}[a-zA-Z0-9]))*$`,
+)
+
+// netgroupRegexp - netgroup name w/o spaces and separators
+var netgroupRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// NewNfsHostRule returns a rule for a host name or an IP address
+func NewNfsHostRule(host string) NfsRuleList {
+	return NfsRuleList{Etype: NfsRuleEtypeFqdn, Entity: host}
+}
+
+// NewNfsNetworkRule returns a rule for an IP network in CIDR notation, e.g. "10.0.0.0/8"
+func NewNfsNetworkRule(cidr string) (NfsRuleList, error) {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return NfsRuleList{}, newBadArgError("NFS rule network must be in CIDR notation, got: '%s'", cidr)
+	} else if !ip.Equal(network.IP) {
+		return NfsRuleList{}, newBadArgError(
+			"NFS rule network '%s' has host bits set, did you mean '%s'?",
+			cidr,
+			network.String(),
+		)
+	}
+
+	mask, _ := network.Mask.Size()
+	return NfsRuleList{Etype: NfsRuleEtypeNetwork, Entity: network.IP.String(), Mask: mask}, nil
+}
+
+// NewNfsDomainRule returns a rule for all hosts of a DNS domain, leading dot is optional
+func NewNfsDomainRule(domain string) NfsRuleList {
+	return NfsRuleList{Etype: NfsRuleEtypeDomain, Entity: strings.TrimPrefix(domain, ".")}
+}
+
+// NewNfsNetgroupRule returns a rule for a netgroup
+func NewNfsNetgroupRule(netgroup string) NfsRuleList {
+	return NfsRuleList{Etype: NfsRuleEtypeNetgroup, Entity: netgroup}
+}
+
+// String returns rule in ParseNfsRules() format
+func (rule NfsRuleList) String() string {
+	switch rule.Etype {
+	case NfsRuleEtypeNetwork:
+		return (&net.IPNet{IP: net.ParseIP(rule.Entity), Mask: nfsRuleMask(rule)}).String()
+	case NfsRuleEtypeDomain:
+		return "." + rule.Entity
+	case NfsRuleEtypeNetgroup:
+		return "@" + rule.Entity
+	}
+	return rule.Entity
+}
+
+func nfsRuleMask(rule NfsRuleList) net.IPMask {
+	if ip := net.ParseIP(rule.Entity); ip != nil && ip.To4() == nil {
+		return net.CIDRMask(rule.Mask, 8*net.IPv6len)
+	}
+	return net.CIDRMask(rule.Mask, 8*net.IPv4len)
+}
+
+// validate checks that rule entity matches its type, rules w/o type are sent to NexentaStor as is
+func (rule NfsRuleList) validate(field string) error {
+	if rule.Etype == "" {
+		return nil
+	}
+
+	if rule.Etype != NfsRuleEtypeNetwork && rule.Mask != 0 {
+		return newBadArgError("%s: Mask can be set for '%s' rules only, got: %+v", field, NfsRuleEtypeNetwork, rule)
+	}
+
+	switch rule.Etype {
+	case NfsRuleEtypeFqdn:
+		if rule.Entity == "*" || rule.Entity == "none" || net.ParseIP(rule.Entity) != nil {
+			return nil
+		} else if len(rule.Entity) <= 253 && hostnameRegexp.MatchString(rule.Entity) {
+			return nil
+		}
+		return newBadArgError("%s: '%s' is not a valid host name or IP address", field, rule.Entity)
+	case NfsRuleEtypeNetwork:
+		ip := net.ParseIP(rule.Entity)
+		if ip == nil {
+			return newBadArgError("%s: '%s' is not a valid network address", field, rule.Entity)
+		}
+		mask := nfsRuleMask(rule)
+		if mask == nil {
+			return newBadArgError("%s: mask %d is out of range for network '%s'", field, rule.Mask, rule.Entity)
+		} else if !ip.Mask(mask).Equal(ip) {
+			return newBadArgError("%s: network '%s/%d' has host bits set", field, rule.Entity, rule.Mask)
+		}
+		return nil
+	case NfsRuleEtypeDomain:
+		if len(rule.Entity) <= 253 && hostnameRegexp.MatchString(rule.Entity) {
+			return nil
+		}
+		return newBadArgError("%s: '%s' is not a valid domain name", field, rule.Entity)
+	case NfsRuleEtypeNetgroup:
+		if netgroupRegexp.MatchString(rule.Entity) {
+			return nil
+		}
+		return newBadArgError("%s: '%s' is not a valid netgroup name", field, rule.Entity)
+	}
+
+	return newBadArgError(
+		"%s: Etype must be one of '%s', '%s', '%s', '%s', got: '%s'",
+		field,
+		NfsRuleEtypeFqdn,
+		NfsRuleEtypeNetwork,
+		NfsRuleEtypeDomain,
+		NfsRuleEtypeNetgroup,
+		rule.Etype,
+	)
+}
+
+// validateNfsRules checks all rules of the list
+func validateNfsRules(field string, rules []NfsRuleList) error {
+	for _, rule := range rules {
+		if err := rule.validate(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseNfsRule parses a rule from a string: "10.0.0.0/8" - network, ".example.com" - domain, "@group" - netgroup,
+// anything else is a host name or an IP address ("*" - all clients, "none" - no clients)
+func ParseNfsRule(s string) (NfsRuleList, error) {
+	var rule NfsRuleList
+	switch {
+	case strings.Contains(s, "/"):
+		return NewNfsNetworkRule(s)
+	case strings.HasPrefix(s, "."):
+		rule = NewNfsDomainRule(s)
+	case strings.HasPrefix(s, "@"):
+		rule = NewNfsNetgroupRule(s[1:])
+	default:
+		rule = NewNfsHostRule(s)
+	}
+
+	if err := rule.validate("NFS rule"); err != nil {
+		return NfsRuleList{}, err
+	}
+
+	return rule, nil
+}
+
+// ParseNfsRules parses a security context from a comma separated list of "access=rule" items,
+// e.g. "rw=10.0.0.0/8,ro=@netgroup,ro=.example.com,root=admin.example.com".
+// Access is one of "rw", "ro", "root", "none" or "sec" to set security modes, e.g. "sec=krb5:krb5i",
+// security mode is NfsSecurityModeSys if it's not set. See ParseNfsRule() for rules format.
+func ParseNfsRules(s string) (NfsSecurityContext, error) {
+	securityContext := NfsSecurityContext{
+		ReadWriteList: []NfsRuleList{},
+		ReadOnlyList:  []NfsRuleList{},
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return NfsSecurityContext{}, newBadArgError("NFS rules item must be in 'access=rule' format, got: '%s'", item)
+		}
+
+		if parts[0] == "sec" {
+			for _, mode := range strings.Split(parts[1], ":") {
+				securityContext.SecurityModes = append(securityContext.SecurityModes, NfsSecurityMode(mode))
+			}
+			continue
+		}
+
+		rule, err := ParseNfsRule(parts[1])
+		if err != nil {
+			return NfsSecurityContext{}, err
+		}
+
+		switch parts[0] {
+		case "rw":
+			securityContext.ReadWriteList = append(securityContext.ReadWriteList, rule)
+		case "ro":
+			securityContext.ReadOnlyList = append(securityContext.ReadOnlyList, rule)
+		case "root":
+			securityContext.RootList = append(securityContext.RootList, rule)
+		case "none":
+			securityContext.NoneList = append(securityContext.NoneList, rule)
+		default:
+			return NfsSecurityContext{}, newBadArgError(
+				"NFS rules access must be one of 'rw', 'ro', 'root', 'none', 'sec', got: '%s'",
+				parts[0],
+			)
+		}
+	}
+
+	if len(securityContext.SecurityModes) == 0 {
+		securityContext.SecurityModes = []NfsSecurityMode{NfsSecurityModeSys}
+	} else if err := validateNfsSecurityModes("NFS rules 'sec'", securityContext.SecurityModes); err != nil {
+		return NfsSecurityContext{}, err
+	}
+
+	return securityContext, nil
+}
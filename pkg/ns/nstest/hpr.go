@@ -0,0 +1,253 @@
+package nstest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (s *Server) registerHprRoutes() {
+	s.handle(http.MethodGet, "hpr/services", s.getHprServices)
+	s.handle(http.MethodPost, "hpr/services", s.createHprService)
+	s.handle(http.MethodGet, "hpr/services/*", s.getHprService)
+	s.handle(http.MethodDelete, "hpr/services/*", s.destroyHprService)
+	s.handle(http.MethodGet, "hpr/services/*/status", s.getHprServiceStatus)
+	s.handle(http.MethodPost, "hpr/services/*/start", s.startHprService)
+	s.handle(http.MethodPost, "hpr/services/*/stop", s.stopHprService)
+	s.handle(http.MethodPost, "hpr/services/*/send", s.sendHprService)
+}
+
+// hprServiceView returns service w/o its runtime status
+func hprServiceView(service object) object {
+	view := service.copy()
+	delete(view, "status")
+	return view
+}
+
+// hprServiceStatus returns runtime status of the service
+func hprServiceStatus(service object) object {
+	status, _ := service["status"].(object)
+	return status
+}
+
+// hprSnapshotPrefix returns path prefix of snapshots created by the service
+func hprSnapshotPrefix(service object) string {
+	return fmt.Sprintf("%s@hpr-%s-", service.str("sourceDataset"), service.str("name"))
+}
+
+// getHprServices returns HPR services filtered by "sourceDataset" query param
+func (s *Server) getHprServices(c *call) (int, interface{}, *apiError) {
+	sourceDataset := c.query.Get("sourceDataset")
+
+	list := []object{}
+	for _, name := range sortedKeys(s.state.hprServices) {
+		service := s.state.hprServices[name]
+		if sourceDataset == "" || service.str("sourceDataset") == sourceDataset {
+			list = append(list, hprServiceView(service))
+		}
+	}
+
+	list, err := paginate(c, list)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, object{"data": list}, nil
+}
+
+func (s *Server) createHprService(c *call) (int, interface{}, *apiError) {
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	name := props.str("name")
+	if name == "" {
+		return 0, nil, badArgError("Parameter 'name' is required")
+	} else if props.str("destinationDataset") == "" {
+		return 0, nil, badArgError("Parameter 'destinationDataset' is required")
+	} else if mode := props.str("mode"); mode != "incremental" && mode != "full" {
+		return 0, nil, badArgError("Parameter 'mode' must be one of 'incremental', 'full', got: '%s'", mode)
+	} else if interval := props.int64("interval"); interval != 0 && interval < 60 {
+		return 0, nil, badArgError("Parameter 'interval' must be at least 60 seconds")
+	} else if _, ok := s.state.hprServices[name]; ok {
+		return 0, nil, existError("HPR service '%s' already exists", name)
+	}
+
+	if remoteNode, _ := props["remoteNode"].(map[string]interface{}); object(remoteNode).str("host") == "" {
+		return 0, nil, badArgError("Parameter 'remoteNode.host' is required")
+	}
+
+	sourceDataset := props.str("sourceDataset")
+	if _, ok := s.state.filesystems[sourceDataset]; !ok {
+		if _, ok := s.state.volumes[sourceDataset]; !ok {
+			return 0, nil, notFoundError("Dataset '%s' not found", sourceDataset)
+		}
+	}
+
+	state := "disabled"
+	if props.bool("enabled") {
+		state = "idle"
+	}
+	props["status"] = object{"state": state, "progress": 0, "bytesSent": 0}
+	s.state.hprServices[name] = props
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) getHprService(c *call) (int, interface{}, *apiError) {
+	service, ok := s.state.hprServices[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("HPR service '%s' not found", c.params[0])
+	}
+	return http.StatusOK, hprServiceView(service), nil
+}
+
+func (s *Server) getHprServiceStatus(c *call) (int, interface{}, *apiError) {
+	service, ok := s.state.hprServices[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("HPR service '%s' not found", c.params[0])
+	}
+	return http.StatusOK, hprServiceStatus(service), nil
+}
+
+func (s *Server) startHprService(c *call) (int, interface{}, *apiError) {
+	service, ok := s.state.hprServices[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("HPR service '%s' not found", c.params[0])
+	}
+
+	service["enabled"] = true
+	status := hprServiceStatus(service)
+	status["state"] = "idle"
+	delete(status, "lastError")
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) stopHprService(c *call) (int, interface{}, *apiError) {
+	service, ok := s.state.hprServices[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("HPR service '%s' not found", c.params[0])
+	}
+
+	service["enabled"] = false
+	status := hprServiceStatus(service)
+	status["state"] = "disabled"
+	status["progress"] = 0
+
+	return http.StatusOK, nil, nil
+}
+
+// sendHprService runs a transfer synchronously: creates a new snapshot of the source dataset
+// and destroys the previous one, there is no remote appliance
+func (s *Server) sendHprService(c *call) (int, interface{}, *apiError) {
+	service, ok := s.state.hprServices[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("HPR service '%s' not found", c.params[0])
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	mode := props.str("mode")
+	if mode == "" {
+		mode = service.str("mode")
+	} else if mode != "incremental" && mode != "full" {
+		return 0, nil, badArgError("Parameter 'mode' must be one of 'incremental', 'full', got: '%s'", mode)
+	}
+
+	status := hprServiceStatus(service)
+	if status.str("state") == "running" {
+		return 0, nil, busyError("HPR service '%s' is running", c.params[0])
+	}
+
+	lastSnapshot := status.str("lastSnapshot")
+	if mode == "incremental" && lastSnapshot != "" {
+		if _, ok := s.state.snapshots[lastSnapshot]; !ok {
+			status["state"] = "faulted"
+			status["lastError"] = fmt.Sprintf("Last replicated snapshot '%s' not found", lastSnapshot)
+			return 0, nil, notFoundError(
+				"Last replicated snapshot '%s' not found, full send is required",
+				lastSnapshot,
+			)
+		}
+	} else {
+		// the first run is always full
+		mode = "full"
+	}
+
+	sourceDataset := service.str("sourceDataset")
+	if !s.state.datasetExists(sourceDataset) {
+		status["state"] = "faulted"
+		status["lastError"] = fmt.Sprintf("Dataset '%s' not found", sourceDataset)
+		return 0, nil, notFoundError("Dataset '%s' not found", sourceDataset)
+	}
+
+	txg := s.state.nextTxg()
+	path := hprSnapshotPrefix(service) + txg
+	s.state.snapshots[path] = object{
+		"path":            path,
+		"name":            path[strings.LastIndex(path, "@")+1:],
+		"parent":          sourceDataset,
+		"clones":          []string{},
+		"creationTxg":     txg,
+		"creationTime":    time.Now().UTC().Format(time.RFC3339),
+		"bytesReferenced": datasetUsedSize,
+		"bytesUsed":       int64(0),
+	}
+
+	if snapshot, ok := s.state.snapshots[lastSnapshot]; ok {
+		if len(snapshot.strings("clones")) == 0 && len(snapshotHolds(snapshot)) == 0 {
+			delete(s.state.snapshots, lastSnapshot)
+		}
+	}
+
+	bytesSent := int64(0)
+	if mode == "full" {
+		bytesSent = datasetUsedSize
+	}
+
+	if service.bool("enabled") {
+		status["state"] = "idle"
+	} else {
+		status["state"] = "disabled"
+	}
+	status["progress"] = 100
+	status["bytesSent"] = bytesSent
+	status["lastMode"] = mode
+	status["lastSnapshot"] = path
+	status["lastSyncTime"] = time.Now().UTC().Format(time.RFC3339)
+	delete(status, "lastError")
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) destroyHprService(c *call) (int, interface{}, *apiError) {
+	service, ok := s.state.hprServices[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("HPR service '%s' not found", c.params[0])
+	} else if hprServiceStatus(service).str("state") == "running" {
+		return 0, nil, busyError("HPR service '%s' is running, stop it first", c.params[0])
+	}
+
+	if c.query.Get("snapshots") == "true" {
+		prefix := hprSnapshotPrefix(service)
+		for _, path := range sortedKeys(s.state.snapshots) {
+			snapshot := s.state.snapshots[path]
+			if !strings.HasPrefix(path, prefix) {
+				continue
+			} else if len(snapshot.strings("clones")) > 0 || len(snapshotHolds(snapshot)) > 0 {
+				return 0, nil, busyError("Snapshot '%s' has clones or holds", path)
+			}
+			delete(s.state.snapshots, path)
+		}
+	}
+
+	delete(s.state.hprServices, c.params[0])
+
+	return http.StatusOK, nil, nil
+}
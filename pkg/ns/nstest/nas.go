@@ -0,0 +1,240 @@
+package nstest
+
+import (
+	"net/http"
+	"strings"
+)
+
+func (s *Server) registerNasRoutes() {
+	s.handle(http.MethodGet, "nas/nfs", s.getNfsShares)
+	s.handle(http.MethodPost, "nas/nfs", s.createNfsShare)
+	s.handle(http.MethodGet, "nas/nfs/*", s.getNfsShare)
+	s.handle(http.MethodPut, "nas/nfs/*", s.updateNfsShare)
+	s.handle(http.MethodDelete, "nas/nfs/*", s.deleteNfsShare)
+
+	s.handle(http.MethodGet, "nas/smb", s.getSmbShares)
+	s.handle(http.MethodPost, "nas/smb", s.createSmbShare)
+	s.handle(http.MethodGet, "nas/smb/*", s.getSmbShare)
+	s.handle(http.MethodPut, "nas/smb/*", s.updateSmbShare)
+	s.handle(http.MethodDelete, "nas/smb/*", s.deleteSmbShare)
+}
+
+func (s *Server) createNfsShare(c *call) (int, interface{}, *apiError) {
+	share := object{}
+	if err := c.decode(&share); err != nil {
+		return 0, nil, err
+	}
+
+	path := share.str("filesystem")
+	if path == "" {
+		return 0, nil, badArgError("Parameter 'filesystem' is required")
+	} else if _, ok := s.state.filesystems[path]; !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", path)
+	} else if _, ok := s.state.nfsShares[path]; ok {
+		return 0, nil, existError("Filesystem '%s' is already shared over NFS", path)
+	}
+
+	share["shareState"] = "online"
+	s.state.nfsShares[path] = share
+
+	return http.StatusCreated, nil, nil
+}
+
+// nfsShareView returns share with the filesystem mount point
+func (st *state) nfsShareView(share object) object {
+	view := share.copy()
+	if fs, ok := st.filesystems[share.str("filesystem")]; ok {
+		view["mountPoint"] = fs.str("mountPoint")
+	}
+	return view
+}
+
+// getNfsShares returns NFS shares of the "parent" query param filesystem and its descendants
+func (s *Server) getNfsShares(c *call) (int, interface{}, *apiError) {
+	parent := c.query.Get("parent")
+
+	list := []object{}
+	for _, path := range sortedKeys(s.state.nfsShares) {
+		if parent == "" || path == parent || isDescendantOf(path, parent) {
+			list = append(list, s.state.nfsShareView(s.state.nfsShares[path]))
+		}
+	}
+
+	list, err := paginate(c, list)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, object{"data": list}, nil
+}
+
+func (s *Server) getNfsShare(c *call) (int, interface{}, *apiError) {
+	share, ok := s.state.nfsShares[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("NFS share for '%s' not found", c.params[0])
+	}
+	return http.StatusOK, s.state.nfsShareView(share), nil
+}
+
+func (s *Server) updateNfsShare(c *call) (int, interface{}, *apiError) {
+	share, ok := s.state.nfsShares[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("NFS share for '%s' not found", c.params[0])
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+	for _, key := range []string{"filesystem", "mountPoint", "shareState"} {
+		if _, ok := props[key]; ok {
+			return 0, nil, badArgError("NFS share property '%s' cannot be changed", key)
+		}
+	}
+	for k, v := range props {
+		share[k] = v
+	}
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) deleteNfsShare(c *call) (int, interface{}, *apiError) {
+	if _, ok := s.state.nfsShares[c.params[0]]; !ok {
+		return 0, nil, notFoundError("NFS share for '%s' not found", c.params[0])
+	}
+	delete(s.state.nfsShares, c.params[0])
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) createSmbShare(c *call) (int, interface{}, *apiError) {
+	share := object{}
+	if err := c.decode(&share); err != nil {
+		return 0, nil, err
+	}
+
+	path := share.str("filesystem")
+	if path == "" {
+		return 0, nil, badArgError("Parameter 'filesystem' is required")
+	} else if _, ok := s.state.filesystems[path]; !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", path)
+	} else if _, ok := s.state.smbShares[path]; ok {
+		return 0, nil, existError("Filesystem '%s' is already shared over SMB", path)
+	}
+
+	if share.str("shareName") == "" {
+		share["shareName"] = strings.Replace(path, "/", "_", -1)
+	}
+	if err := s.state.checkSmbShareName(path, share.str("shareName")); err != nil {
+		return 0, nil, err
+	}
+
+	for k, v := range defaultSmbShareProperties() {
+		if _, ok := share[k]; !ok {
+			share[k] = v
+		}
+	}
+	share["shareState"] = "online"
+	s.state.smbShares[path] = share
+
+	return http.StatusCreated, nil, nil
+}
+
+// defaultSmbShareProperties returns properties of a new SMB share, everyone has full access
+func defaultSmbShareProperties() object {
+	return object{
+		"shareDescription":       "",
+		"guestOk":                false,
+		"accessBasedEnumeration": false,
+		"encryptData":            false,
+		"shareQuota":             0,
+		"shareAcl": []interface{}{
+			map[string]interface{}{"principal": "everyone@", "type": "allow", "permissions": "full"},
+		},
+	}
+}
+
+// checkSmbShareName returns EEXIST error if the share name is used by another filesystem
+func (st *state) checkSmbShareName(path, shareName string) *apiError {
+	for _, sharePath := range sortedKeys(st.smbShares) {
+		if sharePath != path && st.smbShares[sharePath].str("shareName") == shareName {
+			return existError("SMB share name '%s' is already in use", shareName)
+		}
+	}
+	return nil
+}
+
+// smbShareView returns share with the filesystem mount point
+func (st *state) smbShareView(share object) object {
+	view := share.copy()
+	if fs, ok := st.filesystems[share.str("filesystem")]; ok {
+		view["mountPoint"] = fs.str("mountPoint")
+	}
+	return view
+}
+
+// getSmbShares returns SMB shares of the "parent" query param filesystem and its descendants
+func (s *Server) getSmbShares(c *call) (int, interface{}, *apiError) {
+	parent := c.query.Get("parent")
+
+	list := []object{}
+	for _, path := range sortedKeys(s.state.smbShares) {
+		if parent == "" || path == parent || isDescendantOf(path, parent) {
+			list = append(list, s.state.smbShareView(s.state.smbShares[path]))
+		}
+	}
+
+	list, err := paginate(c, list)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, object{"data": list}, nil
+}
+
+func (s *Server) getSmbShare(c *call) (int, interface{}, *apiError) {
+	share, ok := s.state.smbShares[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("SMB share for '%s' not found", c.params[0])
+	}
+	return http.StatusOK, s.state.smbShareView(share), nil
+}
+
+func (s *Server) updateSmbShare(c *call) (int, interface{}, *apiError) {
+	share, ok := s.state.smbShares[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("SMB share for '%s' not found", c.params[0])
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+	for _, key := range []string{"filesystem", "mountPoint", "shareState"} {
+		if _, ok := props[key]; ok {
+			return 0, nil, badArgError("SMB share property '%s' cannot be changed", key)
+		}
+	}
+	if _, ok := props["shareName"]; ok {
+		if props.str("shareName") == "" {
+			return 0, nil, badArgError("SMB share name must not be empty")
+		} else if err := s.state.checkSmbShareName(c.params[0], props.str("shareName")); err != nil {
+			return 0, nil, err
+		}
+	}
+	if _, ok := props["shareQuota"]; ok && props.int64("shareQuota") < 0 {
+		return 0, nil, badArgError("SMB share quota must not be negative")
+	}
+	for k, v := range props {
+		share[k] = v
+	}
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) deleteSmbShare(c *call) (int, interface{}, *apiError) {
+	if _, ok := s.state.smbShares[c.params[0]]; !ok {
+		return 0, nil, notFoundError("SMB share for '%s' not found", c.params[0])
+	}
+	delete(s.state.smbShares, c.params[0])
+	return http.StatusOK, nil, nil
+}
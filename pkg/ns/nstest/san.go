@@ -0,0 +1,240 @@
+package nstest
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (s *Server) registerSanRoutes() {
+	s.handle(http.MethodGet, "san/lunMappings", s.getLunMappings)
+	s.handle(http.MethodPost, "san/lunMappings", s.createLunMapping)
+	s.handle(http.MethodDelete, "san/lunMappings/*", s.destroyLunMapping)
+
+	s.handle(http.MethodGet, "san/iscsi/targets", s.getTargets)
+	s.handle(http.MethodPost, "san/iscsi/targets", s.createTarget)
+	s.handle(http.MethodPut, "san/iscsi/targets/*", s.updateTarget)
+
+	s.handle(http.MethodGet, "san/iscsi/remoteInitiators/*", s.getRemoteInitiator)
+	s.handle(http.MethodPost, "san/iscsi/remoteInitiators", s.createRemoteInitiator)
+	s.handle(http.MethodPut, "san/iscsi/remoteInitiators/*", s.updateRemoteInitiator)
+
+	s.handle(http.MethodGet, "san/targetgroups", s.getTargetGroups)
+	s.handle(http.MethodGet, "san/targetgroups/*", s.getTargetGroup)
+	s.handle(http.MethodPost, "san/targetgroups", s.createTargetGroup)
+	s.handle(http.MethodPut, "san/targetgroups/*", s.updateTargetGroup)
+
+	s.handle(http.MethodGet, "san/hostgroups", s.getHostGroups)
+	s.handle(http.MethodPost, "san/hostgroups", s.createHostGroup)
+	s.handle(http.MethodPut, "san/hostgroups/*", s.updateHostGroup)
+	s.handle(http.MethodPut, "storage/hostgroups/*", s.updateHostGroup)
+}
+
+func (s *Server) getLunMappings(c *call) (int, interface{}, *apiError) {
+	list := []object{}
+	for _, id := range sortedKeys(s.state.lunMappings) {
+		mapping := s.state.lunMappings[id]
+		match := true
+		for _, field := range []string{"volume", "targetGroup", "hostGroup"} {
+			if v := c.query.Get(field); v != "" && mapping.str(field) != v {
+				match = false
+			}
+		}
+		if match {
+			list = append(list, mapping)
+		}
+	}
+
+	list, err := paginate(c, list)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, object{"data": list}, nil
+}
+
+func (s *Server) createLunMapping(c *call) (int, interface{}, *apiError) {
+	mapping := object{}
+	if err := c.decode(&mapping); err != nil {
+		return 0, nil, err
+	}
+
+	volume := mapping.str("volume")
+	if _, ok := s.state.volumes[volume]; !ok {
+		return 0, nil, notFoundError("Volume '%s' not found", volume)
+	}
+
+	lun := int64(0)
+	for _, existing := range s.state.lunMappings {
+		if existing.str("targetGroup") != mapping.str("targetGroup") ||
+			existing.str("hostGroup") != mapping.str("hostGroup") {
+			continue
+		}
+		if existing.str("volume") == volume {
+			return 0, nil, existError("Volume '%s' is already mapped", volume)
+		} else if existing.int64("lun") >= lun {
+			lun = existing.int64("lun") + 1
+		}
+	}
+
+	id := fmt.Sprintf("%032d", s.state.nextID())
+	mapping["id"] = id
+	mapping["lun"] = lun
+	s.state.lunMappings[id] = mapping
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) destroyLunMapping(c *call) (int, interface{}, *apiError) {
+	if _, ok := s.state.lunMappings[c.params[0]]; !ok {
+		return 0, nil, notFoundError("LUN mapping '%s' not found", c.params[0])
+	}
+	delete(s.state.lunMappings, c.params[0])
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) getTargets(c *call) (int, interface{}, *apiError) {
+	list := []object{}
+	for _, name := range sortedKeys(s.state.targets) {
+		if v := c.query.Get("name"); v == "" || v == name {
+			list = append(list, s.state.targets[name])
+		}
+	}
+	return http.StatusOK, object{"data": list}, nil
+}
+
+func (s *Server) createTarget(c *call) (int, interface{}, *apiError) {
+	target := object{}
+	if err := c.decode(&target); err != nil {
+		return 0, nil, err
+	}
+
+	name := target.str("name")
+	if name == "" {
+		return 0, nil, badArgError("Parameter 'name' is required")
+	} else if _, ok := s.state.targets[name]; ok {
+		return 0, nil, existError("iSCSI target '%s' already exists", name)
+	}
+
+	target["state"] = "online"
+	target["authentication"] = "none"
+	target["chapSecretSet"] = false
+	s.state.targets[name] = target
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) updateTarget(c *call) (int, interface{}, *apiError) {
+	return updateObject(c, s.state.targets, "iSCSI target")
+}
+
+func (s *Server) getRemoteInitiator(c *call) (int, interface{}, *apiError) {
+	initiator, ok := s.state.remoteInitiators[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Remote initiator '%s' not found", c.params[0])
+	}
+
+	view := initiator.copy()
+	view["chapSecretSet"] = initiator.str("chapSecret") != ""
+	delete(view, "chapSecret")
+
+	return http.StatusOK, view, nil
+}
+
+func (s *Server) createRemoteInitiator(c *call) (int, interface{}, *apiError) {
+	initiator := object{}
+	if err := c.decode(&initiator); err != nil {
+		return 0, nil, err
+	}
+
+	name := initiator.str("name")
+	if name == "" {
+		return 0, nil, badArgError("Parameter 'name' is required")
+	} else if _, ok := s.state.remoteInitiators[name]; ok {
+		return 0, nil, existError("Remote initiator '%s' already exists", name)
+	}
+
+	s.state.remoteInitiators[name] = initiator
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) updateRemoteInitiator(c *call) (int, interface{}, *apiError) {
+	return updateObject(c, s.state.remoteInitiators, "Remote initiator")
+}
+
+func (s *Server) getTargetGroups(c *call) (int, interface{}, *apiError) {
+	list := []object{}
+	for _, name := range sortedKeys(s.state.targetGroups) {
+		list = append(list, s.state.targetGroups[name])
+	}
+	return http.StatusOK, object{"data": list}, nil
+}
+
+func (s *Server) getTargetGroup(c *call) (int, interface{}, *apiError) {
+	group, ok := s.state.targetGroups[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Target group '%s' not found", c.params[0])
+	}
+	return http.StatusOK, group, nil
+}
+
+func (s *Server) createTargetGroup(c *call) (int, interface{}, *apiError) {
+	return createObject(c, s.state.targetGroups, "Target group")
+}
+
+func (s *Server) updateTargetGroup(c *call) (int, interface{}, *apiError) {
+	return updateObject(c, s.state.targetGroups, "Target group")
+}
+
+func (s *Server) getHostGroups(c *call) (int, interface{}, *apiError) {
+	list := []object{}
+	for _, name := range sortedKeys(s.state.hostGroups) {
+		list = append(list, s.state.hostGroups[name])
+	}
+	return http.StatusOK, object{"data": list}, nil
+}
+
+func (s *Server) createHostGroup(c *call) (int, interface{}, *apiError) {
+	return createObject(c, s.state.hostGroups, "Host group")
+}
+
+func (s *Server) updateHostGroup(c *call) (int, interface{}, *apiError) {
+	return updateObject(c, s.state.hostGroups, "Host group")
+}
+
+// createObject stores named object from request body
+func createObject(c *call, objects map[string]object, kind string) (int, interface{}, *apiError) {
+	o := object{}
+	if err := c.decode(&o); err != nil {
+		return 0, nil, err
+	}
+
+	name := o.str("name")
+	if name == "" {
+		return 0, nil, badArgError("Parameter 'name' is required")
+	} else if _, ok := objects[name]; ok {
+		return 0, nil, existError("%s '%s' already exists", kind, name)
+	}
+
+	objects[name] = o
+
+	return http.StatusCreated, nil, nil
+}
+
+// updateObject merges request body into the object with name from the path
+func updateObject(c *call, objects map[string]object, kind string) (int, interface{}, *apiError) {
+	o, ok := objects[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("%s '%s' not found", kind, c.params[0])
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+	for k, v := range props {
+		o[k] = v
+	}
+
+	return http.StatusOK, nil, nil
+}
@@ -0,0 +1,479 @@
+// Package nstest provides an in-process fake NexentaStor API server for unit tests.
+//
+// The server emulates NEF endpoints used by the "ns" package (auth, storage, nas, san,
+// jobStatus, rsf) with in-memory state, NEF-style error bodies and optional async jobs:
+//
+//	server := nstest.NewServer(nstest.ServerArgs{
+//		Filesystems: []string{"testPool/testDataset"},
+//	})
+//	defer server.Close()
+//
+//	nsProvider, err := ns.NewProvider(ns.ProviderArgs{
+//		Address:  server.URL,
+//		Username: server.Username(),
+//		Password: server.Password(),
+//		Log:      l,
+//	})
+package nstest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaults
+const (
+	defaultUsername = "admin"
+	defaultPassword = "Nexenta@1"
+	defaultPoolName = "testPool"
+
+	// pool size reported by the fake server
+	poolSize int64 = 1024 * 1024 * 1024 * 1024
+
+	// space that is used by an empty dataset
+	datasetUsedSize int64 = 24576
+)
+
+// API version prefix used by some requests, e.g. "v1.2.6/san/iscsi/remoteInitiators"
+var apiVersionRegexp = regexp.MustCompile(`^v\d+(\.\d+)*$`)
+
+// ServerArgs - params to create fake NexentaStor server
+type ServerArgs struct {
+	// credentials to accept on "auth/login", default: "admin" / "Nexenta@1"
+	Username string
+	Password string
+
+	// pools to create on start, default: ["testPool"]
+	Pools []string
+
+	// filesystems to create on start (parents go first), e.g. "testPool/testDataset"
+	Filesystems []string
+
+	// volume groups to create on start, e.g. "testPool/testVolumeGroup"
+	VolumeGroups []string
+
+	// RSF cluster name, the server is not a cluster member if empty
+	ClusterName string
+
+	// AsyncJobs makes all modifying requests respond with 202 and a link to the job status
+	AsyncJobs bool
+
+	// JobPolls is a number of "jobStatus" requests responded with 202 before the job is completed
+	JobPolls int
+
+	// TLS starts HTTPS server with self-signed certificate
+	TLS bool
+
+	// TokenTTL - lifetime of issued auth tokens (reported in JWT "exp" claim), tokens never expire if 0
+	TokenTTL time.Duration
+}
+
+// Request - request received by the server
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+}
+
+// Fault - error to respond with instead of request processing
+type Fault struct {
+	// Method of requests to fail, any method if empty
+	Method string
+
+	// Path of requests to fail w/o query and leading slash, e.g. "storage/filesystems"
+	Path string
+
+	// StatusCode of error response, default: 500
+	StatusCode int
+
+	// Code - NEF error code of error response, e.g. "EBUSY"
+	Code string
+
+	// Count of requests to fail, 0 - fail all matching requests
+	Count int
+
+	// CloseConnection closes client connection w/o response to emulate network errors
+	CloseConnection bool
+}
+
+// Server - fake NexentaStor API server
+type Server struct {
+	*httptest.Server
+
+	args ServerArgs
+
+	mux      sync.Mutex
+	state    *state
+	requests []Request
+	routes   []route
+	faults   []*Fault
+}
+
+// AddFault makes the server fail matching requests,
+// in async jobs mode modifying requests are accepted and their jobs fail
+func (s *Server) AddFault(fault Fault) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if fault.StatusCode == 0 {
+		fault.StatusCode = http.StatusInternalServerError
+	}
+	if fault.Code == "" {
+		fault.Code = "EFAULT"
+	}
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes all faults added by AddFault()
+func (s *Server) ClearFaults() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.faults = nil
+}
+
+// takeFault returns a fault for the request if any
+func (s *Server) takeFault(method, path string) *Fault {
+	for i, f := range s.faults {
+		if (f.Method == "" || f.Method == method) && f.Path == path {
+			if f.Count > 0 {
+				f.Count--
+				if f.Count == 0 {
+					s.faults = append(s.faults[:i], s.faults[i+1:]...)
+				}
+			}
+			return f
+		}
+	}
+	return nil
+}
+
+// Username returns the username accepted by the server
+func (s *Server) Username() string {
+	return s.args.Username
+}
+
+// Password returns the password accepted by the server
+func (s *Server) Password() string {
+	return s.args.Password
+}
+
+// Requests returns all requests received by the server so far
+func (s *Server) Requests() []Request {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// CountRequests returns count of received requests with provided method and path (w/o query)
+func (s *Server) CountRequests(method, path string) int {
+	count := 0
+	for _, r := range s.Requests() {
+		if r.Method == method && r.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+// ExpireTokens invalidates all issued auth tokens, next requests will get EAUTH error
+func (s *Server) ExpireTokens() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.state.tokens = map[string]time.Time{}
+}
+
+// call - parsed API request
+type call struct {
+	method string
+	path   string
+	params []string
+	query  url.Values
+	body   []byte
+}
+
+// decode unmarshals request body to v
+func (c *call) decode(v interface{}) *apiError {
+	if len(c.body) == 0 {
+		return badArgError("Request body is required")
+	}
+	if err := json.Unmarshal(c.body, v); err != nil {
+		return badArgError("Cannot parse request body: %s", err)
+	}
+	return nil
+}
+
+// handler processes API call under the server lock
+type handler func(c *call) (status int, response interface{}, err *apiError)
+
+type route struct {
+	method  string
+	pattern []string
+	handler handler
+}
+
+func (s *Server) handle(method, pattern string, h handler) {
+	s.routes = append(s.routes, route{
+		method:  method,
+		pattern: strings.Split(pattern, "/"),
+		handler: h,
+	})
+}
+
+// match returns captured "*" segments if path matches the pattern
+func (r *route) match(method string, segments []string) ([]string, bool) {
+	if r.method != method || len(r.pattern) != len(segments) {
+		return nil, false
+	}
+
+	params := []string{}
+	for i, p := range r.pattern {
+		if p == "*" {
+			params = append(params, segments[i])
+		} else if p != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func (s *Server) registerRoutes() {
+	s.handle(http.MethodPost, "auth/login", s.login)
+	s.handle(http.MethodGet, "jobStatus/*", s.getJobStatus)
+	s.handle(http.MethodDelete, "jobStatus/*", s.cancelJob)
+
+	s.handle(http.MethodGet, "settings/license", s.getLicense)
+	s.handle(http.MethodGet, "rsf/clusters", s.getRSFClusters)
+	s.handle(http.MethodGet, "storage/pools", s.getPools)
+
+	s.registerStorageRoutes()
+	s.registerNasRoutes()
+	s.registerSanRoutes()
+	s.registerHprRoutes()
+}
+
+// ServeHTTP handles requests to the fake API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := []string{}
+	for _, segment := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, badArgError("Cannot parse path: %s", err))
+			return
+		}
+		segments = append(segments, unescaped)
+	}
+	if len(segments) > 0 && apiVersionRegexp.MatchString(segments[0]) {
+		segments = segments[1:]
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, badArgError("Cannot read request body: %s", err))
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	path := strings.Join(segments, "/")
+	s.requests = append(s.requests, Request{Method: r.Method, Path: path, Query: r.URL.Query()})
+
+	var h handler
+	var params []string
+	pathFound := false
+	for _, route := range s.routes {
+		if p, ok := route.match(r.Method, segments); ok {
+			h = route.handler
+			params = p
+			break
+		} else if _, ok := route.match(route.method, segments); ok {
+			pathFound = true
+		}
+	}
+	if h == nil {
+		if pathFound {
+			writeResponse(w, http.StatusMethodNotAllowed, newAPIError(
+				http.StatusMethodNotAllowed, "EBADARG", "Method %s is not allowed for '%s'", r.Method, path))
+		} else {
+			writeResponse(w, http.StatusNotFound, notFoundError("Resource '%s' not found", path))
+		}
+		return
+	}
+
+	if path != "auth/login" && !s.isAuthorized(r) {
+		writeResponse(w, http.StatusUnauthorized, newAPIError(
+			http.StatusUnauthorized, "EAUTH", "Authentication is required"))
+		return
+	}
+
+	var status int
+	var response interface{}
+	var apiErr *apiError
+
+	if fault := s.takeFault(r.Method, path); fault != nil {
+		if fault.CloseConnection {
+			closeConnection(w)
+			return
+		}
+		apiErr = newAPIError(fault.StatusCode, fault.Code, "Fault injected for '%s %s'", r.Method, path)
+	} else {
+		status, response, apiErr = h(&call{
+			method: r.Method,
+			path:   path,
+			params: params,
+			query:  r.URL.Query(),
+			body:   body,
+		})
+	}
+
+	if s.args.AsyncJobs && r.Method != http.MethodGet && path != "auth/login" && segments[0] != "jobStatus" {
+		jobID := s.state.addJob(fmt.Sprintf("%s %s", r.Method, path), status, response, apiErr, s.args.JobPolls)
+		writeResponse(w, http.StatusAccepted, jobLinks(jobID))
+		return
+	}
+
+	if apiErr != nil {
+		writeResponse(w, apiErr.status, apiErr)
+		return
+	}
+	writeResponse(w, status, response)
+}
+
+func (s *Server) isAuthorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	expires, ok := s.state.tokens[token]
+	return ok && (expires.IsZero() || time.Now().Before(expires))
+}
+
+// closeConnection closes client connection w/o writing a response
+func closeConnection(w http.ResponseWriter) {
+	if hijacker, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hijacker.Hijack(); err == nil {
+			conn.Close()
+			return
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
+
+func writeResponse(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if response != nil {
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (s *Server) login(c *call) (int, interface{}, *apiError) {
+	request := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{}
+	if err := c.decode(&request); err != nil {
+		return 0, nil, err
+	}
+
+	if request.Username != s.args.Username || request.Password != s.args.Password {
+		return 0, nil, newAPIError(http.StatusUnauthorized, "EAUTH", "Invalid username or password")
+	}
+
+	// JWT-like token, NEF clients may read "exp" claim to refresh the token in advance
+	claims := object{"jti": s.state.nextID(), "sub": request.Username}
+	expires := time.Time{}
+	if s.args.TokenTTL > 0 {
+		expires = time.Now().Add(s.args.TokenTTL)
+		claims["exp"] = expires.Unix()
+	}
+	claimsJSON, _ := json.Marshal(claims)
+	token := strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)),
+		base64.RawURLEncoding.EncodeToString(claimsJSON),
+		"fake",
+	}, ".")
+	s.state.tokens[token] = expires
+
+	return http.StatusOK, object{"token": token}, nil
+}
+
+func (s *Server) getLicense(c *call) (int, interface{}, *apiError) {
+	return http.StatusOK, object{
+		"valid":   true,
+		"expires": "2099-12-31T00:00:00.000Z",
+	}, nil
+}
+
+func (s *Server) getRSFClusters(c *call) (int, interface{}, *apiError) {
+	clusters := []object{}
+	if s.args.ClusterName != "" {
+		clusters = append(clusters, object{
+			"clusterName": s.args.ClusterName,
+			"nodes":       []object{},
+		})
+	}
+	return http.StatusOK, object{"data": clusters}, nil
+}
+
+func (s *Server) getPools(c *call) (int, interface{}, *apiError) {
+	pools := []object{}
+	for _, name := range sortedKeys(s.state.pools) {
+		pools = append(pools, object{
+			"poolName": name,
+			"health":   "ONLINE",
+			"status":   "ONLINE",
+		})
+	}
+	return http.StatusOK, object{"data": pools}, nil
+}
+
+// NewServer starts fake NexentaStor API server, it should be stopped by Close()
+func NewServer(args ServerArgs) *Server {
+	if args.Username == "" {
+		args.Username = defaultUsername
+	}
+	if args.Password == "" {
+		args.Password = defaultPassword
+	}
+	if len(args.Pools) == 0 {
+		args.Pools = []string{defaultPoolName}
+	}
+
+	s := &Server{
+		args:  args,
+		state: newState(),
+	}
+	s.registerRoutes()
+
+	for _, pool := range args.Pools {
+		s.state.pools[pool] = true
+		s.state.filesystems[pool] = newFilesystem(pool, nil)
+	}
+	for _, path := range args.Filesystems {
+		s.state.filesystems[path] = newFilesystem(path, nil)
+	}
+	for _, path := range args.VolumeGroups {
+		s.state.volumeGroups[path] = object{"path": path}
+	}
+
+	if args.TLS {
+		s.Server = httptest.NewTLSServer(s)
+	} else {
+		s.Server = httptest.NewServer(s)
+	}
+
+	return s
+}
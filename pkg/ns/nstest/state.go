@@ -0,0 +1,319 @@
+package nstest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// object - NEF resource representation
+type object map[string]interface{}
+
+// copy returns a shallow copy of the object
+func (o object) copy() object {
+	c := object{}
+	for k, v := range o {
+		c[k] = v
+	}
+	return c
+}
+
+func (o object) str(key string) string {
+	if v, ok := o[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (o object) int64(key string) int64 {
+	switch v := o[key].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func (o object) bool(key string) bool {
+	v, _ := o[key].(bool)
+	return v
+}
+
+func (o object) strings(key string) []string {
+	switch v := o[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := []string{}
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return []string{}
+}
+
+// apiError - NEF error response
+type apiError struct {
+	status  int
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+var errorNames = map[string]string{
+	"EAUTH":   "AuthError",
+	"EBADARG": "ValidationError",
+	"EBUSY":   "BusyError",
+	"EEXIST":  "ExistsError",
+	"ENOENT":  "NotFoundError",
+}
+
+func newAPIError(status int, code, format string, args ...interface{}) *apiError {
+	name, ok := errorNames[code]
+	if !ok {
+		name = "NefError"
+	}
+	return &apiError{
+		status:  status,
+		Name:    name,
+		Message: fmt.Sprintf(format, args...),
+		Code:    code,
+	}
+}
+
+func badArgError(format string, args ...interface{}) *apiError {
+	return newAPIError(http.StatusBadRequest, "EBADARG", format, args...)
+}
+
+func notFoundError(format string, args ...interface{}) *apiError {
+	return newAPIError(http.StatusNotFound, "ENOENT", format, args...)
+}
+
+func existError(format string, args ...interface{}) *apiError {
+	return newAPIError(http.StatusConflict, "EEXIST", format, args...)
+}
+
+func busyError(format string, args ...interface{}) *apiError {
+	return newAPIError(http.StatusConflict, "EBUSY", format, args...)
+}
+
+// job - async job created by modifying request
+type job struct {
+	description string
+	polls       int
+	totalPolls  int
+	status      int
+	response    interface{}
+	err         *apiError
+}
+
+// progress returns job completion percentage based on count of status requests
+func (j *job) progress() int64 {
+	if j.totalPolls == 0 {
+		return 100
+	}
+	return int64((j.totalPolls - j.polls) * 100 / (j.totalPolls + 1))
+}
+
+func jobLinks(jobID string) object {
+	return object{
+		"links": []object{
+			{
+				"rel":  "monitor",
+				"href": fmt.Sprintf("/jobStatus/%s", jobID),
+			},
+		},
+	}
+}
+
+// state - in-memory NexentaStor state
+type state struct {
+	id  int64
+	txg int64
+
+	tokens map[string]time.Time // token -> expiration time, zero if never expires
+	jobs   map[string]*job
+
+	pools        map[string]bool
+	filesystems  map[string]object
+	volumeGroups map[string]object
+	volumes      map[string]object
+	snapshots    map[string]object
+
+	snapshotSchedules map[string]object
+	hprServices       map[string]object
+
+	nfsShares map[string]object
+	smbShares map[string]object
+	acls      map[string][]object
+
+	lunMappings      map[string]object
+	targets          map[string]object
+	targetGroups     map[string]object
+	hostGroups       map[string]object
+	remoteInitiators map[string]object
+}
+
+func newState() *state {
+	return &state{
+		tokens:            map[string]time.Time{},
+		jobs:              map[string]*job{},
+		pools:             map[string]bool{},
+		filesystems:       map[string]object{},
+		volumeGroups:      map[string]object{},
+		volumes:           map[string]object{},
+		snapshots:         map[string]object{},
+		snapshotSchedules: map[string]object{},
+		hprServices:       map[string]object{},
+		nfsShares:         map[string]object{},
+		smbShares:         map[string]object{},
+		acls:              map[string][]object{},
+		lunMappings:       map[string]object{},
+		targets:           map[string]object{},
+		targetGroups:      map[string]object{},
+		hostGroups:        map[string]object{},
+		remoteInitiators:  map[string]object{},
+	}
+}
+
+func (st *state) nextID() int64 {
+	st.id++
+	return st.id
+}
+
+func (st *state) nextTxg() string {
+	st.txg++
+	return strconv.FormatInt(st.txg, 10)
+}
+
+func (st *state) addJob(description string, status int, response interface{}, err *apiError, polls int) string {
+	jobID := fmt.Sprintf("job-%d", st.nextID())
+	st.jobs[jobID] = &job{
+		description: description,
+		polls:       polls,
+		totalPolls:  polls,
+		status:      status,
+		response:    response,
+		err:         err,
+	}
+	return jobID
+}
+
+func (s *Server) getJobStatus(c *call) (int, interface{}, *apiError) {
+	j, ok := s.state.jobs[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Job '%s' not found", c.params[0])
+	}
+
+	if j.polls > 0 {
+		response := jobLinks(c.params[0])
+		response["progress"] = j.progress()
+		response["description"] = j.description
+		j.polls--
+		return http.StatusAccepted, response, nil
+	}
+
+	if j.err != nil {
+		return 0, nil, j.err
+	}
+
+	status := j.status
+	if status == http.StatusNoContent {
+		status = http.StatusOK
+	}
+	return status, j.response, nil
+}
+
+// cancelJob marks running job as failed with "ECANCELED" code,
+// changes made by the job's request are not rolled back
+func (s *Server) cancelJob(c *call) (int, interface{}, *apiError) {
+	j, ok := s.state.jobs[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Job '%s' not found", c.params[0])
+	} else if j.polls == 0 {
+		return 0, nil, badArgError("Job '%s' is already completed", c.params[0])
+	}
+
+	j.polls = 0
+	j.err = newAPIError(http.StatusInternalServerError, "ECANCELED", "Job '%s' was canceled", c.params[0])
+
+	return http.StatusOK, nil, nil
+}
+
+// parentPath returns parent dataset path: "p/d/fs" -> "p/d"
+func parentPath(path string) string {
+	if i := strings.LastIndex(path, "/"); i != -1 {
+		return path[:i]
+	}
+	return ""
+}
+
+// isChildOf checks if path is a direct child of parent
+func isChildOf(path, parent string) bool {
+	return parentPath(path) == parent
+}
+
+// isDescendantOf checks if path is a child of parent on any depth
+func isDescendantOf(path, parent string) bool {
+	return strings.HasPrefix(path, parent+"/")
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch v := m.(type) {
+	case map[string]bool:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]object:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// maxPageSize - maximum count of items NexentaStor returns per request
+const maxPageSize = 100
+
+// paginate applies "limit" and "offset" query params to the list, up to maxPageSize items are returned
+func paginate(c *call, list []object) ([]object, *apiError) {
+	offset := 0
+	if v := c.query.Get("offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			return nil, badArgError("Parameter 'offset' must be a non-negative integer, got: '%s'", v)
+		}
+		offset = o
+	}
+
+	if offset >= len(list) {
+		return []object{}, nil
+	}
+	list = list[offset:]
+
+	if v := c.query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return nil, badArgError("Parameter 'limit' must be a positive integer, got: '%s'", v)
+		}
+		if limit < len(list) {
+			list = list[:limit]
+		}
+	}
+	if len(list) > maxPageSize {
+		list = list[:maxPageSize]
+	}
+
+	return list, nil
+}
@@ -0,0 +1,1253 @@
+package nstest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (s *Server) registerStorageRoutes() {
+	s.handle(http.MethodGet, "storage/filesystems", s.getFilesystems)
+	s.handle(http.MethodPost, "storage/filesystems", s.createFilesystem)
+	s.handle(http.MethodPut, "storage/filesystems/*", s.updateFilesystem)
+	s.handle(http.MethodDelete, "storage/filesystems/*", s.destroyFilesystem)
+	s.handle(http.MethodPost, "storage/filesystems/*/promote", s.promoteFilesystem)
+	s.handle(http.MethodGet, "storage/filesystems/*/acl", s.getFilesystemACL)
+	s.handle(http.MethodPost, "storage/filesystems/*/acl", s.setFilesystemACL)
+	s.handle(http.MethodPut, "storage/filesystems/*/acl/*", s.replaceFilesystemACE)
+	s.handle(http.MethodDelete, "storage/filesystems/*/acl/*", s.removeFilesystemACE)
+	s.handle(http.MethodPost, "storage/filesystems/*/rename", s.renameFilesystem)
+
+	s.handle(http.MethodGet, "storage/volumeGroups", s.getVolumeGroups)
+
+	s.handle(http.MethodGet, "storage/volumes", s.getVolumes)
+	s.handle(http.MethodPost, "storage/volumes", s.createVolume)
+	s.handle(http.MethodPut, "storage/volumes/*", s.updateVolume)
+	s.handle(http.MethodDelete, "storage/volumes/*", s.destroyVolume)
+	s.handle(http.MethodPost, "storage/volumes/*/promote", s.promoteVolume)
+	s.handle(http.MethodPost, "storage/volumes/*/rename", s.renameVolume)
+
+	s.handle(http.MethodGet, "storage/snapshots", s.getSnapshots)
+	s.handle(http.MethodPost, "storage/snapshots", s.createSnapshot)
+	s.handle(http.MethodGet, "storage/snapshots/*", s.getSnapshot)
+	s.handle(http.MethodDelete, "storage/snapshots/*", s.destroySnapshot)
+	s.handle(http.MethodPost, "storage/snapshots/*/clone", s.cloneSnapshot)
+	s.handle(http.MethodPost, "storage/snapshots/*/rollback", s.rollbackSnapshot)
+	s.handle(http.MethodPut, "storage/snapshots/*", s.updateSnapshot)
+	s.handle(http.MethodPost, "storage/snapshots/*/rename", s.renameSnapshot)
+	s.handle(http.MethodGet, "storage/snapshots/*/holds", s.getSnapshotHolds)
+	s.handle(http.MethodPost, "storage/snapshots/*/holds", s.holdSnapshot)
+	s.handle(http.MethodDelete, "storage/snapshots/*/holds/*", s.releaseSnapshot)
+
+	s.handle(http.MethodGet, "storage/snapshotSchedules", s.getSnapshotSchedules)
+	s.handle(http.MethodPost, "storage/snapshotSchedules", s.createSnapshotSchedule)
+	s.handle(http.MethodGet, "storage/snapshotSchedules/*", s.getSnapshotSchedule)
+	s.handle(http.MethodPut, "storage/snapshotSchedules/*", s.updateSnapshotSchedule)
+	s.handle(http.MethodDelete, "storage/snapshotSchedules/*", s.destroySnapshotSchedule)
+}
+
+// defaultFilesystemProperties - ZFS properties of a filesystem if they aren't set or inherited
+var defaultFilesystemProperties = object{
+	"compressionMode":          "lz4",
+	"recordSize":               int64(128 * 1024),
+	"accessTime":               true,
+	"syncMode":                 "standard",
+	"logBias":                  "latency",
+	"readOnly":                 false,
+	"nonBlockingMandatoryMode": false,
+	"caseSensitivity":          "sensitive",
+}
+
+// defaultVolumeProperties - ZFS properties of a volume if they aren't set
+var defaultVolumeProperties = object{
+	"volumeBlockSize": int64(8 * 1024),
+	"compressionMode": "lz4",
+	"syncMode":        "standard",
+	"writeBackCache":  false,
+	"dedupMode":       "off",
+	"readOnly":        false,
+}
+
+// inheritedFilesystemProperties - ZFS properties inherited from the parent filesystem
+var inheritedFilesystemProperties = []string{
+	"compressionMode",
+	"recordSize",
+	"accessTime",
+	"syncMode",
+	"logBias",
+	"readOnly",
+	"nonBlockingMandatoryMode",
+}
+
+func newFilesystem(path string, props object) object {
+	fs := defaultFilesystemProperties.copy()
+	for k, v := range props {
+		fs[k] = v
+	}
+	fs["path"] = path
+	fs["mountPoint"] = "/" + path
+	return fs
+}
+
+// inheritProperties sets properties of a new dataset from its parent if they are not set explicitly,
+// user properties are merged
+func inheritProperties(props, parent object, keys []string) {
+	for _, key := range keys {
+		if _, ok := props[key]; !ok {
+			if v, ok := parent[key]; ok {
+				props[key] = v
+			}
+		}
+	}
+
+	userProperties := object{}
+	for _, source := range []object{parent, props} {
+		if v, ok := source["userProperties"].(map[string]interface{}); ok {
+			for name, value := range v {
+				userProperties[name] = value
+			}
+		}
+	}
+	if len(userProperties) > 0 {
+		props["userProperties"] = map[string]interface{}(userProperties)
+	}
+}
+
+// updateProperties sets updated properties of a dataset, user properties are merged
+func updateProperties(dataset, props object) {
+	for k, v := range props {
+		if k == "userProperties" {
+			userProperties, _ := dataset[k].(map[string]interface{})
+			merged := map[string]interface{}{}
+			for name, value := range userProperties {
+				merged[name] = value
+			}
+			if update, ok := v.(map[string]interface{}); ok {
+				for name, value := range update {
+					merged[name] = value
+				}
+			}
+			dataset[k] = merged
+			continue
+		}
+		dataset[k] = v
+	}
+}
+
+// datasetExists checks if there is a filesystem, volume or volume group with provided path
+func (st *state) datasetExists(path string) bool {
+	_, isFilesystem := st.filesystems[path]
+	_, isVolume := st.volumes[path]
+	_, isVolumeGroup := st.volumeGroups[path]
+	return isFilesystem || isVolume || isVolumeGroup
+}
+
+func (st *state) filesystemView(fs object) object {
+	view := fs.copy()
+
+	path := fs.str("path")
+	_, view["sharedOverNfs"] = st.nfsShares[path]
+	_, view["sharedOverSmb"] = st.smbShares[path]
+
+	view["bytesUsed"] = datasetUsedSize
+	view["bytesAvailable"] = poolSize - datasetUsedSize
+	for _, key := range []string{"quotaSize", "referencedQuotaSize"} {
+		if quota := fs.int64(key); quota > 0 && quota-datasetUsedSize < view.int64("bytesAvailable") {
+			view["bytesAvailable"] = quota - datasetUsedSize
+		}
+	}
+
+	return view
+}
+
+func (st *state) volumeView(volume object) object {
+	view := volume.copy()
+
+	view["bytesUsed"] = datasetUsedSize
+	if !volume.bool("sparseVolume") {
+		view["bytesUsed"] = volume.int64("volumeSize")
+	}
+	view["bytesAvailable"] = poolSize - view["bytesUsed"].(int64)
+
+	return view
+}
+
+// datasetList returns datasets filtered by "path" or "parent" query param
+func datasetList(c *call, datasets map[string]object, includeParent bool) []object {
+	list := []object{}
+
+	if path := c.query.Get("path"); path != "" {
+		if d, ok := datasets[path]; ok {
+			list = append(list, d)
+		}
+		return list
+	}
+
+	parent := c.query.Get("parent")
+	if parent != "" && includeParent {
+		if d, ok := datasets[parent]; ok {
+			list = append(list, d)
+		}
+	}
+	for _, path := range sortedKeys(datasets) {
+		if parent == "" || isChildOf(path, parent) {
+			list = append(list, datasets[path])
+		}
+	}
+
+	return list
+}
+
+func (s *Server) getFilesystems(c *call) (int, interface{}, *apiError) {
+	list, err := paginate(c, datasetList(c, s.state.filesystems, true))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	data := []object{}
+	for _, fs := range list {
+		data = append(data, s.state.filesystemView(fs))
+	}
+
+	return http.StatusOK, object{"data": data}, nil
+}
+
+func (s *Server) createFilesystem(c *call) (int, interface{}, *apiError) {
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	path := props.str("path")
+	if path == "" {
+		return 0, nil, badArgError("Parameter 'path' is required")
+	} else if s.state.datasetExists(path) {
+		return 0, nil, existError("Dataset '%s' already exists", path)
+	}
+
+	parent, ok := s.state.filesystems[parentPath(path)]
+	if !ok {
+		return 0, nil, notFoundError("Parent filesystem '%s' not found", parentPath(path))
+	}
+	inheritProperties(props, parent, inheritedFilesystemProperties)
+
+	s.state.filesystems[path] = newFilesystem(path, props)
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) updateFilesystem(c *call) (int, interface{}, *apiError) {
+	fs, ok := s.state.filesystems[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", c.params[0])
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	} else if _, ok := props["caseSensitivity"]; ok {
+		return 0, nil, badArgError("Property 'caseSensitivity' can be set on creation only")
+	}
+	updateProperties(fs, props)
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) destroyFilesystem(c *call) (int, interface{}, *apiError) {
+	path := c.params[0]
+	if _, ok := s.state.filesystems[path]; !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", path)
+	}
+
+	for _, child := range sortedKeys(s.state.filesystems) {
+		if isChildOf(child, path) {
+			return 0, nil, busyError("Filesystem '%s' has children", path)
+		}
+	}
+	for _, child := range sortedKeys(s.state.volumes) {
+		if isChildOf(child, path) {
+			return 0, nil, busyError("Filesystem '%s' has children", path)
+		}
+	}
+
+	if err := s.state.destroyDataset(path, c.query.Get("snapshots") == "true"); err != nil {
+		return 0, nil, err
+	}
+
+	delete(s.state.filesystems, path)
+	delete(s.state.nfsShares, path)
+	delete(s.state.smbShares, path)
+	delete(s.state.acls, path)
+
+	return http.StatusOK, nil, nil
+}
+
+// destroyDataset destroys dataset's snapshots and unlinks it from the origin snapshot
+func (st *state) destroyDataset(path string, destroySnapshots bool) *apiError {
+	snapshots := st.datasetSnapshots(path, false)
+	for _, snapshot := range snapshots {
+		if len(snapshot.strings("clones")) > 0 {
+			return existError(
+				"Dataset '%s' has snapshot '%s' with dependent clones: %v",
+				path,
+				snapshot.str("path"),
+				snapshot.strings("clones"),
+			)
+		}
+	}
+	if len(snapshots) > 0 && !destroySnapshots {
+		return busyError("Dataset '%s' has snapshots", path)
+	}
+	for _, snapshot := range snapshots {
+		if len(snapshotHolds(snapshot)) > 0 {
+			return busyError("Dataset '%s' has held snapshot '%s'", path, snapshot.str("path"))
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		delete(st.snapshots, snapshot.str("path"))
+	}
+
+	st.unlinkClone(path)
+
+	return nil
+}
+
+// unlinkClone removes dataset from its origin snapshot's clone list
+func (st *state) unlinkClone(path string) {
+	d := st.filesystems[path]
+	if d == nil {
+		d = st.volumes[path]
+	}
+	if d == nil {
+		return
+	}
+
+	origin, ok := st.snapshots[d.str("originalSnapshot")]
+	if !ok {
+		return
+	}
+
+	clones := []string{}
+	for _, clone := range origin.strings("clones") {
+		if clone != path {
+			clones = append(clones, clone)
+		}
+	}
+	origin["clones"] = clones
+}
+
+func (s *Server) promoteFilesystem(c *call) (int, interface{}, *apiError) {
+	if _, ok := s.state.filesystems[c.params[0]]; !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", c.params[0])
+	}
+	if err := s.state.promoteDataset(c.params[0]); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, nil, nil
+}
+
+// promoteDataset moves origin snapshots (up to the clone's origin) to the clone,
+// so the original dataset becomes a clone of the promoted one
+func (st *state) promoteDataset(path string) *apiError {
+	d := st.filesystems[path]
+	if d == nil {
+		d = st.volumes[path]
+	}
+
+	originPath := d.str("originalSnapshot")
+	origin, ok := st.snapshots[originPath]
+	if !ok {
+		return badArgError("Dataset '%s' is not a clone", path)
+	}
+
+	originTxg, _ := strconv.ParseInt(origin.str("creationTxg"), 10, 64)
+	sourcePath := origin.str("parent")
+	source := st.filesystems[sourcePath]
+	if source == nil {
+		source = st.volumes[sourcePath]
+	}
+
+	st.unlinkClone(path)
+	delete(d, "originalSnapshot")
+
+	for _, snapshot := range st.datasetSnapshots(sourcePath, false) {
+		txg, _ := strconv.ParseInt(snapshot.str("creationTxg"), 10, 64)
+		if txg > originTxg {
+			continue
+		}
+
+		oldPath := snapshot.str("path")
+		newPath := path + "@" + snapshot.str("name")
+		delete(st.snapshots, oldPath)
+		snapshot["path"] = newPath
+		snapshot["parent"] = path
+		st.snapshots[newPath] = snapshot
+
+		for _, datasets := range []map[string]object{st.filesystems, st.volumes} {
+			for _, dataset := range datasets {
+				if dataset.str("originalSnapshot") == oldPath {
+					dataset["originalSnapshot"] = newPath
+				}
+			}
+		}
+	}
+
+	if source != nil {
+		newOriginPath := path + "@" + origin.str("name")
+		source["originalSnapshot"] = newOriginPath
+		newOrigin := st.snapshots[newOriginPath]
+		newOrigin["clones"] = append(newOrigin.strings("clones"), sourcePath)
+	}
+
+	return nil
+}
+
+// renameFilesystem moves filesystem with its children and snapshots, shared filesystems cannot be renamed
+func (s *Server) renameFilesystem(c *call) (int, interface{}, *apiError) {
+	path := c.params[0]
+	if _, ok := s.state.filesystems[path]; !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", path)
+	} else if s.state.pools[path] {
+		return 0, nil, badArgError("Pool root filesystem '%s' cannot be renamed", path)
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	newPath := props.str("newPath")
+	if err := s.state.checkRename(path, newPath); err != nil {
+		return 0, nil, err
+	}
+
+	for _, sharePath := range append(sortedKeys(s.state.nfsShares), sortedKeys(s.state.smbShares)...) {
+		if sharePath == path || isDescendantOf(sharePath, path) {
+			return 0, nil, busyError("Filesystem '%s' is shared, shares must be destroyed before rename", sharePath)
+		}
+	}
+
+	parent := parentPath(newPath)
+	if _, ok := s.state.filesystems[parent]; !ok {
+		if !props.bool("createParents") {
+			return 0, nil, notFoundError("Parent filesystem '%s' not found", parent)
+		}
+		missing := []string{}
+		for p := parent; p != ""; p = parentPath(p) {
+			if _, ok := s.state.filesystems[p]; ok {
+				break
+			} else if s.state.datasetExists(p) {
+				return 0, nil, badArgError("Parent dataset '%s' is not a filesystem", p)
+			}
+			missing = append(missing, p)
+		}
+		for i := len(missing) - 1; i >= 0; i-- {
+			props := object{}
+			inheritProperties(props, s.state.filesystems[parentPath(missing[i])], inheritedFilesystemProperties)
+			s.state.filesystems[missing[i]] = newFilesystem(missing[i], props)
+		}
+	}
+
+	s.state.renameDataset(path, newPath)
+
+	return http.StatusOK, nil, nil
+}
+
+// renameVolume moves volume with its snapshots, mapped volumes cannot be renamed
+func (s *Server) renameVolume(c *call) (int, interface{}, *apiError) {
+	path := c.params[0]
+	if _, ok := s.state.volumes[path]; !ok {
+		return 0, nil, notFoundError("Volume '%s' not found", path)
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	newPath := props.str("newPath")
+	if err := s.state.checkRename(path, newPath); err != nil {
+		return 0, nil, err
+	}
+
+	for _, mapping := range s.state.lunMappings {
+		if mapping.str("volume") == path {
+			return 0, nil, busyError("Volume '%s' is mapped, LUN mappings must be destroyed before rename", path)
+		}
+	}
+
+	parent := parentPath(newPath)
+	_, isFilesystem := s.state.filesystems[parent]
+	_, isVolumeGroup := s.state.volumeGroups[parent]
+	if !isFilesystem && !isVolumeGroup {
+		return 0, nil, notFoundError("Parent volume group '%s' not found", parent)
+	}
+
+	s.state.renameDataset(path, newPath)
+
+	return http.StatusOK, nil, nil
+}
+
+// checkRename checks new dataset path, datasets cannot be moved across pools or into themselves
+func (st *state) checkRename(path, newPath string) *apiError {
+	if newPath == "" {
+		return badArgError("Parameter 'newPath' is required")
+	} else if newPath == path || isDescendantOf(newPath, path) {
+		return badArgError("Dataset '%s' cannot be moved to '%s'", path, newPath)
+	} else if strings.SplitN(newPath, "/", 2)[0] != strings.SplitN(path, "/", 2)[0] {
+		return badArgError("Dataset '%s' cannot be moved to another pool: '%s'", path, newPath)
+	} else if st.datasetExists(newPath) {
+		return existError("Dataset '%s' already exists", newPath)
+	}
+	return nil
+}
+
+// renamedPath returns the new path of a dataset or snapshot if it's moved with the renamed dataset
+func renamedPath(path, oldPath, newPath string) (string, bool) {
+	if path == oldPath || strings.HasPrefix(path, oldPath+"/") || strings.HasPrefix(path, oldPath+"@") {
+		return newPath + path[len(oldPath):], true
+	}
+	return path, false
+}
+
+// renameDataset moves dataset, its children and snapshots to the new path,
+// references to them from clones, ACLs, snapshot schedules and HPR services are updated
+func (st *state) renameDataset(oldPath, newPath string) {
+	for _, datasets := range []map[string]object{st.filesystems, st.volumes, st.snapshots} {
+		for _, path := range sortedKeys(datasets) {
+			renamed, ok := renamedPath(path, oldPath, newPath)
+			if !ok {
+				continue
+			}
+			d := datasets[path]
+			delete(datasets, path)
+			d["path"] = renamed
+			if mountPoint := d.str("mountPoint"); mountPoint == "/"+path {
+				d["mountPoint"] = "/" + renamed
+			}
+			if parent, ok := renamedPath(d.str("parent"), oldPath, newPath); ok {
+				d["parent"] = parent
+			}
+			datasets[renamed] = d
+		}
+	}
+
+	for _, datasets := range []map[string]object{st.filesystems, st.volumes} {
+		for _, d := range datasets {
+			if origin, ok := renamedPath(d.str("originalSnapshot"), oldPath, newPath); ok {
+				d["originalSnapshot"] = origin
+			}
+		}
+	}
+	for _, snapshot := range st.snapshots {
+		clones := []string{}
+		for _, clone := range snapshot.strings("clones") {
+			clone, _ = renamedPath(clone, oldPath, newPath)
+			clones = append(clones, clone)
+		}
+		snapshot["clones"] = clones
+	}
+
+	for _, path := range sortedKeys(st.acls) {
+		if renamed, ok := renamedPath(path, oldPath, newPath); ok {
+			st.acls[renamed] = st.acls[path]
+			delete(st.acls, path)
+		}
+	}
+	for _, schedule := range st.snapshotSchedules {
+		if dataset, ok := renamedPath(schedule.str("dataset"), oldPath, newPath); ok {
+			schedule["dataset"] = dataset
+		}
+	}
+	for _, service := range st.hprServices {
+		if dataset, ok := renamedPath(service.str("sourceDataset"), oldPath, newPath); ok {
+			service["sourceDataset"] = dataset
+		}
+		status := hprServiceStatus(service)
+		if snapshot, ok := renamedPath(status.str("lastSnapshot"), oldPath, newPath); ok {
+			status["lastSnapshot"] = snapshot
+		}
+	}
+}
+
+// filesystemACL returns ACL of the filesystem, new filesystems have the default one
+func (st *state) filesystemACL(path string) []object {
+	if _, ok := st.acls[path]; !ok {
+		st.acls[path] = []object{
+			{"type": "allow", "principal": "owner@", "permissions": []interface{}{"full_set"}, "flags": []interface{}{}},
+			{"type": "allow", "principal": "group@", "permissions": []interface{}{"read_set"}, "flags": []interface{}{}},
+			{"type": "allow", "principal": "everyone@", "permissions": []interface{}{"read_set"}, "flags": []interface{}{}},
+		}
+	}
+	return st.acls[path]
+}
+
+// decodeACE decodes ACL entry from the request body and checks required params
+func decodeACE(c *call) (object, *apiError) {
+	ace := object{}
+	if err := c.decode(&ace); err != nil {
+		return nil, err
+	}
+
+	if t := ace.str("type"); t != "allow" && t != "deny" {
+		return nil, badArgError("ACL entry type must be 'allow' or 'deny', got: '%s'", t)
+	} else if ace.str("principal") == "" {
+		return nil, badArgError("Parameter 'principal' is required")
+	} else if len(ace.strings("permissions")) == 0 {
+		return nil, badArgError("Parameter 'permissions' must not be empty")
+	}
+	if _, ok := ace["flags"]; !ok {
+		ace["flags"] = []interface{}{}
+	}
+
+	return ace, nil
+}
+
+// aceIndex returns ACL entry index from the request path
+func (s *Server) aceIndex(c *call) (int, *apiError) {
+	acl := s.state.filesystemACL(c.params[0])
+	index, err := strconv.Atoi(c.params[1])
+	if err != nil || index < 0 || index >= len(acl) {
+		return 0, notFoundError("Filesystem '%s' has no ACL entry '%s'", c.params[0], c.params[1])
+	}
+	return index, nil
+}
+
+func (s *Server) getFilesystemACL(c *call) (int, interface{}, *apiError) {
+	path := c.params[0]
+	if _, ok := s.state.filesystems[path]; !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", path)
+	}
+
+	data := []object{}
+	for i, ace := range s.state.filesystemACL(path) {
+		view := ace.copy()
+		view["index"] = i
+		data = append(data, view)
+	}
+
+	return http.StatusOK, object{"data": data}, nil
+}
+
+// setFilesystemACL adds ACL entry, it's inserted at "index" position if set or appended otherwise
+func (s *Server) setFilesystemACL(c *call) (int, interface{}, *apiError) {
+	path := c.params[0]
+	if _, ok := s.state.filesystems[path]; !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", path)
+	}
+
+	ace, err := decodeACE(c)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	acl := s.state.filesystemACL(path)
+	index := len(acl)
+	if _, ok := ace["index"]; ok {
+		index = int(ace.int64("index"))
+		delete(ace, "index")
+		if index < 0 || index > len(acl) {
+			return 0, nil, badArgError("ACL entry index must be in range [0, %d], got: %d", len(acl), index)
+		}
+	}
+	acl = append(acl, nil)
+	copy(acl[index+1:], acl[index:])
+	acl[index] = ace
+	s.state.acls[path] = acl
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) replaceFilesystemACE(c *call) (int, interface{}, *apiError) {
+	if _, ok := s.state.filesystems[c.params[0]]; !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", c.params[0])
+	}
+
+	index, err := s.aceIndex(c)
+	if err != nil {
+		return 0, nil, err
+	}
+	ace, err := decodeACE(c)
+	if err != nil {
+		return 0, nil, err
+	}
+	delete(ace, "index")
+	s.state.acls[c.params[0]][index] = ace
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) removeFilesystemACE(c *call) (int, interface{}, *apiError) {
+	if _, ok := s.state.filesystems[c.params[0]]; !ok {
+		return 0, nil, notFoundError("Filesystem '%s' not found", c.params[0])
+	}
+
+	index, err := s.aceIndex(c)
+	if err != nil {
+		return 0, nil, err
+	}
+	acl := s.state.acls[c.params[0]]
+	s.state.acls[c.params[0]] = append(acl[:index], acl[index+1:]...)
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) getVolumeGroups(c *call) (int, interface{}, *apiError) {
+	list, err := paginate(c, datasetList(c, s.state.volumeGroups, false))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	data := []object{}
+	for _, vg := range list {
+		view := vg.copy()
+		view["bytesUsed"] = datasetUsedSize
+		view["bytesAvailable"] = poolSize - datasetUsedSize
+		data = append(data, view)
+	}
+
+	return http.StatusOK, object{"data": data}, nil
+}
+
+func (s *Server) getVolumes(c *call) (int, interface{}, *apiError) {
+	list, err := paginate(c, datasetList(c, s.state.volumes, false))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	data := []object{}
+	for _, volume := range list {
+		data = append(data, s.state.volumeView(volume))
+	}
+
+	return http.StatusOK, object{"data": data}, nil
+}
+
+func (s *Server) createVolume(c *call) (int, interface{}, *apiError) {
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	path := props.str("path")
+	if path == "" {
+		return 0, nil, badArgError("Parameter 'path' is required")
+	} else if props.int64("volumeSize") <= 0 {
+		return 0, nil, badArgError("Parameter 'volumeSize' must be greater than 0")
+	} else if s.state.datasetExists(path) {
+		return 0, nil, existError("Dataset '%s' already exists", path)
+	}
+
+	parent := parentPath(path)
+	_, isFilesystem := s.state.filesystems[parent]
+	_, isVolumeGroup := s.state.volumeGroups[parent]
+	if !isFilesystem && !isVolumeGroup {
+		return 0, nil, notFoundError("Parent volume group '%s' not found", parent)
+	}
+
+	volume := defaultVolumeProperties.copy()
+	for k, v := range props {
+		volume[k] = v
+	}
+	s.state.volumes[path] = volume
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) updateVolume(c *call) (int, interface{}, *apiError) {
+	volume, ok := s.state.volumes[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Volume '%s' not found", c.params[0])
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	} else if _, ok := props["volumeBlockSize"]; ok {
+		return 0, nil, badArgError("Property 'volumeBlockSize' can be set on creation only")
+	}
+	updateProperties(volume, props)
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) destroyVolume(c *call) (int, interface{}, *apiError) {
+	path := c.params[0]
+	if _, ok := s.state.volumes[path]; !ok {
+		return 0, nil, notFoundError("Volume '%s' not found", path)
+	}
+
+	for _, mapping := range s.state.lunMappings {
+		if mapping.str("volume") == path {
+			return 0, nil, busyError("Volume '%s' is mapped", path)
+		}
+	}
+
+	if err := s.state.destroyDataset(path, c.query.Get("snapshots") == "true"); err != nil {
+		return 0, nil, err
+	}
+
+	delete(s.state.volumes, path)
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) promoteVolume(c *call) (int, interface{}, *apiError) {
+	if _, ok := s.state.volumes[c.params[0]]; !ok {
+		return 0, nil, notFoundError("Volume '%s' not found", c.params[0])
+	}
+	if err := s.state.promoteDataset(c.params[0]); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, nil, nil
+}
+
+// datasetSnapshots returns dataset snapshots sorted by creation, including children's snapshots if recursive
+func (st *state) datasetSnapshots(path string, recursive bool) []object {
+	list := []object{}
+	for _, snapshot := range st.snapshots {
+		parent := snapshot.str("parent")
+		if parent == path || (recursive && isDescendantOf(parent, path)) {
+			list = append(list, snapshot)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		txgI, _ := strconv.ParseInt(list[i].str("creationTxg"), 10, 64)
+		txgJ, _ := strconv.ParseInt(list[j].str("creationTxg"), 10, 64)
+		return txgI < txgJ
+	})
+
+	return list
+}
+
+func (s *Server) getSnapshots(c *call) (int, interface{}, *apiError) {
+	var list []object
+	if parent := c.query.Get("parent"); parent != "" {
+		list = s.state.datasetSnapshots(parent, c.query.Get("recursive") == "true")
+	} else {
+		list = []object{}
+		for _, path := range sortedKeys(s.state.snapshots) {
+			list = append(list, s.state.snapshots[path])
+		}
+	}
+
+	list, err := paginate(c, list)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	data := []object{}
+	for _, snapshot := range list {
+		data = append(data, snapshotView(snapshot))
+	}
+
+	return http.StatusOK, object{"data": data}, nil
+}
+
+// snapshotView returns snapshot representation w/o internal fields
+func snapshotView(snapshot object) object {
+	view := snapshot.copy()
+	delete(view, "holds")
+	return view
+}
+
+// snapshotHolds returns user holds of the snapshot: tag -> hold
+func snapshotHolds(snapshot object) map[string]object {
+	holds, _ := snapshot["holds"].(map[string]object)
+	return holds
+}
+
+func (s *Server) createSnapshot(c *call) (int, interface{}, *apiError) {
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	path := props.str("path")
+	i := strings.LastIndex(path, "@")
+	if i <= 0 || i == len(path)-1 {
+		return 0, nil, badArgError("Parameter 'path' must be in 'dataset@name' format, got: '%s'", path)
+	}
+
+	parent := path[:i]
+	if _, ok := s.state.filesystems[parent]; !ok {
+		if _, ok := s.state.volumes[parent]; !ok {
+			return 0, nil, notFoundError("Dataset '%s' not found", parent)
+		}
+	}
+	if _, ok := s.state.snapshots[path]; ok {
+		return 0, nil, existError("Snapshot '%s' already exists", path)
+	}
+
+	snapshot := props
+	snapshot["name"] = path[i+1:]
+	snapshot["parent"] = parent
+	snapshot["clones"] = []string{}
+	snapshot["creationTxg"] = s.state.nextTxg()
+	snapshot["creationTime"] = time.Now().UTC().Format(time.RFC3339)
+	snapshot["bytesReferenced"] = datasetUsedSize
+	snapshot["bytesUsed"] = int64(0)
+	s.state.snapshots[path] = snapshot
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) getSnapshot(c *call) (int, interface{}, *apiError) {
+	snapshot, ok := s.state.snapshots[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot '%s' not found", c.params[0])
+	}
+	return http.StatusOK, snapshotView(snapshot), nil
+}
+
+func (s *Server) destroySnapshot(c *call) (int, interface{}, *apiError) {
+	snapshot, ok := s.state.snapshots[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot '%s' not found", c.params[0])
+	} else if len(snapshot.strings("clones")) > 0 {
+		return 0, nil, existError("Snapshot '%s' has dependent clones: %v", c.params[0], snapshot.strings("clones"))
+	} else if len(snapshotHolds(snapshot)) > 0 {
+		return 0, nil, busyError("Snapshot '%s' is held", c.params[0])
+	}
+
+	delete(s.state.snapshots, c.params[0])
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) cloneSnapshot(c *call) (int, interface{}, *apiError) {
+	snapshotPath := c.params[0]
+	snapshot, ok := s.state.snapshots[snapshotPath]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot '%s' not found", snapshotPath)
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	targetPath := props.str("targetPath")
+	if targetPath == "" {
+		return 0, nil, badArgError("Parameter 'targetPath' is required")
+	} else if s.state.datasetExists(targetPath) {
+		return 0, nil, existError("Dataset '%s' already exists", targetPath)
+	} else if !s.state.datasetExists(parentPath(targetPath)) {
+		return 0, nil, notFoundError("Parent dataset '%s' not found", parentPath(targetPath))
+	}
+
+	delete(props, "targetPath")
+	props["originalSnapshot"] = snapshotPath
+
+	if source, ok := s.state.volumes[snapshot.str("parent")]; ok {
+		// clone inherits size and block size of the origin, other properties are not copied
+		volume := defaultVolumeProperties.copy()
+		volume["volumeSize"] = source["volumeSize"]
+		volume["volumeBlockSize"] = source["volumeBlockSize"]
+		for k, v := range props {
+			volume[k] = v
+		}
+		volume["path"] = targetPath
+		s.state.volumes[targetPath] = volume
+	} else {
+		s.state.filesystems[targetPath] = newFilesystem(targetPath, props)
+	}
+
+	snapshot["clones"] = append(snapshot.strings("clones"), targetPath)
+
+	return http.StatusCreated, nil, nil
+}
+
+// rollbackSnapshot destroys snapshots which are more recent than the rolled back one,
+// more recent snapshots with clones are destroyed with the clones if "destroyRecentClones" is set
+func (s *Server) rollbackSnapshot(c *call) (int, interface{}, *apiError) {
+	snapshotPath := c.params[0]
+	snapshot, ok := s.state.snapshots[snapshotPath]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot '%s' not found", snapshotPath)
+	}
+
+	props := object{}
+	if len(c.body) > 0 {
+		if err := c.decode(&props); err != nil {
+			return 0, nil, err
+		}
+	}
+	destroyClones := props.bool("destroyRecentClones")
+	destroySnapshots := props.bool("destroyRecentSnapshots") || destroyClones
+
+	txg, _ := strconv.ParseInt(snapshot.str("creationTxg"), 10, 64)
+	recent := []object{}
+	for _, other := range s.state.datasetSnapshots(snapshot.str("parent"), false) {
+		otherTxg, _ := strconv.ParseInt(other.str("creationTxg"), 10, 64)
+		if otherTxg > txg {
+			recent = append(recent, other)
+		}
+	}
+
+	if len(recent) > 0 && !destroySnapshots {
+		return 0, nil, busyError("Dataset '%s' has more recent snapshots than '%s'", snapshot.str("parent"), snapshotPath)
+	}
+	for _, other := range recent {
+		if clones := other.strings("clones"); len(clones) > 0 && !destroyClones {
+			return 0, nil, busyError("Snapshot '%s' has dependent clones: %v", other.str("path"), clones)
+		} else if len(snapshotHolds(other)) > 0 {
+			return 0, nil, busyError("Snapshot '%s' is held", other.str("path"))
+		}
+	}
+
+	for _, other := range recent {
+		for _, clone := range other.strings("clones") {
+			s.state.destroyClone(clone)
+		}
+		delete(s.state.snapshots, other.str("path"))
+	}
+
+	return http.StatusOK, nil, nil
+}
+
+// destroyClone destroys cloned dataset with its children and snapshots
+func (st *state) destroyClone(path string) {
+	for _, datasets := range []map[string]object{st.filesystems, st.volumes} {
+		for _, child := range sortedKeys(datasets) {
+			if isChildOf(child, path) {
+				st.destroyClone(child)
+			}
+		}
+	}
+
+	for _, snapshot := range st.datasetSnapshots(path, false) {
+		for _, clone := range snapshot.strings("clones") {
+			st.destroyClone(clone)
+		}
+		delete(st.snapshots, snapshot.str("path"))
+	}
+
+	st.unlinkClone(path)
+	delete(st.filesystems, path)
+	delete(st.volumes, path)
+	delete(st.nfsShares, path)
+	delete(st.smbShares, path)
+	delete(st.acls, path)
+}
+
+func (s *Server) updateSnapshot(c *call) (int, interface{}, *apiError) {
+	snapshot, ok := s.state.snapshots[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot '%s' not found", c.params[0])
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+	for k := range props {
+		if k != "userProperties" {
+			return 0, nil, badArgError("Snapshot property '%s' cannot be changed", k)
+		}
+	}
+	updateProperties(snapshot, props)
+
+	return http.StatusOK, nil, nil
+}
+
+// renameSnapshot renames snapshot within its dataset, clones are linked to the new snapshot path
+func (s *Server) renameSnapshot(c *call) (int, interface{}, *apiError) {
+	oldPath := c.params[0]
+	snapshot, ok := s.state.snapshots[oldPath]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot '%s' not found", oldPath)
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	newName := props.str("newName")
+	if newName == "" || strings.ContainsAny(newName, "@/") {
+		return 0, nil, badArgError("Parameter 'newName' must be a snapshot name, got: '%s'", newName)
+	}
+
+	newPath := snapshot.str("parent") + "@" + newName
+	if _, ok := s.state.snapshots[newPath]; ok {
+		return 0, nil, existError("Snapshot '%s' already exists", newPath)
+	}
+
+	delete(s.state.snapshots, oldPath)
+	snapshot["path"] = newPath
+	snapshot["name"] = newName
+	s.state.snapshots[newPath] = snapshot
+
+	for _, datasets := range []map[string]object{s.state.filesystems, s.state.volumes} {
+		for _, dataset := range datasets {
+			if dataset.str("originalSnapshot") == oldPath {
+				dataset["originalSnapshot"] = newPath
+			}
+		}
+	}
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) getSnapshotHolds(c *call) (int, interface{}, *apiError) {
+	snapshot, ok := s.state.snapshots[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot '%s' not found", c.params[0])
+	}
+
+	holds := snapshotHolds(snapshot)
+	tags := []string{}
+	for tag := range holds {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	data := []object{}
+	for _, tag := range tags {
+		data = append(data, holds[tag])
+	}
+
+	return http.StatusOK, object{"data": data}, nil
+}
+
+func (s *Server) holdSnapshot(c *call) (int, interface{}, *apiError) {
+	snapshot, ok := s.state.snapshots[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot '%s' not found", c.params[0])
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	tag := props.str("tag")
+	if tag == "" {
+		return 0, nil, badArgError("Parameter 'tag' is required")
+	}
+
+	holds := snapshotHolds(snapshot)
+	if holds == nil {
+		holds = map[string]object{}
+		snapshot["holds"] = holds
+	}
+	if _, ok := holds[tag]; ok {
+		return 0, nil, existError("Snapshot '%s' already has hold '%s'", c.params[0], tag)
+	}
+	holds[tag] = object{
+		"tag":          tag,
+		"creationTime": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) releaseSnapshot(c *call) (int, interface{}, *apiError) {
+	snapshot, ok := s.state.snapshots[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot '%s' not found", c.params[0])
+	}
+
+	holds := snapshotHolds(snapshot)
+	if _, ok := holds[c.params[1]]; !ok {
+		return 0, nil, notFoundError("Snapshot '%s' has no hold '%s'", c.params[0], c.params[1])
+	}
+	delete(holds, c.params[1])
+
+	return http.StatusOK, nil, nil
+}
+
+// getSnapshotSchedules returns snapshot schedules filtered by "dataset" query param,
+// schedules are stored only, snapshots are not created by the server
+func (s *Server) getSnapshotSchedules(c *call) (int, interface{}, *apiError) {
+	dataset := c.query.Get("dataset")
+
+	list := []object{}
+	for _, name := range sortedKeys(s.state.snapshotSchedules) {
+		schedule := s.state.snapshotSchedules[name]
+		if dataset == "" || schedule.str("dataset") == dataset {
+			list = append(list, schedule)
+		}
+	}
+
+	list, err := paginate(c, list)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return http.StatusOK, object{"data": list}, nil
+}
+
+func (s *Server) createSnapshotSchedule(c *call) (int, interface{}, *apiError) {
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+
+	name := props.str("name")
+	if name == "" {
+		return 0, nil, badArgError("Parameter 'name' is required")
+	} else if props.int64("interval") < 60 {
+		return 0, nil, badArgError("Parameter 'interval' must be at least 60 seconds")
+	} else if props.int64("keep") <= 0 {
+		return 0, nil, badArgError("Parameter 'keep' must be greater than 0")
+	} else if _, ok := s.state.snapshotSchedules[name]; ok {
+		return 0, nil, existError("Snapshot schedule '%s' already exists", name)
+	}
+
+	dataset := props.str("dataset")
+	if _, ok := s.state.filesystems[dataset]; !ok {
+		if _, ok := s.state.volumes[dataset]; !ok {
+			return 0, nil, notFoundError("Dataset '%s' not found", dataset)
+		}
+	}
+
+	s.state.snapshotSchedules[name] = props
+
+	return http.StatusCreated, nil, nil
+}
+
+func (s *Server) getSnapshotSchedule(c *call) (int, interface{}, *apiError) {
+	schedule, ok := s.state.snapshotSchedules[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot schedule '%s' not found", c.params[0])
+	}
+	return http.StatusOK, schedule, nil
+}
+
+func (s *Server) updateSnapshotSchedule(c *call) (int, interface{}, *apiError) {
+	schedule, ok := s.state.snapshotSchedules[c.params[0]]
+	if !ok {
+		return 0, nil, notFoundError("Snapshot schedule '%s' not found", c.params[0])
+	}
+
+	props := object{}
+	if err := c.decode(&props); err != nil {
+		return 0, nil, err
+	}
+	for _, key := range []string{"name", "dataset"} {
+		if _, ok := props[key]; ok {
+			return 0, nil, badArgError("Snapshot schedule property '%s' cannot be changed", key)
+		}
+	}
+	for k, v := range props {
+		schedule[k] = v
+	}
+
+	return http.StatusOK, nil, nil
+}
+
+func (s *Server) destroySnapshotSchedule(c *call) (int, interface{}, *apiError) {
+	if _, ok := s.state.snapshotSchedules[c.params[0]]; !ok {
+		return 0, nil, notFoundError("Snapshot schedule '%s' not found", c.params[0])
+	}
+
+	delete(s.state.snapshotSchedules, c.params[0])
+
+	return http.StatusOK, nil, nil
+}
@@ -0,0 +1,272 @@
+package ns
+
+import (
+	"strings"
+)
+
+// CompressionMode - dataset compression algorithm ("compression" ZFS property)
+type CompressionMode string
+
+// compression modes, "gzip-1".."gzip-9" levels are also accepted
+const (
+	CompressionModeOff  CompressionMode = "off"
+	CompressionModeOn   CompressionMode = "on"
+	CompressionModeLZ4  CompressionMode = "lz4"
+	CompressionModeGzip CompressionMode = "gzip"
+	CompressionModeZLE  CompressionMode = "zle"
+	CompressionModeLZJB CompressionMode = "lzjb"
+)
+
+// SyncMode - synchronous requests behavior ("sync" ZFS property)
+type SyncMode string
+
+// sync modes
+const (
+	SyncModeStandard SyncMode = "standard"
+	SyncModeAlways   SyncMode = "always"
+	SyncModeDisabled SyncMode = "disabled"
+)
+
+// LogBias - synchronous requests optimization ("logbias" ZFS property)
+type LogBias string
+
+// log bias values
+const (
+	LogBiasLatency    LogBias = "latency"
+	LogBiasThroughput LogBias = "throughput"
+)
+
+// CaseSensitivity - file name matching ("casesensitivity" ZFS property), can be set on creation only
+type CaseSensitivity string
+
+// case sensitivity values
+const (
+	CaseSensitivitySensitive   CaseSensitivity = "sensitive"
+	CaseSensitivityInsensitive CaseSensitivity = "insensitive"
+	CaseSensitivityMixed       CaseSensitivity = "mixed"
+)
+
+// DedupMode - deduplication mode ("dedup" ZFS property)
+type DedupMode string
+
+// dedup modes
+const (
+	DedupModeOff    DedupMode = "off"
+	DedupModeOn     DedupMode = "on"
+	DedupModeVerify DedupMode = "verify"
+)
+
+const (
+	minRecordSize = 512
+	maxRecordSize = 1024 * 1024
+
+	minVolumeBlockSize = 512
+	maxVolumeBlockSize = 128 * 1024
+)
+
+// nefFilesystemFields - filesystem fields requested from NexentaStor
+const nefFilesystemFields = "path,mountPoint,bytesAvailable,bytesUsed,sharedOverNfs,sharedOverSmb," +
+	"quotaSize,referencedQuotaSize,reservationSize,referencedReservationSize," +
+	"compressionMode,recordSize,accessTime,syncMode,logBias,readOnly,nonBlockingMandatoryMode," +
+	"caseSensitivity,userProperties"
+
+// Bool returns a pointer to the value, it's used to set optional boolean params
+func Bool(value bool) *bool {
+	return &value
+}
+
+// Int64 returns a pointer to the value, it's used to set optional size params which can be set to 0
+func Int64(value int64) *int64 {
+	return &value
+}
+
+// int64Value returns the value of optional param, 0 if it's not set
+func int64Value(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// datasetProperties - ZFS properties common for filesystems and volumes, zero values are not validated
+type datasetProperties struct {
+	compressionMode CompressionMode
+	syncMode        SyncMode
+	logBias         LogBias
+	quotaSize       int64
+	reservationSize int64
+	userProperties  map[string]string
+}
+
+func (props datasetProperties) validate(prefix string) error {
+	if mode := props.compressionMode; mode != "" {
+		switch mode {
+		case CompressionModeOff, CompressionModeOn, CompressionModeLZ4,
+			CompressionModeGzip, CompressionModeZLE, CompressionModeLZJB:
+		default:
+			if !isGzipLevel(mode) {
+				return newBadArgError("%s.CompressionMode has unsupported value '%s'", prefix, mode)
+			}
+		}
+	}
+
+	switch props.syncMode {
+	case "", SyncModeStandard, SyncModeAlways, SyncModeDisabled:
+	default:
+		return newBadArgError("%s.SyncMode has unsupported value '%s'", prefix, props.syncMode)
+	}
+
+	switch props.logBias {
+	case "", LogBiasLatency, LogBiasThroughput:
+	default:
+		return newBadArgError("%s.LogBias has unsupported value '%s'", prefix, props.logBias)
+	}
+
+	if props.quotaSize < 0 || props.reservationSize < 0 {
+		return newBadArgError("%s: sizes must not be negative", prefix)
+	} else if props.quotaSize > 0 && props.reservationSize > props.quotaSize {
+		return newBadArgError(
+			"%s.ReservationSize (%d) must not be greater than QuotaSize (%d)",
+			prefix,
+			props.reservationSize,
+			props.quotaSize,
+		)
+	}
+
+	for name := range props.userProperties {
+		// ZFS user property names must contain a colon to be distinguished from native properties
+		if !strings.Contains(name, ":") || strings.HasPrefix(name, ":") {
+			return newBadArgError(
+				"%s.UserProperties: name '%s' must be in 'module:property' format",
+				prefix,
+				name,
+			)
+		}
+	}
+
+	return nil
+}
+
+// isGzipLevel checks if the mode is gzip with compression level: "gzip-1".."gzip-9"
+func isGzipLevel(mode CompressionMode) bool {
+	return len(mode) == len("gzip-1") && strings.HasPrefix(string(mode), "gzip-") && mode[5] >= '1' && mode[5] <= '9'
+}
+
+// validateRecordSize checks that the size is a power of two in min..max range, 0 means not set
+func validateRecordSize(prefix string, size, min, max int64) error {
+	if size == 0 {
+		return nil
+	} else if size < min || size > max || size&(size-1) != 0 {
+		return newBadArgError("%s must be a power of two between %d and %d, got: %d", prefix, min, max, size)
+	}
+	return nil
+}
+
+// validate checks filesystem params before sending them to NexentaStor
+func (params CreateFilesystemParams) validate() error {
+	switch params.CaseSensitivity {
+	case "", CaseSensitivitySensitive, CaseSensitivityInsensitive, CaseSensitivityMixed:
+	default:
+		return newBadArgError(
+			"CreateFilesystemParams.CaseSensitivity has unsupported value '%s'",
+			params.CaseSensitivity,
+		)
+	}
+
+	err := validateRecordSize("CreateFilesystemParams.RecordSize", params.RecordSize, minRecordSize, maxRecordSize)
+	if err != nil {
+		return err
+	}
+
+	return datasetProperties{
+		compressionMode: params.CompressionMode,
+		syncMode:        params.SyncMode,
+		logBias:         params.LogBias,
+		quotaSize:       params.QuotaSize,
+		reservationSize: params.ReservationSize,
+		userProperties:  params.UserProperties,
+	}.validate("CreateFilesystemParams")
+}
+
+// validate checks filesystem params before sending them to NexentaStor
+func (params UpdateFilesystemParams) validate() error {
+	err := validateRecordSize("UpdateFilesystemParams.RecordSize", params.RecordSize, minRecordSize, maxRecordSize)
+	if err != nil {
+		return err
+	}
+
+	return datasetProperties{
+		compressionMode: params.CompressionMode,
+		syncMode:        params.SyncMode,
+		logBias:         params.LogBias,
+		quotaSize:       int64Value(params.QuotaSize),
+		reservationSize: int64Value(params.ReservationSize),
+		userProperties:  params.UserProperties,
+	}.validate("UpdateFilesystemParams")
+}
+
+// validate checks volume params before sending them to NexentaStor
+func (params CreateVolumeParams) validate() error {
+	err := validateRecordSize(
+		"CreateVolumeParams.VolumeBlockSize",
+		params.VolumeBlockSize,
+		minVolumeBlockSize,
+		maxVolumeBlockSize,
+	)
+	if err != nil {
+		return err
+	}
+
+	if params.VolumeSize <= 0 {
+		return newBadArgError("CreateVolumeParams.VolumeSize must be greater than 0, got: %d", params.VolumeSize)
+	} else if params.VolumeBlockSize > 0 && params.VolumeSize%params.VolumeBlockSize != 0 {
+		return newBadArgError(
+			"CreateVolumeParams.VolumeSize (%d) must be a multiple of VolumeBlockSize (%d)",
+			params.VolumeSize,
+			params.VolumeBlockSize,
+		)
+	} else if params.SparseVolume && params.ReservationSize > 0 {
+		return newBadArgError("CreateVolumeParams.ReservationSize cannot be set for sparse volume")
+	} else if params.ReservationSize > params.VolumeSize {
+		return newBadArgError(
+			"CreateVolumeParams.ReservationSize (%d) must not be greater than VolumeSize (%d)",
+			params.ReservationSize,
+			params.VolumeSize,
+		)
+	}
+
+	if err := validateDedupMode("CreateVolumeParams", params.DedupMode); err != nil {
+		return err
+	}
+
+	return datasetProperties{
+		compressionMode: params.CompressionMode,
+		syncMode:        params.SyncMode,
+		reservationSize: params.ReservationSize,
+		userProperties:  params.UserProperties,
+	}.validate("CreateVolumeParams")
+}
+
+// validate checks volume params before sending them to NexentaStor
+func (params UpdateVolumeParams) validate() error {
+	if params.VolumeSize < 0 {
+		return newBadArgError("UpdateVolumeParams.VolumeSize must not be negative, got: %d", params.VolumeSize)
+	} else if err := validateDedupMode("UpdateVolumeParams", params.DedupMode); err != nil {
+		return err
+	}
+
+	return datasetProperties{
+		compressionMode: params.CompressionMode,
+		syncMode:        params.SyncMode,
+		reservationSize: int64Value(params.ReservationSize),
+		userProperties:  params.UserProperties,
+	}.validate("UpdateVolumeParams")
+}
+
+func validateDedupMode(prefix string, mode DedupMode) error {
+	switch mode {
+	case "", DedupModeOff, DedupModeOn, DedupModeVerify:
+		return nil
+	}
+	return newBadArgError("%s.DedupMode has unsupported value '%s'", prefix, mode)
+}
@@ -1,88 +1,131 @@
 package ns
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/sirupsen/logrus"
-
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
 	"github.com/Nexenta/go-nexentastor/pkg/rest"
 )
 
-const (
-	checkJobStatusInterval = 3 * time.Second
-	checkJobStatusTimeout  = 60 * time.Second
-)
-
 // ProviderInterface - NexentaStor provider interface
 type ProviderInterface interface {
 	// system
-	LogIn() error
-	IsJobDone(jobID string) (bool, error)
-	GetLicense() (License, error)
-	GetRSFClusters() ([]RSFCluster, error)
+	LogIn(ctx context.Context) error
+	IsJobDone(ctx context.Context, jobID string) (bool, error)
+	StartJob(ctx context.Context, fn func(ctx context.Context) error) (*Job, error)
+	GetLicense(ctx context.Context) (License, error)
+	GetRSFClusters(ctx context.Context) ([]RSFCluster, error)
 
 	// pools
-	GetPools() ([]Pool, error)
+	GetPools(ctx context.Context) ([]Pool, error)
 
 	// filesystems
-	CreateFilesystem(params CreateFilesystemParams) error
-	UpdateFilesystem(path string, params UpdateFilesystemParams) error
-	DestroyFilesystem(path string, params DestroyFilesystemParams) error
-	SetFilesystemACL(path string, aclRuleSet ACLRuleSet) error
-	GetFilesystem(path string) (Filesystem, error)
-	GetFilesystemAvailableCapacity(path string) (int64, error)
-	GetFilesystems(parent string) ([]Filesystem, error)
-	GetFilesystemsWithStartingToken(parent string, startingToken string, limit int) ([]Filesystem, string, error)
-	GetFilesystemsSlice(parent string, limit, offset int) ([]Filesystem, error)
+	CreateFilesystem(ctx context.Context, params CreateFilesystemParams) error
+	UpdateFilesystem(ctx context.Context, path string, params UpdateFilesystemParams) error
+	DestroyFilesystem(ctx context.Context, path string, params DestroyFilesystemParams) error
+	SetFilesystemACL(ctx context.Context, path string, aclRuleSet ACLRuleSet) error
+	GetFilesystemACL(ctx context.Context, path string) ([]ACE, error)
+	AddFilesystemACE(ctx context.Context, path string, ace ACE) error
+	InsertFilesystemACE(ctx context.Context, path string, index int, ace ACE) error
+	ReplaceFilesystemACE(ctx context.Context, path string, index int, ace ACE) error
+	RemoveFilesystemACE(ctx context.Context, path string, index int) error
+	ReconcileFilesystemACL(ctx context.Context, path string, desired []ACE) (ACLDiff, error)
+	GetFilesystem(ctx context.Context, path string) (Filesystem, error)
+	GetFilesystemAvailableCapacity(ctx context.Context, path string) (int64, error)
+	GetFilesystems(ctx context.Context, parent string) ([]Filesystem, error)
+	GetFilesystemsWithStartingToken(ctx context.Context, parent string, startingToken string, limit int) ([]Filesystem, string, error)
+	GetFilesystemsSlice(ctx context.Context, parent string, limit, offset int) ([]Filesystem, error)
+	IterateFilesystems(ctx context.Context, parent string, params IteratorParams) *FilesystemIterator
+	RenameFilesystem(ctx context.Context, path, newPath string, params RenameFilesystemParams) error
 
 	// filesystems - nfs share
-	CreateNfsShare(params CreateNfsShareParams) error
-	DeleteNfsShare(path string) error
+	CreateNfsShare(ctx context.Context, params CreateNfsShareParams) error
+	DeleteNfsShare(ctx context.Context, path string) error
+	GetNfsShare(ctx context.Context, path string) (NfsShare, error)
+	ListNfsShares(ctx context.Context, parent string) ([]NfsShare, error)
+	UpdateNfsShare(ctx context.Context, path string, params UpdateNfsShareParams) error
 
 	// filesystems - smb share
-	CreateSmbShare(params CreateSmbShareParams) error
-	DeleteSmbShare(path string) error
-	GetSmbShareName(path string) (string, error)
+	CreateSmbShare(ctx context.Context, params CreateSmbShareParams) error
+	DeleteSmbShare(ctx context.Context, path string) error
+	GetSmbShareName(ctx context.Context, path string) (string, error)
+	GetSmbShare(ctx context.Context, path string) (SmbShare, error)
+	ListSmbShares(ctx context.Context, parent string) ([]SmbShare, error)
+	UpdateSmbShare(ctx context.Context, path string, params UpdateSmbShareParams) error
 
 	// snapshots
-	CreateSnapshot(params CreateSnapshotParams) error
-	DestroySnapshot(path string) error
-	GetSnapshot(path string) (Snapshot, error)
-	GetSnapshots(volumePath string, recursive bool) ([]Snapshot, error)
-	CloneSnapshot(path string, params CloneSnapshotParams) error
-	PromoteFilesystem(path string) error
+	CreateSnapshot(ctx context.Context, params CreateSnapshotParams) error
+	DestroySnapshot(ctx context.Context, path string) error
+	GetSnapshot(ctx context.Context, path string) (Snapshot, error)
+	GetSnapshots(ctx context.Context, volumePath string, recursive bool) ([]Snapshot, error)
+	IterateSnapshots(ctx context.Context, volumePath string, recursive bool, params IteratorParams) *SnapshotIterator
+	CloneSnapshot(ctx context.Context, path string, params CloneSnapshotParams) error
+	RollbackFilesystem(ctx context.Context, snapshotPath string, params RollbackSnapshotParams) error
+	RollbackVolume(ctx context.Context, snapshotPath string, params RollbackSnapshotParams) error
+	UpdateSnapshot(ctx context.Context, path string, params UpdateSnapshotParams) error
+	RenameSnapshot(ctx context.Context, path, newName string) error
+	HoldSnapshot(ctx context.Context, path, tag string) error
+	ReleaseSnapshot(ctx context.Context, path, tag string) error
+	GetSnapshotHolds(ctx context.Context, path string) ([]SnapshotHold, error)
+	ApplyRetentionPolicy(ctx context.Context, dataset string, policy RetentionPolicy) ([]Snapshot, error)
+	PromoteFilesystem(ctx context.Context, path string) error
+
+	// snapshot schedules
+	CreateSnapshotSchedule(ctx context.Context, params CreateSnapshotScheduleParams) error
+	GetSnapshotSchedule(ctx context.Context, name string) (SnapshotSchedule, error)
+	GetSnapshotSchedules(ctx context.Context, dataset string) ([]SnapshotSchedule, error)
+	UpdateSnapshotSchedule(ctx context.Context, name string, params UpdateSnapshotScheduleParams) error
+	DestroySnapshotSchedule(ctx context.Context, name string) error
+
+	// replication (HPR)
+	CreateReplicationService(ctx context.Context, params CreateReplicationServiceParams) error
+	GetReplicationService(ctx context.Context, name string) (ReplicationService, error)
+	GetReplicationServices(ctx context.Context, sourceDataset string) ([]ReplicationService, error)
+	GetReplicationServiceStatus(ctx context.Context, name string) (ReplicationStatus, error)
+	StartReplicationService(ctx context.Context, name string) error
+	StopReplicationService(ctx context.Context, name string) error
+	SendReplicationNow(ctx context.Context, name string, params SendReplicationParams) error
+	DestroyReplicationService(ctx context.Context, name string, params DestroyReplicationServiceParams) error
 
 	// volumes
-	CreateVolume(params CreateVolumeParams) error
-	GetVolume(path string) (Volume, error)
-	GetVolumes(parent string) ([]Volume, error)
-	UpdateVolume(path string, params UpdateVolumeParams) error
-	DestroyVolume(path string, params DestroyVolumeParams) error
-	GetVolumeGroup(path string) (VolumeGroup, error)
-	GetVolumesWithStartingToken(parent string, startingToken string, limit int) ([]Volume, string, error)
-	PromoteVolume(path string) error
+	CreateVolume(ctx context.Context, params CreateVolumeParams) error
+	GetVolume(ctx context.Context, path string) (Volume, error)
+	GetVolumes(ctx context.Context, parent string) ([]Volume, error)
+	UpdateVolume(ctx context.Context, path string, params UpdateVolumeParams) error
+	DestroyVolume(ctx context.Context, path string, params DestroyVolumeParams) error
+	GetVolumeGroup(ctx context.Context, path string) (VolumeGroup, error)
+	GetVolumesWithStartingToken(ctx context.Context, parent string, startingToken string, limit int) ([]Volume, string, error)
+	IterateVolumes(ctx context.Context, parent string, params IteratorParams) *VolumeIterator
+	PromoteVolume(ctx context.Context, path string) error
+	RenameVolume(ctx context.Context, path, newPath string) error
+	CloneVolumeSnapshot(ctx context.Context, snapshotPath string, params CloneVolumeSnapshotParams) error
+	CloneVolume(ctx context.Context, sourcePath string, params CloneVolumeSnapshotParams) error
 
 	// iSCSI
-	CreateLunMapping(params CreateLunMappingParams) error
-	GetLunMapping(path string) (LunMapping, error)
-	GetLunMappings(params GetLunMappingsParams) (lunMappings []LunMapping, err error)
-	DestroyLunMapping(id string) error
-	CreateISCSITarget(params CreateISCSITargetParams) error
-	UpdateISCSITarget(name string, params UpdateISCSITargetParams) error
-	GetISCSITarget(name string) (target ISCSITarget, err error)
-	GetTargetGroups() ([]TargetGroup, error)
-	GetTargetGroup(name string) (targetGroup TargetGroup, err error)
-	CreateUpdateTargetGroup(params CreateTargetGroupParams) error
-	CreateHostGroup(params CreateHostGroupParams) error
-	GetHostGroups() ([]nefHostGroup, error)
-	UpdateHostGroup(path string, params UpdateHostGroupParams) error
-	GetRemoteInitiator(name string) (remoteInitiator RemoteInitiator, err error)
-	CreateRemoteInitiator(params CreateRemoteInitiatorParams) error
-	UpdateRemoteInitiator(name string, params UpdateRemoteInitiatorParams) error
+	CreateLunMapping(ctx context.Context, params CreateLunMappingParams) error
+	GetLunMapping(ctx context.Context, path string) (LunMapping, error)
+	GetLunMappings(ctx context.Context, params GetLunMappingsParams) (lunMappings []LunMapping, err error)
+	IterateLunMappings(ctx context.Context, filter GetLunMappingsParams, params IteratorParams) *LunMappingIterator
+	DestroyLunMapping(ctx context.Context, id string) error
+	CreateISCSITarget(ctx context.Context, params CreateISCSITargetParams) error
+	UpdateISCSITarget(ctx context.Context, name string, params UpdateISCSITargetParams) error
+	GetISCSITarget(ctx context.Context, name string) (target ISCSITarget, err error)
+	GetTargetGroups(ctx context.Context) ([]TargetGroup, error)
+	GetTargetGroup(ctx context.Context, name string) (targetGroup TargetGroup, err error)
+	CreateUpdateTargetGroup(ctx context.Context, params CreateTargetGroupParams) error
+	CreateHostGroup(ctx context.Context, params CreateHostGroupParams) error
+	GetHostGroups(ctx context.Context) ([]nefHostGroup, error)
+	UpdateHostGroup(ctx context.Context, path string, params UpdateHostGroupParams) error
+	GetRemoteInitiator(ctx context.Context, name string) (remoteInitiator RemoteInitiator, err error)
+	CreateRemoteInitiator(ctx context.Context, params CreateRemoteInitiatorParams) error
+	UpdateRemoteInitiator(ctx context.Context, name string, params UpdateRemoteInitiatorParams) error
 }
 
 // Provider - NexentaStor API provider
@@ -91,7 +134,26 @@ type Provider struct {
 	Username   string
 	Password   string
 	RestClient rest.ClientInterface
-	Log        *logrus.Entry
+	Log        logger.Logger
+
+	// RetryPolicy to repeat failed requests and async jobs, no retries if not set
+	RetryPolicy *rest.RetryPolicy
+
+	// JobPollInterval and JobTimeout for async jobs, defaults are used if not set
+	JobPollInterval time.Duration
+	JobTimeout      time.Duration
+
+	// TokenRefreshWindow - time before token expiration to log in again, negative disables refresh
+	TokenRefreshWindow time.Duration
+
+	errorObserversMux sync.RWMutex
+	errorObservers    []func(ctx context.Context, path string, err error)
+
+	// auth token state, see setAuthToken() and reLogIn()
+	tokenMux        sync.Mutex
+	tokenGeneration uint64
+	tokenExpires    time.Time
+	loginCall       *loginCall
 }
 
 func (p *Provider) String() string {
@@ -99,45 +161,14 @@ func (p *Provider) String() string {
 }
 
 func (p *Provider) parseNefError(bodyBytes []byte, prefix string) error {
-	var restErrorMessage string
-	var restErrorCode string
-
-	response := struct {
-		Name    string `json:"name"`
-		Message string `json:"message"`
-		Errors  string `json:"errors"`
-		Code    string `json:"code"`
-	}{}
-
-	if err := json.Unmarshal(bodyBytes, &response); err != nil {
-		return nil
+	if nefError := parseNefErrorResponse(bodyBytes, prefix); nefError != nil {
+		return nefError
 	}
-
-	if response.Name != "" {
-		restErrorMessage = fmt.Sprint(response.Name)
-	}
-	if response.Message != "" {
-		restErrorMessage = fmt.Sprintf("%s: %s", restErrorMessage, response.Message)
-	}
-	if response.Errors != "" {
-		restErrorMessage = fmt.Sprintf("%s, errors: [%s]", restErrorMessage, response.Errors)
-	}
-	if response.Code != "" {
-		restErrorCode = response.Code
-	}
-
-	if restErrorMessage != "" {
-		return &NefError{
-			Err:  fmt.Errorf("%s: %s", prefix, restErrorMessage),
-			Code: restErrorCode,
-		}
-	}
-
 	return nil
 }
 
-func (p *Provider) sendRequestWithStruct(method, path string, data, response interface{}) error {
-	bodyBytes, err := p.doAuthRequest(method, path, data)
+func (p *Provider) sendRequestWithStruct(ctx context.Context, method, path string, data, response interface{}) error {
+	bodyBytes, err := p.doAuthRequest(ctx, method, path, data)
 	if err != nil {
 		return err
 	}
@@ -165,53 +196,86 @@ func (p *Provider) sendRequestWithStruct(method, path string, data, response int
 	return nil
 }
 
-func (p *Provider) sendRequest(method, path string, data interface{}) error {
-	_, err := p.doAuthRequest(method, path, data)
+func (p *Provider) sendRequest(ctx context.Context, method, path string, data interface{}) error {
+	_, err := p.doAuthRequest(ctx, method, path, data)
 	return err
 }
 
-func (p *Provider) doAuthRequest(method, path string, data interface{}) ([]byte, error) {
+func (p *Provider) doAuthRequest(ctx context.Context, method, path string, data interface{}) (
+	bodyBytes []byte,
+	err error,
+) {
 	l := p.Log.WithField("func", "doAuthRequest()")
 
-	statusCode, bodyBytes, err := p.RestClient.Send(method, path, data)
-	if err != nil {
-		return bodyBytes, err
-	}
-
-	nefError := p.parseNefError(bodyBytes, "checking login status")
-
-	// log in again if user is not logged in
-	if statusCode == http.StatusUnauthorized && IsAuthNefError(nefError) {
-		// do login call if used is not authorized in api
-		l.Debugf("log in as '%s'...", p.Username)
-
-		err = p.LogIn()
+	defer func() {
 		if err != nil {
-			return nil, err
+			p.notifyErrorObservers(ctx, datasetPathFromURI(path), err)
+		}
+	}()
+
+	// HTTP level errors are retried by the rest client, but it can't see async job results,
+	// so failed jobs with retryable error codes (e.g. EBUSY) are retried here
+	maxAttempts := p.RetryPolicy.Attempts(method)
+	for attempt := 1; ; attempt++ {
+		var isJobError bool
+		bodyBytes, isJobError, err = p.doAuthRequestAttempt(ctx, method, path, data)
+		if !isJobError || attempt >= maxAttempts || !p.RetryPolicy.IsRetryableErrorCode(GetNefErrorCode(err)) {
+			return bodyBytes, err
 		}
 
-		// send original request again
-		statusCode, bodyBytes, err = p.RestClient.Send(method, path, data)
-		if err != nil {
+		l.WithField("attempt", attempt).Debugf(
+			"async job of '%s %s' failed (attempt %d of %d): %s, retrying...",
+			method,
+			path,
+			attempt,
+			maxAttempts,
+			err,
+		)
+		if waitErr := p.RetryPolicy.Wait(ctx, attempt); waitErr != nil {
 			return bodyBytes, err
 		}
 	}
+}
+
+// doAuthRequestAttempt sends request, logs in if needed and waits for async job completion,
+// isJobError is true if the request was accepted but its async job failed
+func (p *Provider) doAuthRequestAttempt(ctx context.Context, method, path string, data interface{}) (
+	bodyBytes []byte,
+	isJobError bool,
+	err error,
+) {
+	l := p.Log.WithField("func", "doAuthRequest()")
+
+	statusCode, bodyBytes, err := p.sendWithAuth(ctx, method, path, data)
+	if err != nil {
+		return bodyBytes, false, err
+	}
 
 	if statusCode == http.StatusAccepted {
 		// this is an async job
 		var href string
 		href, err = p.parseAsyncJobHref(bodyBytes)
 		if err != nil {
-			return bodyBytes, err
+			return bodyBytes, false, err
+		}
+
+		jobID := strings.TrimPrefix(href, "/jobStatus/")
+		if p.captureJob(ctx, jobID, method, path) {
+			l.Debugf("job '%s' is started in non-blocking mode", jobID)
+			return bodyBytes, false, nil
 		}
 
-		err = p.waitForAsyncJob(strings.TrimPrefix(href, "/jobStatus/"))
+		err = p.waitForAsyncJob(ctx, jobID)
 		if err != nil {
+			setNefErrorRequest(err, method, path)
 			l.Debugf("waitForAsyncJob() error: %s", err)
+			return bodyBytes, true, err
 		}
 	} else if statusCode >= 300 {
-		nefError := p.parseNefError(bodyBytes, "request error")
-		if nefError != nil {
+		if nefError := parseNefErrorResponse(bodyBytes, "request error"); nefError != nil {
+			nefError.StatusCode = statusCode
+			nefError.Method = method
+			nefError.Path = path
 			err = nefError
 		} else {
 			err = fmt.Errorf(
@@ -222,7 +286,88 @@ func (p *Provider) doAuthRequest(method, path string, data interface{}) ([]byte,
 		}
 	}
 
-	return bodyBytes, err
+	return bodyBytes, false, err
+}
+
+// sendWithAuth sends request with current token, the token is refreshed before it expires,
+// the request is sent again after login if it's failed with EAUTH
+func (p *Provider) sendWithAuth(ctx context.Context, method, path string, data interface{}) (int, []byte, error) {
+	p.refreshAuthToken(ctx)
+
+	tokenGeneration := p.getTokenGeneration()
+	statusCode, bodyBytes, err := p.RestClient.SendContext(ctx, method, path, data)
+	if err != nil {
+		return statusCode, bodyBytes, err
+	}
+
+	nefError := p.parseNefError(bodyBytes, "checking login status")
+
+	// log in again if user is not logged in
+	if statusCode == http.StatusUnauthorized && IsAuthNefError(nefError) {
+		// do login call if used is not authorized in api,
+		// skipped if the token was updated by another request while this one was in flight
+		if err := p.reLogIn(ctx, tokenGeneration); err != nil {
+			return 0, nil, err
+		}
+
+		// send original request again
+		return p.RestClient.SendContext(ctx, method, path, data)
+	}
+
+	return statusCode, bodyBytes, nil
+}
+
+// datasetPathFromURI returns dataset path of requests to a dataset (e.g. "storage/filesystems/p%2Ffs")
+// or its actions and collections (e.g. ".../p%2Ffs/rename"), empty string for other requests
+func datasetPathFromURI(uri string) string {
+	segments := strings.Split(strings.SplitN(uri, "?", 2)[0], "/")
+	if len(segments) < 3 || len(segments) > 4 || segments[0] != "storage" {
+		return ""
+	}
+
+	switch segments[1] {
+	case "filesystems", "volumes", "volumeGroups", "snapshots":
+		path, err := url.PathUnescape(segments[2])
+		if err != nil {
+			return ""
+		}
+		return path
+	}
+
+	return ""
+}
+
+// cleanupTimeout - maximum time of requests undoing partially done changes
+const cleanupTimeout = time.Minute
+
+// newCleanupContext returns a context for requests undoing partially done changes (e.g. destroy of
+// a clone failed to resize), it's not cancelled with the request context, so cleanup runs if the caller gave up
+func newCleanupContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), cleanupTimeout)
+}
+
+// addErrorObserver registers a function to call on failed requests (used by resolver cache),
+// path is set if the request was made to a dataset or a resource was looked up by path
+func (p *Provider) addErrorObserver(fn func(ctx context.Context, path string, err error)) {
+	p.errorObserversMux.Lock()
+	defer p.errorObserversMux.Unlock()
+	p.errorObservers = append(p.errorObservers, fn)
+}
+
+func (p *Provider) notifyErrorObservers(ctx context.Context, path string, err error) {
+	p.errorObserversMux.RLock()
+	defer p.errorObserversMux.RUnlock()
+	for _, fn := range p.errorObservers {
+		fn(ctx, path, err)
+	}
+}
+
+// notExistError returns ENOENT error for the resource not found by the request, NS responds
+// with an empty list in this case, so the error is reported to observers with the resource path
+func (p *Provider) notExistError(ctx context.Context, path string, err error) error {
+	nefErr := &NefError{Code: NefCodeNotExist, Err: err}
+	p.notifyErrorObservers(ctx, path, nefErr)
+	return nefErr
 }
 
 func (p *Provider) parseAsyncJobHref(bodyBytes []byte) (string, error) {
@@ -240,50 +385,42 @@ func (p *Provider) parseAsyncJobHref(bodyBytes []byte) (string, error) {
 	return "", fmt.Errorf("Request return an async job, but response doesn't contain any links: %v", bodyBytes)
 }
 
-// waitForAsyncJob - keep asking for job status while it's not completed, return an error if timeout exceeded
-func (p *Provider) waitForAsyncJob(jobID string) (err error) {
-	l := p.Log.WithField("job", jobID)
-
-	timer := time.NewTimer(0)
-	timeout := time.After(checkJobStatusTimeout)
-	startTime := time.Now()
-
-	for {
-		select {
-		case <-timer.C:
-			jobDone, err := p.IsJobDone(jobID)
-			if err != nil { // request failed
-				return err
-			} else if jobDone { // job is completed
-				return nil
-			} else {
-				waitingTimeSeconds := time.Since(startTime).Seconds()
-				if waitingTimeSeconds >= checkJobStatusInterval.Seconds() {
-					l.Warnf("waiting job for %.0fs...", waitingTimeSeconds)
-				}
-				timer = time.NewTimer(checkJobStatusInterval)
-			}
-		case <-timeout:
-			timer.Stop()
-			return fmt.Errorf("Checking job status timeout exceeded (%ds)", checkJobStatusTimeout)
-		}
-	}
-}
-
 // ProviderArgs - params to create Provider instance
 type ProviderArgs struct {
 	Address  string
 	Username string
 	Password string
-	Log      *logrus.Entry
+
+	// Log - logger to use, messages are discarded if not set (see logger package for adapters)
+	Log logger.Logger
 
 	// InsecureSkipVerify controls whether a client verifies the server's certificate chain and host name.
 	InsecureSkipVerify bool
+
+	// RetryPolicy to repeat failed requests (see rest.DefaultRetryPolicy()), no retries if not set
+	RetryPolicy *rest.RetryPolicy
+
+	// JobPollInterval - interval between async job status requests, default: DefaultJobPollInterval
+	JobPollInterval time.Duration
+
+	// JobTimeout - time to wait for async job completion, default: DefaultJobTimeout
+	JobTimeout time.Duration
+
+	// TokenRefreshWindow - time before auth token expiration (JWT "exp" claim) to log in again,
+	// default: DefaultTokenRefreshWindow, negative value disables proactive refresh
+	TokenRefreshWindow time.Duration
+
+	// SensitiveFields - extra JSON fields to hide in logs, see rest.DefaultSensitiveFields()
+	SensitiveFields []string
 }
 
 // NewProvider creates NexentaStor provider instance
 func NewProvider(args ProviderArgs) (ProviderInterface, error) {
-	l := args.Log.WithFields(logrus.Fields{
+	log := args.Log
+	if log == nil {
+		log = logger.Noop()
+	}
+	l := log.WithFields(logger.Fields{
 		"cmp": "NSProvider",
 		"ns":  args.Address,
 	})
@@ -296,14 +433,21 @@ func NewProvider(args ProviderArgs) (ProviderInterface, error) {
 		Address:            args.Address,
 		Log:                l,
 		InsecureSkipVerify: args.InsecureSkipVerify,
+		RetryPolicy:        args.RetryPolicy,
+		SensitiveFields:    args.SensitiveFields,
 	})
 
 	l.Debugf("created for '%s'", args.Address)
 	return &Provider{
-		Address:    args.Address,
-		Username:   args.Username,
-		Password:   args.Password,
-		RestClient: restClient,
-		Log:        l,
+		Address:         args.Address,
+		Username:        args.Username,
+		Password:        args.Password,
+		RestClient:      restClient,
+		Log:             l,
+		RetryPolicy:     args.RetryPolicy,
+		JobPollInterval: args.JobPollInterval,
+		JobTimeout:      args.JobTimeout,
+
+		TokenRefreshWindow: args.TokenRefreshWindow,
 	}, nil
 }
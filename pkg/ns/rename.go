@@ -0,0 +1,210 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RenameFilesystemParams - params to rename (move) filesystem
+type RenameFilesystemParams struct {
+	// CreateParents - create missing parent filesystems of the new path ("zfs rename -p")
+	CreateParents bool
+
+	// ForceUnmount - unmount filesystem even if it's busy ("zfs rename -f")
+	ForceUnmount bool
+}
+
+// validateRenamePaths checks that dataset can be moved to the new path,
+// ZFS datasets cannot be moved across pools or into themselves
+func validateRenamePaths(path, newPath string) error {
+	if path == newPath {
+		return newBadArgError("New dataset path must differ from the current one, got: '%s'", newPath)
+	} else if strings.HasPrefix(newPath, path+"/") {
+		return newBadArgError("Dataset '%s' cannot be moved into itself, got: '%s'", path, newPath)
+	} else if strings.ContainsAny(newPath, "@") || strings.HasSuffix(newPath, "/") {
+		return newBadArgError("New dataset path must be in 'pool/dataset' format, got: '%s'", newPath)
+	}
+
+	pool := strings.SplitN(path, "/", 2)[0]
+	if !strings.HasPrefix(newPath, pool+"/") {
+		return newBadArgError("Dataset '%s' cannot be moved out of pool '%s', got: '%s'", path, pool, newPath)
+	}
+
+	return nil
+}
+
+// RenameShareError - filesystem was renamed, but its NFS or SMB shares were not created for the new path
+type RenameShareError struct {
+	Path    string
+	NewPath string
+	Err     error
+}
+
+func (e *RenameShareError) Error() string {
+	return fmt.Sprintf(
+		"Filesystem '%s' was renamed to '%s', but its shares were not restored: %s",
+		e.Path,
+		e.NewPath,
+		e.Err,
+	)
+}
+
+// Unwrap returns the share creation error
+func (e *RenameShareError) Unwrap() error {
+	return e.Err
+}
+
+// RenameFilesystem renames filesystem or moves it to another parent within the same pool,
+// child filesystems and snapshots are moved as well. If the filesystem is shared over NFS or SMB,
+// its shares are re-created for the new path with the same settings
+// (SMB share name is kept unless it's the default one).
+// Shares of child filesystems are not re-created, NexentaStor returns EBUSY if there are any.
+// EEXIST NefError is returned if the new path is in use, ENOENT one - if the filesystem is not found.
+// RenameShareError is returned if the filesystem was renamed, but its shares were not created for the new path.
+func (p *Provider) RenameFilesystem(ctx context.Context, path, newPath string, params RenameFilesystemParams) error {
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	} else if newPath == "" {
+		return fmt.Errorf("New filesystem path is required")
+	} else if err := validateRenamePaths(path, newPath); err != nil {
+		return err
+	}
+
+	l := p.Log.WithField("func", "RenameFilesystem()")
+
+	// shares are removed before rename, their jobs are waited in non-blocking mode
+	stepCtx := waitJobs(ctx)
+
+	fs, err := p.GetFilesystem(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	// shares are bound to the filesystem path, so they are removed before rename and created for the new path
+	var nfsShare *NfsShare
+	if fs.SharedOverNfs {
+		share, err := p.GetNfsShare(stepCtx, path)
+		if err != nil {
+			return err
+		} else if err := p.DeleteNfsShare(stepCtx, path); err != nil {
+			return err
+		}
+		nfsShare = &share
+	}
+
+	var smbShare *SmbShare
+	if fs.SharedOverSmb {
+		share, err := p.GetSmbShare(stepCtx, path)
+		if err != nil {
+			return p.withRestoredShares(err, path, nfsShare, nil)
+		}
+		if share.ShareName == fs.GetDefaultSmbShareName() {
+			share.ShareName = ""
+		}
+		if err := p.DeleteSmbShare(stepCtx, path); err != nil {
+			return p.withRestoredShares(err, path, nfsShare, nil)
+		}
+		smbShare = &share
+	}
+
+	uri := fmt.Sprintf("storage/filesystems/%s/rename", url.PathEscape(path))
+	data := &nefDatasetRenameRequest{
+		NewPath:       newPath,
+		CreateParents: params.CreateParents,
+		Force:         params.ForceUnmount,
+	}
+	// shares are created for the new path after rename, so rename job is waited in this case
+	renameCtx := ctx
+	if nfsShare != nil || smbShare != nil {
+		renameCtx = stepCtx
+	}
+	if err := p.sendRequest(renameCtx, http.MethodPost, uri, data); err != nil {
+		l.Debugf("rename of '%s' failed, restoring its shares: %s", path, err)
+		return p.withRestoredShares(err, path, nfsShare, smbShare)
+	}
+
+	if err := p.restoreShares(newPath, nfsShare, smbShare); err != nil {
+		return &RenameShareError{Path: path, NewPath: newPath, Err: err}
+	}
+
+	return nil
+}
+
+// RenameVolume renames volume or moves it to another volume group within the same pool.
+// Mapped volumes cannot be renamed, NexentaStor returns EBUSY for them.
+// EEXIST NefError is returned if the new path is in use, ENOENT one - if the volume is not found.
+func (p *Provider) RenameVolume(ctx context.Context, path, newPath string) error {
+	if path == "" {
+		return fmt.Errorf("Volume path is required")
+	} else if newPath == "" {
+		return fmt.Errorf("New volume path is required")
+	} else if err := validateRenamePaths(path, newPath); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("storage/volumes/%s/rename", url.PathEscape(path))
+	data := &nefDatasetRenameRequest{NewPath: newPath}
+
+	return p.sendRequest(ctx, http.MethodPost, uri, data)
+}
+
+// withRestoredShares restores shares of the filesystem after failed rename,
+// the rename error is returned, restore error is added to it if shares were not restored
+func (p *Provider) withRestoredShares(err error, path string, nfsShare *NfsShare, smbShare *SmbShare) error {
+	if restoreErr := p.restoreShares(path, nfsShare, smbShare); restoreErr != nil {
+		return fmt.Errorf("%w (shares of '%s' were not restored: %s)", err, path, restoreErr)
+	}
+	return err
+}
+
+// restoreShares creates NFS and SMB shares for the filesystem path, errors of both shares are returned.
+// Requests run on a cleanup context, so the shares are restored even if the request context is cancelled.
+func (p *Provider) restoreShares(path string, nfsShare *NfsShare, smbShare *SmbShare) error {
+	l := p.Log.WithField("func", "restoreShares()")
+
+	ctx, cancel := newCleanupContext()
+	defer cancel()
+
+	var nfsErr, smbErr error
+	if nfsShare != nil {
+		err := p.CreateNfsShare(ctx, CreateNfsShareParams{
+			Filesystem:       path,
+			SecurityContexts: nfsShare.SecurityContexts,
+			Anon:             nfsShare.Anon,
+			RootMapping:      nfsShare.RootMapping,
+			Nosuid:           nfsShare.Nosuid,
+			Nohide:           nfsShare.Nohide,
+			Versions:         nfsShare.Versions,
+		})
+		if err != nil {
+			l.Errorf("failed to create NFS share for '%s': %s", path, err)
+			nfsErr = fmt.Errorf("NFS share: %w", err)
+		}
+	}
+	if smbShare != nil {
+		data := &nefNasSmbRequest{
+			Filesystem:             path,
+			ShareName:              smbShare.ShareName,
+			Description:            smbShare.Description,
+			GuestOk:                smbShare.GuestOk,
+			AccessBasedEnumeration: smbShare.AccessBasedEnumeration,
+			EncryptData:            smbShare.EncryptData,
+			ShareQuota:             smbShare.ShareQuota,
+			ShareACL:               smbShare.ShareACL,
+		}
+		if err := p.sendRequest(ctx, http.MethodPost, "nas/smb", data); err != nil {
+			l.Errorf("failed to create SMB share for '%s': %s", path, err)
+			smbErr = fmt.Errorf("SMB share: %w", err)
+		}
+	}
+
+	if nfsErr != nil && smbErr != nil {
+		return fmt.Errorf("%w; %s", nfsErr, smbErr)
+	} else if nfsErr != nil {
+		return nfsErr
+	}
+	return smbErr
+}
@@ -0,0 +1,319 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ReplicationMode - High Performance Replication (HPR) send mode
+type ReplicationMode string
+
+const (
+	// ReplicationModeIncremental - send changes since the last replicated snapshot,
+	// the first run of a service is always full
+	ReplicationModeIncremental ReplicationMode = "incremental"
+
+	// ReplicationModeFull - send full dataset stream, destination dataset is overwritten
+	ReplicationModeFull ReplicationMode = "full"
+)
+
+// ReplicationState - replication service state
+type ReplicationState string
+
+const (
+	// ReplicationStateDisabled - service is stopped, scheduled runs are not performed
+	ReplicationStateDisabled ReplicationState = "disabled"
+
+	// ReplicationStateIdle - service is started and waits for the next run
+	ReplicationStateIdle ReplicationState = "idle"
+
+	// ReplicationStateRunning - data is being sent to the remote appliance
+	ReplicationStateRunning ReplicationState = "running"
+
+	// ReplicationStateFaulted - the last run failed, see ReplicationStatus.LastError
+	ReplicationStateFaulted ReplicationState = "faulted"
+)
+
+// ReplicationService - NexentaStor HPR service replicating a filesystem or volume to a remote appliance
+type ReplicationService struct {
+	Name string
+
+	// SourceDataset - local filesystem or volume path
+	SourceDataset string
+
+	// DestinationDataset - dataset path on the remote appliance
+	DestinationDataset string
+
+	// RemoteHost and RemotePort of the remote appliance
+	RemoteHost string
+	RemotePort int
+
+	Mode      ReplicationMode
+	Recursive bool
+
+	// Interval between scheduled runs, zero if the service is run only by SendReplicationNow()
+	Interval time.Duration
+
+	// Enabled - service is started
+	Enabled bool
+}
+
+func (service *ReplicationService) String() string {
+	return service.Name
+}
+
+// ReplicationStatus - replication service runtime status
+type ReplicationStatus struct {
+	State ReplicationState
+
+	// Progress of the running transfer in percents
+	Progress int
+
+	// BytesSent by the last (or running) transfer
+	BytesSent int64
+
+	// LastMode - mode of the last transfer, may be full for incremental services on the first run
+	LastMode ReplicationMode
+
+	// LastSnapshot - path of the last snapshot replicated to the remote appliance
+	LastSnapshot string
+
+	// LastSyncTime - completion time of the last successful transfer, zero if there were none
+	LastSyncTime time.Time
+
+	// LastError - error of the last transfer if service is faulted
+	LastError string
+}
+
+// CreateReplicationServiceParams - params to create replication service
+type CreateReplicationServiceParams struct {
+	// Name - unique service name (required)
+	Name string
+
+	// SourceDataset - local filesystem or volume path (required)
+	SourceDataset string
+
+	// DestinationDataset - dataset path on the remote appliance (required)
+	DestinationDataset string
+
+	// RemoteHost - address of the remote appliance (required)
+	RemoteHost string
+
+	// RemotePort - HPR port of the remote appliance, server default if not set
+	RemotePort int
+
+	// Mode of scheduled runs, default: incremental
+	Mode ReplicationMode
+
+	// Recursive - replicate child datasets as well
+	Recursive bool
+
+	// Interval between scheduled runs, at least one minute, fractions of a second are dropped,
+	// the service is run only by SendReplicationNow() if not set
+	Interval time.Duration
+
+	// Start - start the service after creation, services are created stopped by default
+	Start bool
+}
+
+// CreateReplicationService creates replication service for a local filesystem or volume
+func (p *Provider) CreateReplicationService(ctx context.Context, params CreateReplicationServiceParams) error {
+	if params.Name == "" {
+		return fmt.Errorf("Parameter 'CreateReplicationServiceParams.Name' is required")
+	} else if params.SourceDataset == "" {
+		return fmt.Errorf("Parameter 'CreateReplicationServiceParams.SourceDataset' is required")
+	} else if params.DestinationDataset == "" {
+		return fmt.Errorf("Parameter 'CreateReplicationServiceParams.DestinationDataset' is required")
+	} else if params.RemoteHost == "" {
+		return fmt.Errorf("Parameter 'CreateReplicationServiceParams.RemoteHost' is required")
+	} else if params.RemotePort < 0 || params.RemotePort > 65535 {
+		return newBadArgError(
+			"CreateReplicationServiceParams.RemotePort must be in 1-65535 range or 0 for server default, got: %d",
+			params.RemotePort,
+		)
+	} else if params.Interval != 0 && params.Interval < minSnapshotScheduleInterval {
+		return newBadArgError(
+			"CreateReplicationServiceParams.Interval must be at least %s, got: %s",
+			minSnapshotScheduleInterval,
+			params.Interval,
+		)
+	}
+
+	mode := params.Mode
+	if mode == "" {
+		mode = ReplicationModeIncremental
+	} else if err := mode.validate("CreateReplicationServiceParams"); err != nil {
+		return err
+	}
+
+	data := &nefHprService{
+		Name:               params.Name,
+		SourceDataset:      params.SourceDataset,
+		DestinationDataset: params.DestinationDataset,
+		RemoteNode:         nefHprRemoteNode{Host: params.RemoteHost, Port: params.RemotePort},
+		Mode:               string(mode),
+		Recursive:          params.Recursive,
+		Interval:           int64(params.Interval / time.Second),
+		Enabled:            params.Start,
+	}
+
+	return p.sendRequest(ctx, http.MethodPost, "hpr/services", data)
+}
+
+// GetReplicationService returns replication service by its name
+func (p *Provider) GetReplicationService(ctx context.Context, name string) (ReplicationService, error) {
+	if name == "" {
+		return ReplicationService{}, fmt.Errorf("Replication service name is required")
+	}
+
+	uri := fmt.Sprintf("hpr/services/%s", url.PathEscape(name))
+
+	response := nefHprService{}
+	if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+		return ReplicationService{}, err
+	}
+
+	return response.toReplicationService(), nil
+}
+
+// GetReplicationServices returns replication services of the source dataset, all services if dataset is empty
+func (p *Provider) GetReplicationServices(ctx context.Context, sourceDataset string) ([]ReplicationService, error) {
+	query := map[string]string{}
+	if sourceDataset != "" {
+		query["sourceDataset"] = sourceDataset
+	}
+	uri := p.RestClient.BuildURI("hpr/services", query)
+
+	response := nefHprServicesResponse{}
+	if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+		return nil, err
+	}
+
+	services := make([]ReplicationService, 0, len(response.Data))
+	for _, service := range response.Data {
+		services = append(services, service.toReplicationService())
+	}
+
+	return services, nil
+}
+
+// GetReplicationServiceStatus returns runtime status of the replication service
+func (p *Provider) GetReplicationServiceStatus(ctx context.Context, name string) (ReplicationStatus, error) {
+	if name == "" {
+		return ReplicationStatus{}, fmt.Errorf("Replication service name is required")
+	}
+
+	uri := fmt.Sprintf("hpr/services/%s/status", url.PathEscape(name))
+
+	response := nefHprServiceStatus{}
+	if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+		return ReplicationStatus{}, err
+	}
+
+	return ReplicationStatus{
+		State:        ReplicationState(response.State),
+		Progress:     response.Progress,
+		BytesSent:    response.BytesSent,
+		LastMode:     ReplicationMode(response.LastMode),
+		LastSnapshot: response.LastSnapshot,
+		LastSyncTime: response.LastSyncTime,
+		LastError:    response.LastError,
+	}, nil
+}
+
+// StartReplicationService starts scheduled runs of the replication service, faulted service is reset to idle
+func (p *Provider) StartReplicationService(ctx context.Context, name string) error {
+	return p.replicationServiceAction(ctx, name, "start", nil)
+}
+
+// StopReplicationService stops the replication service, running transfer is aborted
+func (p *Provider) StopReplicationService(ctx context.Context, name string) error {
+	return p.replicationServiceAction(ctx, name, "stop", nil)
+}
+
+// SendReplicationParams - params of one-shot replication run
+type SendReplicationParams struct {
+	// Mode of the run, default: mode of the service
+	Mode ReplicationMode
+}
+
+// SendReplicationNow runs one-shot transfer of the replication service, stopped services can be run as well.
+// Incremental run fails with ENOENT if the last replicated snapshot is not found, a full run is required then.
+func (p *Provider) SendReplicationNow(ctx context.Context, name string, params SendReplicationParams) error {
+	if params.Mode != "" {
+		if err := params.Mode.validate("SendReplicationParams"); err != nil {
+			return err
+		}
+	}
+
+	return p.replicationServiceAction(ctx, name, "send", &nefHprSendRequest{Mode: string(params.Mode)})
+}
+
+// DestroyReplicationServiceParams - params to destroy replication service
+type DestroyReplicationServiceParams struct {
+	// DestroySnapshots - destroy local snapshots created by the service
+	DestroySnapshots bool
+}
+
+// DestroyReplicationService destroys replication service, replicated data on the remote appliance is kept.
+// Running service must be stopped first, EBUSY is returned otherwise.
+func (p *Provider) DestroyReplicationService(
+	ctx context.Context,
+	name string,
+	params DestroyReplicationServiceParams,
+) error {
+	if name == "" {
+		return fmt.Errorf("Replication service name is required")
+	}
+
+	uri := p.RestClient.BuildURI(
+		fmt.Sprintf("hpr/services/%s", url.PathEscape(name)),
+		map[string]string{
+			"snapshots": strconv.FormatBool(params.DestroySnapshots),
+		},
+	)
+
+	return p.sendRequest(ctx, http.MethodDelete, uri, nil)
+}
+
+func (p *Provider) replicationServiceAction(ctx context.Context, name, action string, data interface{}) error {
+	if name == "" {
+		return fmt.Errorf("Replication service name is required")
+	}
+
+	uri := fmt.Sprintf("hpr/services/%s/%s", url.PathEscape(name), action)
+
+	return p.sendRequest(ctx, http.MethodPost, uri, data)
+}
+
+func (mode ReplicationMode) validate(prefix string) error {
+	switch mode {
+	case ReplicationModeIncremental, ReplicationModeFull:
+		return nil
+	}
+	return newBadArgError(
+		"%s.Mode must be one of '%s', '%s', got: '%s'",
+		prefix,
+		ReplicationModeIncremental,
+		ReplicationModeFull,
+		mode,
+	)
+}
+
+func (service nefHprService) toReplicationService() ReplicationService {
+	return ReplicationService{
+		Name:               service.Name,
+		SourceDataset:      service.SourceDataset,
+		DestinationDataset: service.DestinationDataset,
+		RemoteHost:         service.RemoteNode.Host,
+		RemotePort:         service.RemoteNode.Port,
+		Mode:               ReplicationMode(service.Mode),
+		Recursive:          service.Recursive,
+		Interval:           time.Duration(service.Interval) * time.Second,
+		Enabled:            service.Enabled,
+	}
+}
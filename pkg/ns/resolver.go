@@ -1,90 +1,260 @@
 package ns
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
 )
 
 // Resolver - NexentaStor cluster API provider
 type Resolver struct {
 	Nodes []ProviderInterface
-	Log   *logrus.Entry
+	Log   logger.Logger
+
+	// Timeout - maximum time to resolve a path, no limit if 0
+	Timeout time.Duration
+
+	// CacheTTL - time to keep resolved nodes in the cache, cache is disabled if 0.
+	// A cached path is invalidated if its node reports that the path doesn't exist,
+	// all cached entries of a node are invalidated if a request to it fails with a transport error.
+	CacheTTL time.Duration
+
+	cacheMux      sync.Mutex
+	cache         map[string]resolverCacheEntry
+	cacheStats    ResolverCacheStats
+	observedNodes map[*Provider]bool
 }
 
-// Resolve returns one NS from the list of NSs by provided pool/dataset/fs path
-func (r *Resolver) Resolve(path string) (ProviderInterface, error) {
-	l := r.Log.WithField("func", "Resolve()")
+// NodeError - an error returned by a node during resolving
+type NodeError struct {
+	Node string
+	Err  error
+}
 
-	if path == "" {
-		return nil, fmt.Errorf("Resolved was called with empty pool/dataset path")
+// ResolveError - an error returned if none of the nodes has the path, contains errors of all nodes
+type ResolveError struct {
+	Path       string
+	NodeErrors []NodeError
+}
+
+func (e *ResolveError) Error() string {
+	nodeErrors := make([]string, 0, len(e.NodeErrors))
+	for _, nodeError := range e.NodeErrors {
+		nodeErrors = append(nodeErrors, fmt.Sprintf("%s: %s", nodeError.Node, nodeError.Err))
 	}
+	return fmt.Sprintf("Cannot resolve '%s' on any NexentaStor: [%s]", e.Path, strings.Join(nodeErrors, "; "))
+}
 
-	//TODO do non-block requests to all NSs in the list, select first one responded
-	var nefError error
-	var resolvedNS ProviderInterface
-	for _, ns := range r.Nodes {
-		_, err := ns.GetFilesystem(path)
-		if err != nil {
-			nefError = err
-		} else {
-			resolvedNS = ns
-			break
+// Is reports whether errors of all nodes match target,
+// e.g. errors.Is(err, ns.ErrNotExist) is true if none of the nodes has the path
+func (e *ResolveError) Is(target error) bool {
+	if len(e.NodeErrors) == 0 {
+		return false
+	}
+	for _, nodeError := range e.NodeErrors {
+		if !errors.Is(nodeError.Err, target) {
+			return false
 		}
 	}
+	return true
+}
 
-	if resolvedNS != nil {
-		l.Debugf("resolve '%s' to '%s'", path, resolvedNS)
-		return resolvedNS, nil
+// Unwrap returns the error of the first node if all nodes returned NefError with the same code,
+// so GetNefErrorCode(err) and errors.As(err, &nefErr) work as for a single node error
+func (e *ResolveError) Unwrap() error {
+	if len(e.NodeErrors) == 0 {
+		return nil
 	}
-
-	if nefError != nil {
-		l.Debugf("error while resolving '%s' to '%s': %s", path, resolvedNS, nefError)
-		return nil, nefError
+	code := GetNefErrorCode(e.NodeErrors[0].Err)
+	if code == "" {
+		return nil
+	}
+	for _, nodeError := range e.NodeErrors[1:] {
+		if GetNefErrorCode(nodeError.Err) != code {
+			return nil
+		}
 	}
+	return e.NodeErrors[0].Err
+}
 
-	l.Debugf("no NexentaStor(s) found with pool/dataset: '%s'", path)
-	return nil, nil
+// ResourceKind - kind of NexentaStor resource to resolve
+type ResourceKind string
+
+// resource kinds supported by resolver
+const (
+	ResourceKindPool        ResourceKind = "pool"
+	ResourceKindFilesystem  ResourceKind = "filesystem"
+	ResourceKindVolumeGroup ResourceKind = "volumeGroup"
+	ResourceKindVolume      ResourceKind = "volume"
+	ResourceKindSnapshot    ResourceKind = "snapshot"
+	ResourceKindTarget      ResourceKind = "iscsiTarget"
+)
+
+// Resolve returns one NS from the list of NSs by provided pool/dataset/fs path
+func (r *Resolver) Resolve(ctx context.Context, path string) (ProviderInterface, error) {
+	return r.ResolveResource(ctx, ResourceKindFilesystem, path)
+}
+
+// ResolveFromVg returns one NS from the list of NSs by provided pool/volumeGroup path
+func (r *Resolver) ResolveFromVg(ctx context.Context, path string) (ProviderInterface, error) {
+	return r.ResolveResource(ctx, ResourceKindVolumeGroup, path)
+}
+
+// ResolvePool returns one NS from the list of NSs that has the pool imported
+func (r *Resolver) ResolvePool(ctx context.Context, name string) (ProviderInterface, error) {
+	return r.ResolveResource(ctx, ResourceKindPool, name)
 }
 
-// Resolve returns one NS from the list of NSs by provided pool/volumeGroup path
-func (r *Resolver) ResolveFromVg(path string) (ProviderInterface, error) {
-	l := r.Log.WithField("func", "Resolve()")
+// ResolveVolume returns one NS from the list of NSs by provided pool/volumeGroup/volume path
+func (r *Resolver) ResolveVolume(ctx context.Context, path string) (ProviderInterface, error) {
+	return r.ResolveResource(ctx, ResourceKindVolume, path)
+}
+
+// ResolveSnapshot returns one NS from the list of NSs by provided snapshot path (e.g. "p/d/fs@s")
+func (r *Resolver) ResolveSnapshot(ctx context.Context, path string) (ProviderInterface, error) {
+	return r.ResolveResource(ctx, ResourceKindSnapshot, path)
+}
+
+// ResolveTarget returns one NS from the list of NSs by provided iSCSI target name
+func (r *Resolver) ResolveTarget(ctx context.Context, name string) (ProviderInterface, error) {
+	return r.ResolveResource(ctx, ResourceKindTarget, name)
+}
+
+// ResolveResource returns one NS from the list of NSs that has the resource of the kind
+// by provided path (or name for pools and iSCSI targets)
+func (r *Resolver) ResolveResource(ctx context.Context, kind ResourceKind, path string) (ProviderInterface, error) {
+	l := r.Log.WithFields(logger.Fields{
+		"func": "ResolveResource()",
+		"kind": kind,
+	})
 
 	if path == "" {
-		return nil, fmt.Errorf("Resolved was called with empty pool/volumeGroup path")
+		return nil, fmt.Errorf("Resolver was called with empty %s path", kind)
 	}
 
-	var nefError error
-	var resolvedNS ProviderInterface
-	for _, ns := range r.Nodes {
-		_, err := ns.GetVolumeGroup(path)
-		if err != nil {
-			nefError = err
-		} else {
-			resolvedNS = ns
-			break
+	var check func(ctx context.Context, node ProviderInterface) error
+	switch kind {
+	case ResourceKindPool:
+		check = func(ctx context.Context, node ProviderInterface) error {
+			pools, err := node.GetPools(ctx)
+			if err != nil {
+				return err
+			}
+			for _, pool := range pools {
+				if pool.Name == path {
+					return nil
+				}
+			}
+			return &NefError{Code: NefCodeNotExist, Err: fmt.Errorf("Pool '%s' not found", path)}
+		}
+	case ResourceKindFilesystem:
+		check = func(ctx context.Context, node ProviderInterface) error {
+			_, err := node.GetFilesystem(ctx, path)
+			return err
+		}
+	case ResourceKindVolumeGroup:
+		check = func(ctx context.Context, node ProviderInterface) error {
+			_, err := node.GetVolumeGroup(ctx, path)
+			return err
+		}
+	case ResourceKindVolume:
+		check = func(ctx context.Context, node ProviderInterface) error {
+			_, err := node.GetVolume(ctx, path)
+			return err
+		}
+	case ResourceKindSnapshot:
+		check = func(ctx context.Context, node ProviderInterface) error {
+			_, err := node.GetSnapshot(ctx, path)
+			return err
+		}
+	case ResourceKindTarget:
+		check = func(ctx context.Context, node ProviderInterface) error {
+			_, err := node.GetISCSITarget(ctx, path)
+			return err
 		}
+	default:
+		return nil, fmt.Errorf("Resolver doesn't support '%s' resource kind", kind)
+	}
+
+	return r.resolve(ctx, l, kind, path, check)
+}
+
+// resolve runs check on all nodes concurrently and returns the first node succeeded,
+// requests to other nodes are cancelled. ResolveError is returned if check fails on all nodes.
+// Resolved nodes are cached by resource kind and path if CacheTTL is set.
+func (r *Resolver) resolve(
+	ctx context.Context,
+	l logger.Logger,
+	kind ResourceKind,
+	path string,
+	check func(ctx context.Context, node ProviderInterface) error,
+) (ProviderInterface, error) {
+	if node := r.cacheGet(kind, path); node != nil {
+		l.Debugf("resolve '%s' to '%s' (cached)", path, node)
+		return node, nil
+	}
+
+	if len(r.Nodes) == 0 {
+		l.Debugf("no NexentaStor(s) found with path: '%s'", path)
+		return nil, nil
+	}
+
+	if r.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, r.Timeout)
+		defer cancelTimeout()
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, resolveKey{}, true))
+	defer cancel()
+
+	type nodeResult struct {
+		index int
+		node  ProviderInterface
+		err   error
+	}
+
+	// buffered, so goroutines of nodes responded after the first success are not blocked
+	results := make(chan nodeResult, len(r.Nodes))
+	for i, node := range r.Nodes {
+		go func(i int, node ProviderInterface) {
+			results <- nodeResult{i, node, check(ctx, node)}
+		}(i, node)
 	}
 
-	if resolvedNS != nil {
-		l.Debugf("resolve '%s' to '%s'", path, resolvedNS)
-		return resolvedNS, nil
+	nodeErrors := make([]NodeError, len(r.Nodes))
+	for range r.Nodes {
+		result := <-results
+		if result.err == nil {
+			l.Debugf("resolve '%s' to '%s'", path, result.node)
+			r.cachePut(kind, path, result.node)
+			return result.node, nil
+		}
+
+		l.Debugf("node '%s' cannot resolve '%s': %s", result.node, path, result.err)
+		nodeErrors[result.index] = NodeError{
+			Node: fmt.Sprint(result.node),
+			Err:  result.err,
+		}
 	}
 
-	if nefError != nil {
-		l.Debugf("error while resolving '%s' to '%s': %s", path, resolvedNS, nefError)
-		return nil, nefError
+	err := &ResolveError{
+		Path:       path,
+		NodeErrors: nodeErrors,
 	}
+	l.Debugf("error while resolving '%s': %s", path, err)
 
-	l.Debugf("no NexentaStor(s) found with pool/volumeGroup: '%s'", path)
-	return nil, nil
+	return nil, err
 }
 
 // IsCluster checks if nodes is a NS cluster
 // For now it simple checks if all nodes return at least one similar cluster name
-func (r *Resolver) IsCluster() (bool, error) {
+func (r *Resolver) IsCluster(ctx context.Context) (bool, error) {
 	l := r.Log.WithField("func", "IsCluster()")
 
 	if len(r.Nodes) < 2 {
@@ -97,7 +267,7 @@ func (r *Resolver) IsCluster() (bool, error) {
 
 	for _, node := range r.Nodes {
 		// get RSF cluster from each node
-		clusters, err := node.GetRSFClusters()
+		clusters, err := node.GetRSFClusters(ctx)
 		if err != nil {
 			return false, err
 		}
@@ -125,15 +295,38 @@ type ResolverArgs struct {
 	Address  string
 	Username string
 	Password string
-	Log      *logrus.Entry
+
+	// Log - logger to use, messages are discarded if not set (see logger package for adapters)
+	Log logger.Logger
 
 	// InsecureSkipVerify controls whether a client verifies the server's certificate chain and host name.
 	InsecureSkipVerify bool
+
+	// RetryPolicy to repeat failed requests (see rest.DefaultRetryPolicy()), no retries if not set
+	RetryPolicy *rest.RetryPolicy
+
+	// JobPollInterval, JobTimeout and TokenRefreshWindow, see ProviderArgs
+	JobPollInterval    time.Duration
+	JobTimeout         time.Duration
+	TokenRefreshWindow time.Duration
+
+	// ResolveTimeout - maximum time to resolve a path on all nodes, no limit if 0
+	ResolveTimeout time.Duration
+
+	// CacheTTL - time to keep resolved nodes in the cache, cache is disabled if 0
+	CacheTTL time.Duration
+
+	// SensitiveFields - extra JSON fields to hide in logs, see rest.DefaultSensitiveFields()
+	SensitiveFields []string
 }
 
 // NewResolver creates NexentaStor resolver instance based on configuration
 func NewResolver(args ResolverArgs) (*Resolver, error) {
-	l := args.Log.WithFields(logrus.Fields{
+	log := args.Log
+	if log == nil {
+		log = logger.Noop()
+	}
+	l := log.WithFields(logger.Fields{
 		"cmp": "NSResolver",
 		"ns":  args.Address,
 	})
@@ -151,6 +344,11 @@ func NewResolver(args ResolverArgs) (*Resolver, error) {
 			Password:           args.Password,
 			Log:                l,
 			InsecureSkipVerify: args.InsecureSkipVerify,
+			RetryPolicy:        args.RetryPolicy,
+			JobPollInterval:    args.JobPollInterval,
+			JobTimeout:         args.JobTimeout,
+			TokenRefreshWindow: args.TokenRefreshWindow,
+			SensitiveFields:    args.SensitiveFields,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("Cannot create provider for %s NexentaStor: %s", address, err)
@@ -160,7 +358,9 @@ func NewResolver(args ResolverArgs) (*Resolver, error) {
 
 	l.Debugf("created for '%s'", args.Address)
 	return &Resolver{
-		Nodes: nodes,
-		Log:   l,
+		Nodes:    nodes,
+		Log:      l,
+		Timeout:  args.ResolveTimeout,
+		CacheTTL: args.CacheTTL,
 	}, nil
 }
@@ -0,0 +1,173 @@
+package ns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ResolverCacheStats - resolver cache metrics
+type ResolverCacheStats struct {
+	// Hits - count of resolves returned from the cache
+	Hits uint64
+
+	// Misses - count of resolves that requested nodes
+	Misses uint64
+
+	// Invalidations - count of entries removed before TTL expiration
+	Invalidations uint64
+
+	// Entries - current count of cached paths
+	Entries int
+}
+
+// resolveKey - context key to mark requests made by resolver
+type resolveKey struct{}
+
+type resolverCacheEntry struct {
+	path    string
+	node    ProviderInterface
+	expires time.Time
+}
+
+func resolverCacheKey(kind ResourceKind, path string) string {
+	return fmt.Sprintf("%s:%s", kind, path)
+}
+
+// cacheGet returns cached node for the path, hit/miss metrics are updated
+func (r *Resolver) cacheGet(kind ResourceKind, path string) ProviderInterface {
+	if r.CacheTTL <= 0 {
+		return nil
+	}
+
+	r.cacheMux.Lock()
+	defer r.cacheMux.Unlock()
+
+	key := resolverCacheKey(kind, path)
+	if entry, ok := r.cache[key]; ok {
+		if time.Now().Before(entry.expires) {
+			r.cacheStats.Hits++
+			return entry.node
+		}
+		delete(r.cache, key)
+	}
+
+	r.cacheStats.Misses++
+	return nil
+}
+
+// cachePut stores resolved node, starts watching node errors to invalidate its entries
+func (r *Resolver) cachePut(kind ResourceKind, path string, node ProviderInterface) {
+	if r.CacheTTL <= 0 {
+		return
+	}
+
+	r.cacheMux.Lock()
+	defer r.cacheMux.Unlock()
+
+	if r.cache == nil {
+		r.cache = map[string]resolverCacheEntry{}
+	}
+	if r.observedNodes == nil {
+		r.observedNodes = map[*Provider]bool{}
+	}
+
+	r.cache[resolverCacheKey(kind, path)] = resolverCacheEntry{
+		path:    path,
+		node:    node,
+		expires: time.Now().Add(r.CacheTTL),
+	}
+
+	if provider, ok := node.(*Provider); ok && !r.observedNodes[provider] {
+		r.observedNodes[provider] = true
+		provider.addErrorObserver(func(ctx context.Context, path string, err error) {
+			// nodes that don't have the path respond with ENOENT during resolving, it's not a failover
+			if ctx.Value(resolveKey{}) != nil {
+				return
+			}
+			if path != "" && IsNotExistNefError(err) {
+				r.invalidatePath(node, path, err)
+			} else if isTransportError(err) {
+				r.invalidateNode(node, err)
+			}
+		})
+	}
+}
+
+// invalidateNode removes all cached entries of the node, a pool may be moved to another node
+// by RSF service failover, so any path on this node can be resolved to another node now
+func (r *Resolver) invalidateNode(node ProviderInterface, reason error) {
+	r.cacheMux.Lock()
+	defer r.cacheMux.Unlock()
+
+	count := 0
+	for key, entry := range r.cache {
+		if entry.node == node {
+			delete(r.cache, key)
+			count++
+		}
+	}
+
+	if count > 0 {
+		r.cacheStats.Invalidations += uint64(count)
+		r.Log.Debugf("%d cache entries of '%s' are invalidated: %s", count, node, reason)
+	}
+}
+
+// invalidatePath removes cached entries of the node for the path and its descendants (children
+// and snapshots), the path may be moved to another node, other entries of the node are kept
+func (r *Resolver) invalidatePath(node ProviderInterface, path string, reason error) {
+	r.cacheMux.Lock()
+	defer r.cacheMux.Unlock()
+
+	count := 0
+	for key, entry := range r.cache {
+		if entry.node == node && isSameOrDescendantPath(entry.path, path) {
+			delete(r.cache, key)
+			count++
+		}
+	}
+
+	if count > 0 {
+		r.cacheStats.Invalidations += uint64(count)
+		r.Log.Debugf("%d cache entries of '%s' for '%s' are invalidated: %s", count, node, path, reason)
+	}
+}
+
+// isSameOrDescendantPath checks if the path is the parent path itself, its child or snapshot
+func isSameOrDescendantPath(path, parent string) bool {
+	return path == parent || strings.HasPrefix(path, parent+"/") || strings.HasPrefix(path, parent+"@")
+}
+
+// InvalidateCache removes cached nodes for the path (all resource kinds)
+func (r *Resolver) InvalidateCache(path string) {
+	r.cacheMux.Lock()
+	defer r.cacheMux.Unlock()
+
+	for key := range r.cache {
+		if strings.HasSuffix(key, ":"+path) {
+			delete(r.cache, key)
+			r.cacheStats.Invalidations++
+		}
+	}
+}
+
+// CacheStats returns resolver cache metrics
+func (r *Resolver) CacheStats() ResolverCacheStats {
+	r.cacheMux.Lock()
+	defer r.cacheMux.Unlock()
+
+	stats := r.cacheStats
+	stats.Entries = len(r.cache)
+
+	return stats
+}
+
+// isTransportError checks if the request failed before NexentaStor responded (connection errors, timeouts)
+func isTransportError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,152 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy - client-side snapshot retention policy, a snapshot is kept if any rule keeps it.
+// Periodic rules keep the most recent snapshot of each of the last N hours/days/weeks that have snapshots.
+type RetentionPolicy struct {
+	// KeepLast - count of the most recent snapshots to keep
+	KeepLast int
+
+	// KeepHourly, KeepDaily and KeepWeekly - count of hours, days and (ISO) weeks to keep a snapshot for
+	KeepHourly int
+	KeepDaily  int
+	KeepWeekly int
+
+	// Prefix - only snapshots with the name prefix are managed by the policy, all if empty
+	Prefix string
+
+	// Location - time zone to split snapshots by days and weeks, default: UTC
+	Location *time.Location
+}
+
+func (policy RetentionPolicy) validate() error {
+	if policy.KeepLast < 0 || policy.KeepHourly < 0 || policy.KeepDaily < 0 || policy.KeepWeekly < 0 {
+		return newBadArgError("RetentionPolicy: counts must not be negative, got: %+v", policy)
+	} else if policy.KeepLast+policy.KeepHourly+policy.KeepDaily+policy.KeepWeekly == 0 {
+		return newBadArgError("RetentionPolicy must keep at least one snapshot, got: %+v", policy)
+	}
+	return nil
+}
+
+// ExpiredSnapshots returns snapshots that are not kept by the policy, the oldest go first.
+// Snapshots with clones and snapshots w/o the policy prefix are never returned.
+// Nothing is returned if the policy doesn't keep any snapshot.
+func (policy RetentionPolicy) ExpiredSnapshots(snapshots []Snapshot) []Snapshot {
+	if policy.validate() != nil {
+		return []Snapshot{}
+	}
+
+	location := policy.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	managed := []Snapshot{}
+	for _, snapshot := range snapshots {
+		if strings.HasPrefix(snapshot.Name, policy.Prefix) {
+			managed = append(managed, snapshot)
+		}
+	}
+	sort.SliceStable(managed, func(i, j int) bool {
+		return managed[i].CreationTime.After(managed[j].CreationTime)
+	})
+
+	kept := make([]bool, len(managed))
+	for i := 0; i < policy.KeepLast && i < len(managed); i++ {
+		kept[i] = true
+	}
+
+	periods := []struct {
+		count  int
+		period func(t time.Time) string
+	}{
+		{policy.KeepHourly, func(t time.Time) string { return t.Format("2006-01-02T15") }},
+		{policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+	}
+	for _, rule := range periods {
+		lastPeriod := ""
+		count := 0
+		for i, snapshot := range managed {
+			if count >= rule.count {
+				break
+			}
+			// snapshots are sorted from newest, so the first one of a period is the most recent in it
+			if period := rule.period(snapshot.CreationTime.In(location)); period != lastPeriod {
+				lastPeriod = period
+				kept[i] = true
+				count++
+			}
+		}
+	}
+
+	expired := []Snapshot{}
+	for i := len(managed) - 1; i >= 0; i-- {
+		if !kept[i] && len(managed[i].Clones) == 0 {
+			expired = append(expired, managed[i])
+		}
+	}
+
+	return expired
+}
+
+// ApplyRetentionPolicy destroys dataset snapshots that are not kept by the policy, returns destroyed snapshots.
+// Snapshots with clones or user holds are skipped.
+func (p *Provider) ApplyRetentionPolicy(ctx context.Context, dataset string, policy RetentionPolicy) (
+	[]Snapshot,
+	error,
+) {
+	if dataset == "" {
+		return nil, fmt.Errorf("Dataset path is required")
+	} else if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	l := p.Log.WithField("func", "ApplyRetentionPolicy()")
+
+	snapshots, err := p.GetSnapshots(ctx, dataset, false)
+	if err != nil {
+		return nil, err
+	}
+
+	destroyed := []Snapshot{}
+	for _, snapshot := range policy.ExpiredSnapshots(snapshots) {
+		// list response doesn't contain clones, so snapshot is requested to check them
+		snapshot, err := p.GetSnapshot(ctx, snapshot.Path)
+		if IsNotExistNefError(err) {
+			continue
+		} else if err != nil {
+			return destroyed, err
+		} else if len(snapshot.Clones) > 0 {
+			l.Debugf("skip expired snapshot '%s', it has clones: %v", snapshot.Path, snapshot.Clones)
+			continue
+		}
+
+		// destroyed snapshots are returned, so destroy jobs are waited in non-blocking mode (see StartJob)
+		err = p.DestroySnapshot(waitJobs(ctx), snapshot.Path)
+		if IsBusyNefError(err) || IsAlreadyExistNefError(err) {
+			// snapshot is held or got a clone
+			l.Debugf("skip expired snapshot '%s': %s", snapshot.Path, err)
+			continue
+		} else if IsNotExistNefError(err) {
+			// snapshot is already destroyed by someone else
+			continue
+		} else if err != nil {
+			return destroyed, err
+		}
+
+		destroyed = append(destroyed, snapshot)
+	}
+
+	return destroyed, nil
+}
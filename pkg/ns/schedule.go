@@ -0,0 +1,203 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// minSnapshotScheduleInterval - NexentaStor doesn't run snapshot schedules more often than once a minute
+const minSnapshotScheduleInterval = time.Minute
+
+// SnapshotSchedule - NexentaStor snapshot service: snapshots of the dataset are created periodically,
+// the oldest ones are destroyed when there are more than KeepCount of them
+type SnapshotSchedule struct {
+	Name    string
+	Dataset string
+
+	// Interval between snapshots, NexentaStor stores it in whole seconds
+	Interval time.Duration
+
+	// KeepCount - count of the most recent scheduled snapshots to keep
+	KeepCount int
+
+	// Recursive - create snapshots of child datasets as well
+	Recursive bool
+
+	// Prefix of snapshot names, the rest of a name is a creation timestamp
+	Prefix string
+
+	// Enabled - snapshots are created only by enabled schedules
+	Enabled bool
+}
+
+func (schedule *SnapshotSchedule) String() string {
+	return schedule.Name
+}
+
+// CreateSnapshotScheduleParams - params to create snapshot schedule
+type CreateSnapshotScheduleParams struct {
+	// Name - unique schedule name (required)
+	Name string
+
+	// Dataset - filesystem or volume path (required)
+	Dataset string
+
+	// Interval between snapshots, at least one minute (required), fractions of a second are dropped
+	Interval time.Duration
+
+	// KeepCount - count of the most recent scheduled snapshots to keep (required)
+	KeepCount int
+
+	// Recursive - create snapshots of child datasets as well
+	Recursive bool
+
+	// Prefix of snapshot names, default: schedule name
+	Prefix string
+
+	// Disabled - create the schedule in disabled state
+	Disabled bool
+}
+
+// CreateSnapshotSchedule creates snapshot schedule for a dataset
+func (p *Provider) CreateSnapshotSchedule(ctx context.Context, params CreateSnapshotScheduleParams) error {
+	if params.Name == "" {
+		return fmt.Errorf("Parameter 'CreateSnapshotScheduleParams.Name' is required")
+	} else if params.Dataset == "" {
+		return fmt.Errorf("Parameter 'CreateSnapshotScheduleParams.Dataset' is required")
+	} else if err := validateSnapshotSchedule("CreateSnapshotScheduleParams", params.Interval, params.KeepCount); err != nil {
+		return err
+	}
+
+	prefix := params.Prefix
+	if prefix == "" {
+		prefix = params.Name
+	}
+	if err := validateSnapshotName(prefix); err != nil {
+		return err
+	}
+
+	data := &nefSnapshotSchedule{
+		Name:      params.Name,
+		Dataset:   params.Dataset,
+		Interval:  int64(params.Interval / time.Second),
+		Keep:      params.KeepCount,
+		Recursive: params.Recursive,
+		Prefix:    prefix,
+		Enabled:   !params.Disabled,
+	}
+
+	return p.sendRequest(ctx, http.MethodPost, "storage/snapshotSchedules", data)
+}
+
+// GetSnapshotSchedule returns snapshot schedule by its name
+func (p *Provider) GetSnapshotSchedule(ctx context.Context, name string) (SnapshotSchedule, error) {
+	if name == "" {
+		return SnapshotSchedule{}, fmt.Errorf("Snapshot schedule name is required")
+	}
+
+	uri := fmt.Sprintf("storage/snapshotSchedules/%s", url.PathEscape(name))
+
+	response := nefSnapshotSchedule{}
+	if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+		return SnapshotSchedule{}, err
+	}
+
+	return response.toSnapshotSchedule(), nil
+}
+
+// GetSnapshotSchedules returns snapshot schedules of the dataset, all schedules if dataset is empty
+func (p *Provider) GetSnapshotSchedules(ctx context.Context, dataset string) ([]SnapshotSchedule, error) {
+	query := map[string]string{}
+	if dataset != "" {
+		query["dataset"] = dataset
+	}
+	uri := p.RestClient.BuildURI("storage/snapshotSchedules", query)
+
+	response := nefSnapshotSchedulesResponse{}
+	if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+		return nil, err
+	}
+
+	schedules := make([]SnapshotSchedule, 0, len(response.Data))
+	for _, schedule := range response.Data {
+		schedules = append(schedules, schedule.toSnapshotSchedule())
+	}
+
+	return schedules, nil
+}
+
+// UpdateSnapshotScheduleParams - params to update snapshot schedule, only set fields are changed
+type UpdateSnapshotScheduleParams struct {
+	// Interval between snapshots, fractions of a second are dropped
+	Interval  time.Duration
+	KeepCount int
+	Recursive *bool
+	Prefix    string
+	Enabled   *bool
+}
+
+// UpdateSnapshotSchedule updates snapshot schedule by its name
+func (p *Provider) UpdateSnapshotSchedule(ctx context.Context, name string, params UpdateSnapshotScheduleParams) error {
+	if name == "" {
+		return fmt.Errorf("Snapshot schedule name is required")
+	} else if params.Interval != 0 && params.Interval < minSnapshotScheduleInterval {
+		return newBadArgError(
+			"UpdateSnapshotScheduleParams.Interval must be at least %s, got: %s",
+			minSnapshotScheduleInterval,
+			params.Interval,
+		)
+	} else if params.KeepCount < 0 {
+		return newBadArgError("UpdateSnapshotScheduleParams.KeepCount must not be negative, got: %d", params.KeepCount)
+	} else if params.Prefix != "" {
+		if err := validateSnapshotName(params.Prefix); err != nil {
+			return err
+		}
+	}
+
+	data := &nefSnapshotScheduleUpdateRequest{
+		Interval:  int64(params.Interval / time.Second),
+		Keep:      params.KeepCount,
+		Recursive: params.Recursive,
+		Prefix:    params.Prefix,
+		Enabled:   params.Enabled,
+	}
+
+	uri := fmt.Sprintf("storage/snapshotSchedules/%s", url.PathEscape(name))
+
+	return p.sendRequest(ctx, http.MethodPut, uri, data)
+}
+
+// DestroySnapshotSchedule destroys snapshot schedule by its name, created snapshots are kept
+func (p *Provider) DestroySnapshotSchedule(ctx context.Context, name string) error {
+	if name == "" {
+		return fmt.Errorf("Snapshot schedule name is required")
+	}
+
+	uri := fmt.Sprintf("storage/snapshotSchedules/%s", url.PathEscape(name))
+
+	return p.sendRequest(ctx, http.MethodDelete, uri, nil)
+}
+
+func validateSnapshotSchedule(prefix string, interval time.Duration, keepCount int) error {
+	if interval < minSnapshotScheduleInterval {
+		return newBadArgError("%s.Interval must be at least %s, got: %s", prefix, minSnapshotScheduleInterval, interval)
+	} else if keepCount <= 0 {
+		return newBadArgError("%s.KeepCount must be greater than 0, got: %d", prefix, keepCount)
+	}
+	return nil
+}
+
+func (schedule nefSnapshotSchedule) toSnapshotSchedule() SnapshotSchedule {
+	return SnapshotSchedule{
+		Name:      schedule.Name,
+		Dataset:   schedule.Dataset,
+		Interval:  time.Duration(schedule.Interval) * time.Second,
+		KeepCount: schedule.Keep,
+		Recursive: schedule.Recursive,
+		Prefix:    schedule.Prefix,
+		Enabled:   schedule.Enabled,
+	}
+}
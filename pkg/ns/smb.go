@@ -0,0 +1,201 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SmbShareStateOnline - SMB share state when clients can connect to the share
+const SmbShareStateOnline = "online"
+
+// SmbShareACEType - SMB share ACL entry type
+type SmbShareACEType string
+
+const (
+	// SmbShareACETypeAllow - entry grants permission
+	SmbShareACETypeAllow SmbShareACEType = "allow"
+
+	// SmbShareACETypeDeny - entry denies permission
+	SmbShareACETypeDeny SmbShareACEType = "deny"
+)
+
+// SmbSharePermission - SMB share level permission, filesystem ACL is checked as well
+type SmbSharePermission string
+
+const (
+	// SmbSharePermissionFull - full control, including changing file permissions
+	SmbSharePermissionFull SmbSharePermission = "full"
+
+	// SmbSharePermissionChange - read, write and delete files
+	SmbSharePermissionChange SmbSharePermission = "change"
+
+	// SmbSharePermissionRead - read files only
+	SmbSharePermissionRead SmbSharePermission = "read"
+)
+
+// SmbShareACE - SMB share ACL entry
+type SmbShareACE struct {
+	// Principal - "user:name", "group:name" or "everyone@"
+	Principal   string             `json:"principal"`
+	Type        SmbShareACEType    `json:"type"`
+	Permissions SmbSharePermission `json:"permissions"`
+}
+
+// SmbShare - NexentaStor SMB share of a filesystem
+type SmbShare struct {
+	Filesystem  string `json:"filesystem"`
+	MountPoint  string `json:"mountPoint"`
+	ShareName   string `json:"shareName"`
+	ShareState  string `json:"shareState"`
+	Description string `json:"shareDescription"`
+
+	// GuestOk - clients can connect to the share w/o authentication
+	GuestOk bool `json:"guestOk"`
+
+	// AccessBasedEnumeration - files and folders are listed only if the user has access to them
+	AccessBasedEnumeration bool `json:"accessBasedEnumeration"`
+
+	// EncryptData - SMB3 clients must encrypt data, clients w/o encryption support cannot connect
+	EncryptData bool `json:"encryptData"`
+
+	// ShareQuota - space reported to clients in bytes, 0 if not set
+	ShareQuota int64 `json:"shareQuota"`
+
+	ShareACL []SmbShareACE `json:"shareAcl"`
+}
+
+func (share *SmbShare) String() string {
+	return share.ShareName
+}
+
+// GetSmbShare returns SMB share of the filesystem
+func (p *Provider) GetSmbShare(ctx context.Context, path string) (SmbShare, error) {
+	if path == "" {
+		return SmbShare{}, fmt.Errorf("Filesystem path is required")
+	}
+
+	uri := fmt.Sprintf("nas/smb/%s", url.PathEscape(path))
+
+	share := SmbShare{}
+	if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &share); err != nil {
+		return SmbShare{}, err
+	}
+
+	return share, nil
+}
+
+// ListSmbShares returns SMB shares of the parent filesystem and its descendants, all shares if parent is empty
+func (p *Provider) ListSmbShares(ctx context.Context, parent string) ([]SmbShare, error) {
+	it := newPageIterator(ctx, IteratorParams{}, 0, func(ctx context.Context, limit, offset int) (
+		[]interface{},
+		int,
+		error,
+	) {
+		query := map[string]string{
+			"limit":  fmt.Sprint(limit),
+			"offset": fmt.Sprint(offset),
+		}
+		if parent != "" {
+			query["parent"] = parent
+		}
+		uri := p.RestClient.BuildURI("nas/smb", query)
+
+		response := nefNasSmbListResponse{}
+		if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+			return nil, 0, err
+		}
+
+		items := make([]interface{}, 0, len(response.Data))
+		for _, share := range response.Data {
+			items = append(items, share)
+		}
+
+		return items, len(response.Data), nil
+	}, func(item interface{}) string {
+		return item.(SmbShare).Filesystem
+	})
+
+	shares := []SmbShare{}
+	for it.next() {
+		shares = append(shares, it.item.(SmbShare))
+	}
+	if it.err != nil {
+		return nil, it.err
+	}
+
+	return shares, nil
+}
+
+// UpdateSmbShareParams - params to update SMB share, only set fields are changed
+type UpdateSmbShareParams struct {
+	// ShareName - new share name, clients have to reconnect using it
+	ShareName string `json:"shareName,omitempty"`
+
+	// Description - set to empty string to remove description
+	Description *string `json:"shareDescription,omitempty"`
+
+	GuestOk                *bool `json:"guestOk,omitempty"`
+	AccessBasedEnumeration *bool `json:"accessBasedEnumeration,omitempty"`
+	EncryptData            *bool `json:"encryptData,omitempty"`
+
+	// ShareQuota - space reported to clients in bytes, set to 0 to remove quota
+	ShareQuota *int64 `json:"shareQuota,omitempty"`
+
+	// ShareACL replaces all entries of the share ACL if set
+	ShareACL []SmbShareACE `json:"shareAcl,omitempty"`
+}
+
+// UpdateSmbShare updates SMB share of the filesystem in place
+func (p *Provider) UpdateSmbShare(ctx context.Context, path string, params UpdateSmbShareParams) error {
+	if path == "" {
+		return fmt.Errorf("Filesystem path is required")
+	}
+
+	if params.ShareQuota != nil && *params.ShareQuota < 0 {
+		return newBadArgError("UpdateSmbShareParams.ShareQuota must not be negative, got: %d", *params.ShareQuota)
+	} else if err := validateSmbShareACL("UpdateSmbShareParams.ShareACL", params.ShareACL); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("nas/smb/%s", url.PathEscape(path))
+
+	return p.sendRequest(ctx, http.MethodPut, uri, params)
+}
+
+func validateSmbShareACL(field string, acl []SmbShareACE) error {
+	for i, ace := range acl {
+		if ace.Principal == "" {
+			return newBadArgError("%s[%d].Principal is required", field, i)
+		}
+
+		switch ace.Type {
+		case SmbShareACETypeAllow, SmbShareACETypeDeny:
+		default:
+			return newBadArgError(
+				"%s[%d].Type must be one of '%s', '%s', got: '%s'",
+				field,
+				i,
+				SmbShareACETypeAllow,
+				SmbShareACETypeDeny,
+				ace.Type,
+			)
+		}
+
+		switch ace.Permissions {
+		case SmbSharePermissionFull, SmbSharePermissionChange, SmbSharePermissionRead:
+		default:
+			return newBadArgError(
+				"%s[%d].Permissions must be one of '%s', '%s', '%s', got: '%s'",
+				field,
+				i,
+				SmbSharePermissionFull,
+				SmbSharePermissionChange,
+				SmbSharePermissionRead,
+				ace.Permissions,
+			)
+		}
+	}
+	return nil
+}
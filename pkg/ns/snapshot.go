@@ -0,0 +1,190 @@
+package ns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// nefSnapshotFields - snapshot fields requested from NexentaStor in lists
+const nefSnapshotFields = "path,name,parent,creationTime,bytesReferenced,bytesUsed,userProperties"
+
+// splitSnapshotPath splits "pool/fs@snapshot" path to dataset path and snapshot name
+func splitSnapshotPath(path string) (dataset, name string, err error) {
+	i := strings.LastIndex(path, "@")
+	if i <= 0 || i == len(path)-1 {
+		return "", "", newBadArgError("Snapshot path must be in 'dataset@name' format, got: '%s'", path)
+	}
+	return path[:i], path[i+1:], nil
+}
+
+// RollbackSnapshotParams - params to roll a dataset back to a snapshot
+type RollbackSnapshotParams struct {
+	// DestroyRecentSnapshots destroys snapshots more recent than the one rolled back to ("zfs rollback -r"),
+	// rollback fails with EBUSY if there are more recent snapshots and it's not set
+	DestroyRecentSnapshots bool `json:"destroyRecentSnapshots,omitempty"`
+
+	// DestroyRecentClones destroys more recent snapshots and their clones ("zfs rollback -R"),
+	// rollback fails with EBUSY if more recent snapshots have clones and it's not set
+	DestroyRecentClones bool `json:"destroyRecentClones,omitempty"`
+}
+
+// RollbackFilesystem rolls filesystem back to the snapshot, all data written since the snapshot is lost.
+// snapshotPath - "pool/dataset/fs@snapshot", EBADARG error is returned for volume snapshots
+func (p *Provider) RollbackFilesystem(ctx context.Context, snapshotPath string, params RollbackSnapshotParams) error {
+	return p.rollbackSnapshot(ctx, snapshotPath, params, false)
+}
+
+// RollbackVolume rolls volume back to the snapshot, all data written since the snapshot is lost.
+// The volume should not be in use (e.g. mapped and mounted by initiators) during rollback.
+// snapshotPath - "pool/volumeGroup/volume@snapshot", EBADARG error is returned for filesystem snapshots
+func (p *Provider) RollbackVolume(ctx context.Context, snapshotPath string, params RollbackSnapshotParams) error {
+	return p.rollbackSnapshot(ctx, snapshotPath, params, true)
+}
+
+func (p *Provider) rollbackSnapshot(
+	ctx context.Context,
+	snapshotPath string,
+	params RollbackSnapshotParams,
+	volume bool,
+) error {
+	if snapshotPath == "" {
+		return fmt.Errorf("Snapshot path is required")
+	}
+
+	dataset, _, err := splitSnapshotPath(snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	if err := p.checkSnapshotParent(ctx, dataset, volume); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("storage/snapshots/%s/rollback", url.PathEscape(snapshotPath))
+
+	return p.sendRequest(ctx, http.MethodPost, uri, params)
+}
+
+// checkSnapshotParent returns EBADARG error if the dataset is not a volume (volume=true) or a filesystem,
+// ENOENT error is returned if the dataset doesn't exist at all
+func (p *Provider) checkSnapshotParent(ctx context.Context, dataset string, volume bool) error {
+	getFilesystem := func() error {
+		_, err := p.GetFilesystem(ctx, dataset)
+		return err
+	}
+	getVolume := func() error {
+		_, err := p.GetVolume(ctx, dataset)
+		return err
+	}
+
+	expected, other, otherType := getFilesystem, getVolume, "volume"
+	if volume {
+		expected, other, otherType = getVolume, getFilesystem, "filesystem"
+	}
+
+	err := expected()
+	if !IsNotExistNefError(err) {
+		return err
+	}
+
+	// the dataset is checked once more only to report a mismatched type instead of ENOENT
+	if otherErr := other(); otherErr == nil {
+		return newBadArgError("Dataset '%s' is a %s, snapshot parent type doesn't match", dataset, otherType)
+	}
+
+	return err
+}
+
+// validateSnapshotName checks snapshot name w/o dataset path
+func validateSnapshotName(name string) error {
+	if name == "" || strings.ContainsAny(name, "@/") {
+		return newBadArgError("Snapshot name must be non-empty and must not contain '@' or '/', got: '%s'", name)
+	}
+	return nil
+}
+
+// UpdateSnapshotParams - params to update snapshot
+type UpdateSnapshotParams struct {
+	// UserProperties - ZFS user properties to set, other user properties are kept
+	UserProperties map[string]string `json:"userProperties,omitempty"`
+}
+
+// UpdateSnapshot updates snapshot properties
+func (p *Provider) UpdateSnapshot(ctx context.Context, path string, params UpdateSnapshotParams) error {
+	if path == "" {
+		return fmt.Errorf("Snapshot path is required")
+	}
+
+	err := datasetProperties{userProperties: params.UserProperties}.validate("UpdateSnapshotParams")
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("storage/snapshots/%s", url.PathEscape(path))
+
+	return p.sendRequest(ctx, http.MethodPut, uri, params)
+}
+
+// RenameSnapshot renames snapshot within its dataset, clones are kept linked to the renamed snapshot.
+// path - "pool/dataset/fs@snapshot", newName - new snapshot name w/o dataset path
+func (p *Provider) RenameSnapshot(ctx context.Context, path, newName string) error {
+	if path == "" {
+		return fmt.Errorf("Snapshot path is required")
+	} else if _, _, err := splitSnapshotPath(path); err != nil {
+		return err
+	} else if err := validateSnapshotName(newName); err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("storage/snapshots/%s/rename", url.PathEscape(path))
+	data := &nefStorageSnapshotRenameRequest{NewName: newName}
+
+	return p.sendRequest(ctx, http.MethodPost, uri, data)
+}
+
+// HoldSnapshot places a user hold with the tag on the snapshot, held snapshot cannot be destroyed
+// until all its holds are released. Multiple holds with different tags can be placed on one snapshot.
+func (p *Provider) HoldSnapshot(ctx context.Context, path, tag string) error {
+	if path == "" {
+		return fmt.Errorf("Snapshot path is required")
+	} else if tag == "" {
+		return fmt.Errorf("Snapshot hold tag is required")
+	}
+
+	uri := fmt.Sprintf("storage/snapshots/%s/holds", url.PathEscape(path))
+	data := &nefStorageSnapshotHoldRequest{Tag: tag}
+
+	return p.sendRequest(ctx, http.MethodPost, uri, data)
+}
+
+// ReleaseSnapshot releases user hold with the tag on the snapshot
+func (p *Provider) ReleaseSnapshot(ctx context.Context, path, tag string) error {
+	if path == "" {
+		return fmt.Errorf("Snapshot path is required")
+	} else if tag == "" {
+		return fmt.Errorf("Snapshot hold tag is required")
+	}
+
+	uri := fmt.Sprintf("storage/snapshots/%s/holds/%s", url.PathEscape(path), url.PathEscape(tag))
+
+	return p.sendRequest(ctx, http.MethodDelete, uri, nil)
+}
+
+// GetSnapshotHolds returns user holds on the snapshot
+func (p *Provider) GetSnapshotHolds(ctx context.Context, path string) ([]SnapshotHold, error) {
+	if path == "" {
+		return nil, fmt.Errorf("Snapshot path is required")
+	}
+
+	uri := fmt.Sprintf("storage/snapshots/%s/holds", url.PathEscape(path))
+
+	response := nefStorageSnapshotHoldsResponse{}
+	if err := p.sendRequestWithStruct(ctx, http.MethodGet, uri, nil, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
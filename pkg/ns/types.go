@@ -30,6 +30,21 @@ type Filesystem struct {
 	SharedOverSmb  bool   `json:"sharedOverSmb"`
 	BytesAvailable int64  `json:"bytesAvailable"`
 	BytesUsed      int64  `json:"bytesUsed"`
+
+	// ZFS properties, see CreateFilesystemParams
+	QuotaSize                 int64             `json:"quotaSize"`
+	ReferencedQuotaSize       int64             `json:"referencedQuotaSize"`
+	ReservationSize           int64             `json:"reservationSize"`
+	ReferencedReservationSize int64             `json:"referencedReservationSize"`
+	CompressionMode           CompressionMode   `json:"compressionMode"`
+	RecordSize                int64             `json:"recordSize"`
+	AccessTime                bool              `json:"accessTime"`
+	SyncMode                  SyncMode          `json:"syncMode"`
+	LogBias                   LogBias           `json:"logBias"`
+	ReadOnly                  bool              `json:"readOnly"`
+	NonBlockingMandatoryMode  bool              `json:"nonBlockingMandatoryMode"`
+	CaseSensitivity           CaseSensitivity   `json:"caseSensitivity"`
+	UserProperties            map[string]string `json:"userProperties"`
 }
 
 // Volume - NexentaStor volume
@@ -38,6 +53,17 @@ type Volume struct {
 	BytesAvailable int64  `json:"bytesAvailable"`
 	BytesUsed      int64  `json:"bytesUsed"`
 	VolumeSize     int64  `json:"volumeSize"`
+
+	// ZFS properties, see CreateVolumeParams
+	SparseVolume    bool              `json:"sparseVolume"`
+	VolumeBlockSize int64             `json:"volumeBlockSize"`
+	CompressionMode CompressionMode   `json:"compressionMode"`
+	SyncMode        SyncMode          `json:"syncMode"`
+	WriteBackCache  bool              `json:"writeBackCache"`
+	DedupMode       DedupMode         `json:"dedupMode"`
+	ReservationSize int64             `json:"reservationSize"`
+	ReadOnly        bool              `json:"readOnly"`
+	UserProperties  map[string]string `json:"userProperties"`
 }
 
 // VolumeGroup - NexentaStor volumeGroup
@@ -97,12 +123,27 @@ type Snapshot struct {
 	Clones       []string  `json:"clones"`
 	CreationTxg  string    `json:"creationTxg"`
 	CreationTime time.Time `json:"creationTime"`
+
+	// BytesReferenced - size of data accessible by the snapshot, i.e. dataset size at snapshot time
+	BytesReferenced int64 `json:"bytesReferenced"`
+
+	// BytesUsed - space which is freed if the snapshot is destroyed
+	BytesUsed int64 `json:"bytesUsed"`
+
+	// UserProperties - ZFS user properties of the snapshot
+	UserProperties map[string]string `json:"userProperties"`
 }
 
 func (snapshot *Snapshot) String() string {
 	return snapshot.Path
 }
 
+// SnapshotHold - user hold on a snapshot, held snapshot cannot be destroyed
+type SnapshotHold struct {
+	Tag          string    `json:"tag"`
+	CreationTime time.Time `json:"creationTime"`
+}
+
 // RSFCluster - RSF cluster with a name
 type RSFCluster struct {
 	Name string `json:"clusterName"`
@@ -153,15 +194,26 @@ type nefStorageSnapshotsResponse struct {
 	Data []Snapshot `json:"data"`
 }
 
-type nefNasNfsRequest struct {
-	Filesystem       string                            `json:"filesystem"`
-	Anon             string                            `json:"anon"`
-	SecurityContexts []nefNasNfsRequestSecurityContext `json:"securityContexts"`
+type nefStorageSnapshotHoldsResponse struct {
+	Data []SnapshotHold `json:"data"`
 }
-type nefNasNfsRequestSecurityContext struct {
-	SecurityModes []string 							`json:"securityModes"`
-	ReadWriteList []NfsRuleList						`json:"readWriteList"`
-	ReadOnlyList  []NfsRuleList						`json:"readOnlyList"`
+
+type nefStorageSnapshotHoldRequest struct {
+	Tag string `json:"tag"`
+}
+
+type nefStorageSnapshotRenameRequest struct {
+	NewName string `json:"newName"`
+}
+
+type nefNasNfsRequest struct {
+	Filesystem       string               `json:"filesystem"`
+	Anon             string               `json:"anon"`
+	RootMapping      string               `json:"rootMapping,omitempty"`
+	Nosuid           bool                 `json:"nosuid,omitempty"`
+	Nohide           bool                 `json:"nohide,omitempty"`
+	Versions         []NfsVersion         `json:"versions,omitempty"`
+	SecurityContexts []NfsSecurityContext `json:"securityContexts"`
 }
 
 type NfsRuleList struct {
@@ -176,7 +228,19 @@ type Portal struct {
 }
 
 type nefNasSmbResponse struct {
-	ShareName string `json:"shareName"`
+	ShareName  string `json:"shareName"`
+	ShareState string `json:"shareState"`
+}
+
+type nefNasSmbRequest struct {
+	Filesystem             string        `json:"filesystem"`
+	ShareName              string        `json:"shareName,omitempty"`
+	Description            string        `json:"shareDescription,omitempty"`
+	GuestOk                bool          `json:"guestOk"`
+	AccessBasedEnumeration bool          `json:"accessBasedEnumeration"`
+	EncryptData            bool          `json:"encryptData"`
+	ShareQuota             int64         `json:"shareQuota,omitempty"`
+	ShareACL               []SmbShareACE `json:"shareAcl,omitempty"`
 }
 
 type nefStorageFilesystemsACLRequest struct {
@@ -184,6 +248,11 @@ type nefStorageFilesystemsACLRequest struct {
 	Principal   string   `json:"principal"`
 	Flags       []string `json:"flags"`
 	Permissions []string `json:"permissions"`
+	Index       *int     `json:"index,omitempty"`
+}
+
+type nefStorageFilesystemsACLResponse struct {
+	Data []ACE `json:"data"`
 }
 
 type nefRsfClustersResponse struct {
@@ -191,7 +260,9 @@ type nefRsfClustersResponse struct {
 }
 
 type nefJobStatusResponse struct {
-	Links []nefJobStatusResponseLink `json:"links"`
+	Links       []nefJobStatusResponseLink `json:"links"`
+	Progress    int                        `json:"progress"`
+	Description string                     `json:"description"`
 }
 type nefJobStatusResponseLink struct {
 	Rel  string `json:"rel"`
@@ -214,3 +285,75 @@ type nefTargetGroupsResponse struct {
 type nefTargetsResponse struct {
 	Data 	[]ISCSITarget  `json:"data"`
 }
+
+// nefSnapshotSchedule - NEF snapshot schedule, interval is in seconds
+type nefSnapshotSchedule struct {
+	Name      string `json:"name"`
+	Dataset   string `json:"dataset"`
+	Interval  int64  `json:"interval"`
+	Keep      int    `json:"keep"`
+	Recursive bool   `json:"recursive"`
+	Prefix    string `json:"prefix"`
+	Enabled   bool   `json:"enabled"`
+}
+
+type nefSnapshotSchedulesResponse struct {
+	Data []nefSnapshotSchedule `json:"data"`
+}
+
+type nefSnapshotScheduleUpdateRequest struct {
+	Interval  int64  `json:"interval,omitempty"`
+	Keep      int    `json:"keep,omitempty"`
+	Recursive *bool  `json:"recursive,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	Enabled   *bool  `json:"enabled,omitempty"`
+}
+
+// nefHprService - NEF HPR service, interval is in seconds
+type nefHprService struct {
+	Name               string           `json:"name"`
+	SourceDataset      string           `json:"sourceDataset"`
+	DestinationDataset string           `json:"destinationDataset"`
+	RemoteNode         nefHprRemoteNode `json:"remoteNode"`
+	Mode               string           `json:"mode"`
+	Recursive          bool             `json:"recursive"`
+	Interval           int64            `json:"interval"`
+	Enabled            bool             `json:"enabled"`
+}
+
+type nefHprRemoteNode struct {
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"`
+}
+
+type nefHprServicesResponse struct {
+	Data []nefHprService `json:"data"`
+}
+
+type nefHprServiceStatus struct {
+	State        string    `json:"state"`
+	Progress     int       `json:"progress"`
+	BytesSent    int64     `json:"bytesSent"`
+	LastMode     string    `json:"lastMode"`
+	LastSnapshot string    `json:"lastSnapshot"`
+	LastSyncTime time.Time `json:"lastSyncTime"`
+	LastError    string    `json:"lastError"`
+}
+
+type nefHprSendRequest struct {
+	Mode string `json:"mode,omitempty"`
+}
+
+type nefDatasetRenameRequest struct {
+	NewPath       string `json:"newPath"`
+	CreateParents bool   `json:"createParents,omitempty"`
+	Force         bool   `json:"force,omitempty"`
+}
+
+type nefNasNfsResponse struct {
+	Data []NfsShare `json:"data"`
+}
+
+type nefNasSmbListResponse struct {
+	Data []SmbShare `json:"data"`
+}
@@ -1,6 +1,8 @@
 package rest
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -8,30 +10,33 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
 )
 
 const requestTimeout = 30 * time.Second
 
 // Client - request client for any REST API
 type Client struct {
-	address    string
-	authToken  string
-	httpClient *http.Client
-	log        *logrus.Entry
+	address     string
+	httpClient  *http.Client
+	log         logger.Logger
+	retryPolicy *RetryPolicy
+	redactor    *redactor
 
+	// mux guards requestID and authToken
 	mux       sync.Mutex
 	requestID int64
+	authToken string
 }
 
 // ClientInterface - request client interface
 type ClientInterface interface {
 	BuildURI(uri string, params map[string]string) string
 	Send(method, path string, data interface{}) (int, []byte, error)
+	SendContext(ctx context.Context, method, path string, data interface{}) (int, []byte, error)
 	SetAuthToken(token string)
 }
 
@@ -57,9 +62,16 @@ func (c *Client) BuildURI(uri string, params map[string]string) string {
 // Send sends request to REST server
 // data interface{} - request payload, any interface for json.Marshal()
 func (c *Client) Send(method, path string, data interface{}) (int, []byte, error) {
+	return c.SendContext(context.Background(), method, path, data)
+}
+
+// SendContext sends request to REST server, the request is canceled when ctx is done
+// data interface{} - request payload, any interface for json.Marshal()
+// Failed attempts are repeated according to the client's retry policy.
+func (c *Client) SendContext(ctx context.Context, method, path string, data interface{}) (int, []byte, error) {
 	c.mux.Lock()
 	c.requestID++
-	l := c.log.WithFields(logrus.Fields{
+	l := c.log.WithFields(logger.Fields{
 		"func":  "Send()",
 		"req":   fmt.Sprintf("%s %s", method, path),
 		"reqID": c.requestID,
@@ -68,31 +80,60 @@ func (c *Client) Send(method, path string, data interface{}) (int, []byte, error
 
 	uri := fmt.Sprintf("%s/%s", c.address, path)
 
-	l.Debug("send request")
 	// send request data as json
-	var jsonDataReader io.Reader
+	var jsonData []byte
 	if data != nil {
-		jsonData, err := json.Marshal(data)
+		var err error
+		jsonData, err = json.Marshal(data)
 		if err != nil {
 			return 0, nil, err
 		}
-		jsonDataReader = strings.NewReader(string(jsonData))
-		l.Debugf("data: %+v", data) //TODO hide passwords
 	}
 
-	req, err := http.NewRequest(method, uri, jsonDataReader)
+	maxAttempts := c.retryPolicy.Attempts(method)
+	for attempt := 1; ; attempt++ {
+		al := l.WithField("attempt", attempt)
+
+		statusCode, bodyBytes, err := c.send(ctx, al, method, uri, jsonData)
+		if attempt >= maxAttempts || ctx.Err() != nil {
+			return statusCode, bodyBytes, err
+		} else if err == nil && !c.retryPolicy.isRetryableResponse(statusCode, bodyBytes) {
+			return statusCode, bodyBytes, err
+		}
+
+		al.Debugf("attempt %d of %d failed (status code: %d, error: %v), retrying...",
+			attempt, maxAttempts, statusCode, err)
+		if waitErr := c.retryPolicy.Wait(ctx, attempt); waitErr != nil {
+			return statusCode, bodyBytes, err
+		}
+	}
+}
+
+// send makes a single request attempt
+func (c *Client) send(ctx context.Context, l logger.Logger, method, uri string, jsonData []byte) (
+	int,
+	[]byte,
+	error,
+) {
+	l.Debugf("send request")
+
+	var jsonDataReader io.Reader
+	if jsonData != nil {
+		jsonDataReader = bytes.NewReader(jsonData)
+		if logger.IsDebugEnabled(l) {
+			l.Debugf("data: %s", c.redactor.redactJSON(jsonData))
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, jsonDataReader)
 	if err != nil {
 		l.Errorf("request creation error: %s", err)
 		return 0, nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	// Remove auth header if token is empty
-	if c.authToken == "" {
-		req.Header.Del("Authorization")
-	}
-	if len(c.authToken) != 0 {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.authToken))
+	if authToken := c.getAuthToken(); authToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", authToken))
 	}
 
 	res, err := c.httpClient.Do(req)
@@ -112,26 +153,51 @@ func (c *Client) Send(method, path string, data interface{}) (int, []byte, error
 		return res.StatusCode, nil, err
 	}
 
+	if len(bodyBytes) > 0 && logger.IsDebugEnabled(l) {
+		l.Debugf("response body: %s", c.redactor.redactJSON(bodyBytes))
+	}
+
 	return res.StatusCode, bodyBytes, err
 }
 
-// SetAuthToken sets Bearer auth token for all requests
+// SetAuthToken sets Bearer auth token for all requests, it's safe to call concurrently with Send()
 func (c *Client) SetAuthToken(token string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
 	c.authToken = token
 }
 
+func (c *Client) getAuthToken() string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.authToken
+}
+
 // ClientArgs - params to create Client instance
 type ClientArgs struct {
 	Address string
-	Log     *logrus.Entry
+
+	// Log - logger to use, messages are discarded if not set (see logger package for adapters)
+	Log logger.Logger
 
 	// InsecureSkipVerify controls whether a client verifies the server's certificate chain and host name.
 	InsecureSkipVerify bool
+
+	// RetryPolicy to repeat failed requests, requests are sent once if not set
+	RetryPolicy *RetryPolicy
+
+	// SensitiveFields - JSON fields to mask in logged request and response bodies
+	// in addition to DefaultSensitiveFields() (case-insensitive)
+	SensitiveFields []string
 }
 
 // NewClient creates new REST client
 func NewClient(args ClientArgs) ClientInterface {
-	l := args.Log.WithField("cmp", "RestClient")
+	log := args.Log
+	if log == nil {
+		log = logger.Noop()
+	}
+	l := log.WithField("cmp", "RestClient")
 
 	tr := &http.Transport{
 		IdleConnTimeout: 60 * time.Second,
@@ -147,9 +213,11 @@ func NewClient(args ClientArgs) ClientInterface {
 
 	l.Debugf("created for '%s'", args.Address)
 	return &Client{
-		address:    args.Address,
-		httpClient: httpClient,
-		log:        l,
-		requestID:  0,
+		address:     args.Address,
+		httpClient:  httpClient,
+		log:         l,
+		retryPolicy: args.RetryPolicy,
+		redactor:    newRedactor(args.SensitiveFields),
+		requestID:   0,
 	}
 }
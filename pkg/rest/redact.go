@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RedactedValue replaces values of sensitive fields in logs
+const RedactedValue = "*****"
+
+// maxLoggedBodySize - request and response bodies are truncated in logs to this size (in bytes)
+const maxLoggedBodySize = 4096
+
+// defaultSensitiveFields - JSON fields which values are never logged (case-insensitive)
+var defaultSensitiveFields = []string{
+	"password",
+	"newPassword",
+	"oldPassword",
+	"chapSecret",
+	"mutualChapSecret",
+	"secret",
+	"secretKey",
+	"privateKey",
+	"passphrase",
+	"token",
+	"accessToken",
+	"refreshToken",
+}
+
+// DefaultSensitiveFields returns JSON fields which values are never logged (case-insensitive),
+// extra fields are set by ClientArgs.SensitiveFields
+func DefaultSensitiveFields() []string {
+	return append([]string{}, defaultSensitiveFields...)
+}
+
+// redactor masks values of sensitive fields in JSON payloads before logging
+type redactor struct {
+	fields map[string]bool
+}
+
+func newRedactor(extraFields []string) *redactor {
+	r := &redactor{fields: map[string]bool{}}
+	for _, fields := range [][]string{defaultSensitiveFields, extraFields} {
+		for _, field := range fields {
+			r.fields[strings.ToLower(field)] = true
+		}
+	}
+	return r
+}
+
+// redactJSON returns JSON payload with masked values of sensitive fields,
+// non-JSON payloads are returned as is, truncated to maxLoggedBodySize
+func (r *redactor) redactJSON(data []byte) string {
+	if r == nil {
+		r = newRedactor(nil)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		if len(data) > maxLoggedBodySize {
+			return fmt.Sprintf("%s... (%d bytes)", data[:maxLoggedBodySize], len(data))
+		}
+		return string(data)
+	}
+
+	redacted, err := json.Marshal(r.redactValue(value))
+	if err != nil {
+		return fmt.Sprintf("<cannot log payload: %s>", err)
+	} else if len(redacted) > maxLoggedBodySize {
+		return fmt.Sprintf("%s... (%d bytes)", redacted[:maxLoggedBodySize], len(redacted))
+	}
+
+	return string(redacted)
+}
+
+func (r *redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, fieldValue := range v {
+			if r.fields[strings.ToLower(key)] {
+				v[key] = RedactedValue
+			} else {
+				v[key] = r.redactValue(fieldValue)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.redactValue(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,150 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryPolicy - request retry policy, exponential backoff with jitter is used between attempts
+type RetryPolicy struct {
+	// MaxAttempts - maximum count of attempts including the first one, values < 2 disable retries
+	MaxAttempts int
+
+	// InitialBackoff - delay before the second attempt
+	InitialBackoff time.Duration
+
+	// MaxBackoff - maximum delay between attempts, no limit if 0
+	MaxBackoff time.Duration
+
+	// Multiplier - backoff growth factor for each next attempt, 2 is used if not set
+	Multiplier float64
+
+	// Jitter - random part of the backoff [0..1], backoff 10s with 0.2 jitter results in 8s..12s delay
+	Jitter float64
+
+	// RetryableStatusCodes - HTTP response status codes to retry
+	RetryableStatusCodes []int
+
+	// RetryableErrorCodes - "code" field values of JSON error response to retry.
+	// NEF "EBUSY" can be added here, but NexentaStor also uses it for lasting states (e.g. dataset has
+	// snapshots or snapshot is held), so such requests fail only after all attempts.
+	RetryableErrorCodes []string
+
+	// RetryNonIdempotent enables retries of non-idempotent requests (POST, PATCH),
+	// by default only idempotent methods are retried
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns retry policy suitable for NexentaStor API:
+// 5 attempts within ~30s, retries on gateway errors only, see RetryableErrorCodes to retry "EBUSY"
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 2 * time.Second,
+		MaxBackoff:     15 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatusCodes: []int{
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+var (
+	jitterRandMux sync.Mutex
+	jitterRand    = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Attempts returns maximum count of attempts for the request method
+func (p *RetryPolicy) Attempts(method string) int {
+	if p == nil || p.MaxAttempts < 2 || !(p.RetryNonIdempotent || IsIdempotentMethod(method)) {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Backoff returns delay after the attempt (starts from 1)
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		jitterRandMux.Lock()
+		random := jitterRand.Float64()
+		jitterRandMux.Unlock()
+		backoff += backoff * p.Jitter * (2*random - 1)
+	}
+
+	return time.Duration(backoff)
+}
+
+// Wait waits for the backoff delay after the attempt, returns an error if ctx is done earlier
+func (p *RetryPolicy) Wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.Backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// IsRetryableErrorCode checks if an error code from response body should be retried
+func (p *RetryPolicy) IsRetryableErrorCode(code string) bool {
+	if p == nil || code == "" {
+		return false
+	}
+	for _, c := range p.RetryableErrorCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryableResponse checks response status code and error code in the body
+func (p *RetryPolicy) isRetryableResponse(statusCode int, body []byte) bool {
+	for _, c := range p.RetryableStatusCodes {
+		if c == statusCode {
+			return true
+		}
+	}
+
+	if statusCode < 300 || len(p.RetryableErrorCodes) == 0 {
+		return false
+	}
+
+	response := struct {
+		Code string `json:"code"`
+	}{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false
+	}
+
+	return p.IsRetryableErrorCode(response.Code)
+}
+
+// IsIdempotentMethod checks if HTTP method is idempotent (RFC 7231)
+func IsIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package provider_test
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -11,7 +12,9 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
 	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
 )
 
 // defaults
@@ -36,10 +39,12 @@ type config struct {
 	smbShareName string
 	snapshotName string
 	cluster      bool
+	fake         bool
 }
 
 var c *config
 var l *logrus.Entry
+var ctx = context.Background()
 
 func TestMain(m *testing.M) {
 	var (
@@ -61,8 +66,18 @@ func TestMain(m *testing.M) {
 		l.Logger.SetLevel(logrus.DebugLevel)
 	}
 
+	// run tests against in-process fake NexentaStor if no address provided
+	var fakeServer *nstest.Server
 	if *address == "" {
-		l.Fatal("--address=[schema://host:port,...] flag cannot be empty")
+		fakeServer = nstest.NewServer(nstest.ServerArgs{
+			Username:    *username,
+			Password:    *password,
+			Pools:       []string{*pool},
+			Filesystems: []string{fmt.Sprintf("%s/%s", *pool, *dataset)},
+			TLS:         true,
+		})
+		*address = fakeServer.URL
+		l = l.WithField("ns", *address)
 	}
 
 	c = &config{
@@ -72,12 +87,17 @@ func TestMain(m *testing.M) {
 		pool:         *pool,
 		dataset:      fmt.Sprintf("%s/%s", *pool, *dataset),
 		filesystem:   fmt.Sprintf("%s/%s/%s", *pool, *dataset, *filesystem),
+		fake:         fakeServer != nil,
 		cluster:      *cluster,
 		smbShareName: "testShareName",
 		snapshotName: "snap-test",
 	}
 
-	os.Exit(m.Run())
+	code := m.Run()
+	if fakeServer != nil {
+		fakeServer.Close()
+	}
+	os.Exit(code)
 }
 
 func TestProvider_NewProvider(t *testing.T) {
@@ -90,7 +110,7 @@ func TestProvider_NewProvider(t *testing.T) {
 		Address:            c.address,
 		Username:           c.username,
 		Password:           c.password,
-		Log:                l,
+		Log:                logger.NewLogrus(l),
 		InsecureSkipVerify: true,
 	})
 	if err != nil {
@@ -98,7 +118,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	}
 
 	t.Run("GetLicense()", func(tt *testing.T) {
-		license, err := nsp.GetLicense()
+		license, err := nsp.GetLicense(ctx)
 		if err != nil {
 			t.Error(err)
 		} else if !license.Valid {
@@ -109,7 +129,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("GetPools()", func(t *testing.T) {
-		pools, err := nsp.GetPools()
+		pools, err := nsp.GetPools(ctx)
 		if err != nil {
 			t.Error(err)
 		} else if !poolArrayContains(pools, c.pool) {
@@ -118,7 +138,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("GetFilesystems()", func(t *testing.T) {
-		filesystems, err := nsp.GetFilesystems(c.pool)
+		filesystems, err := nsp.GetFilesystems(ctx, c.pool)
 		if err != nil {
 			t.Error(err)
 		} else if filesystemArrayContains(filesystems, c.pool) {
@@ -129,7 +149,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("GetFilesystem() exists", func(t *testing.T) {
-		filesystem, err := nsp.GetFilesystem(c.dataset)
+		filesystem, err := nsp.GetFilesystem(ctx, c.dataset)
 		if err != nil {
 			t.Error(err)
 		} else if filesystem.Path != c.dataset {
@@ -139,7 +159,7 @@ func TestProvider_NewProvider(t *testing.T) {
 
 	t.Run("GetFilesystem() not exists", func(t *testing.T) {
 		nonExistingName := "NON_EXISTING"
-		filesystem, err := nsp.GetFilesystem(nonExistingName)
+		filesystem, err := nsp.GetFilesystem(ctx, nonExistingName)
 		if err != nil && !strings.Contains(err.Error(), "not found") {
 			t.Error(err)
 		} else if filesystem.Path != "" {
@@ -150,7 +170,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	t.Run("CreateFilesystem()", func(t *testing.T) {
 		destroyFilesystemWithDependents(nsp, c.filesystem)
 
-		err = nsp.CreateFilesystem(ns.CreateFilesystemParams{
+		err = nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{
 			Path: c.filesystem,
 		})
 		if err != nil {
@@ -158,7 +178,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		filesystems, err := nsp.GetFilesystems(c.dataset)
+		filesystems, err := nsp.GetFilesystems(ctx, c.dataset)
 		if err != nil {
 			t.Error(err)
 		} else if !filesystemArrayContains(filesystems, c.filesystem) {
@@ -167,7 +187,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("GetFilesystem() created filesystem should not be shared", func(t *testing.T) {
-		filesystem, err := nsp.GetFilesystem(c.filesystem)
+		filesystem, err := nsp.GetFilesystem(ctx, c.filesystem)
 		if err != nil {
 			t.Error(err)
 		} else if filesystem.SharedOverNfs {
@@ -178,9 +198,9 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("CreateNfsShare()", func(t *testing.T) {
-		nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: c.filesystem})
+		nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: c.filesystem})
 
-		err = nsp.CreateNfsShare(ns.CreateNfsShareParams{
+		err = nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{
 			Filesystem: c.filesystem,
 		})
 		if err != nil {
@@ -189,7 +209,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("GetFilesystem() created filesystem should be shared over NFS", func(t *testing.T) {
-		filesystem, err := nsp.GetFilesystem(c.filesystem)
+		filesystem, err := nsp.GetFilesystem(ctx, c.filesystem)
 		if err != nil {
 			t.Error(err)
 		} else if !filesystem.SharedOverNfs {
@@ -198,6 +218,10 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("nfs share should appear on NS", func(t *testing.T) {
+		if c.fake {
+			t.Skip("Skipping showmount check on fake NS")
+		}
+
 		//TODO other way to cut out host from address
 		host := strings.Split(c.address, "//")[1]
 		host = strings.Split(host, ":")[0]
@@ -211,7 +235,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("DeleteNfsShare()", func(t *testing.T) {
-		filesystems, err := nsp.GetFilesystems(c.dataset)
+		filesystems, err := nsp.GetFilesystems(ctx, c.dataset)
 		if err != nil {
 			t.Error(err)
 			return
@@ -220,7 +244,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		err = nsp.DeleteNfsShare(c.filesystem)
+		err = nsp.DeleteNfsShare(ctx, c.filesystem)
 		if err != nil {
 			t.Error(err)
 		}
@@ -232,9 +256,9 @@ func TestProvider_NewProvider(t *testing.T) {
 		t.Run(
 			fmt.Sprintf("CreateSmbShare() should create SMB share with '%s' share name", smbShareName),
 			func(t *testing.T) {
-				nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: c.filesystem})
+				nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: c.filesystem})
 
-				err = nsp.CreateSmbShare(ns.CreateSmbShareParams{
+				err = nsp.CreateSmbShare(ctx, ns.CreateSmbShareParams{
 					Filesystem: c.filesystem,
 					ShareName:  smbShareName,
 				})
@@ -245,7 +269,7 @@ func TestProvider_NewProvider(t *testing.T) {
 		)
 
 		t.Run("GetFilesystem() created filesystem should be shared over SMB", func(t *testing.T) {
-			filesystem, err := nsp.GetFilesystem(c.filesystem)
+			filesystem, err := nsp.GetFilesystem(ctx, c.filesystem)
 			if err != nil {
 				t.Error(err)
 			} else if !filesystem.SharedOverSmb {
@@ -254,7 +278,7 @@ func TestProvider_NewProvider(t *testing.T) {
 		})
 
 		t.Run("GetSmbShareName() should return SMB share name", func(t *testing.T) {
-			filesystem, err := nsp.GetFilesystem(c.filesystem)
+			filesystem, err := nsp.GetFilesystem(ctx, c.filesystem)
 			if err != nil {
 				t.Error(err)
 				return
@@ -267,7 +291,7 @@ func TestProvider_NewProvider(t *testing.T) {
 				expectedShareName = smbShareName
 			}
 
-			shareName, err := nsp.GetSmbShareName(c.filesystem)
+			shareName, err := nsp.GetSmbShareName(ctx, c.filesystem)
 			if err != nil {
 				t.Error(err)
 			} else if shareName != expectedShareName {
@@ -284,7 +308,7 @@ func TestProvider_NewProvider(t *testing.T) {
 		//TODO test SMB share, mount cifs?
 
 		t.Run("DeleteSmbShare()", func(t *testing.T) {
-			err = nsp.DeleteSmbShare(c.filesystem)
+			err = nsp.DeleteSmbShare(ctx, c.filesystem)
 			if err != nil {
 				t.Error(err)
 			}
@@ -292,19 +316,19 @@ func TestProvider_NewProvider(t *testing.T) {
 	}
 
 	t.Run("DestroyFilesystem()", func(t *testing.T) {
-		nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
-		nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: c.filesystem})
+		nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: c.filesystem})
 
-		err = nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{DestroySnapshots: true})
+		err = nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{DestroySnapshots: true})
 		if err != nil {
 			t.Error(err)
 			return
 		}
 
-		filesystems, err := nsp.GetFilesystems(c.dataset)
+		filesystems, err := nsp.GetFilesystems(ctx, c.dataset)
 		if err != nil {
 			t.Error(err)
 		} else if filesystemArrayContains(filesystems, c.filesystem) {
@@ -313,14 +337,14 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("CreateFilesystem() with referenced quota size", func(t *testing.T) {
-		nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
 
 		var referencedQuotaSize int64 = 2 * 1024 * 1024 * 1024
 
-		err = nsp.CreateFilesystem(ns.CreateFilesystemParams{
+		err = nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{
 			Path:                c.filesystem,
 			ReferencedQuotaSize: referencedQuotaSize,
 		})
@@ -329,7 +353,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		filesystem, err := nsp.GetFilesystem(c.filesystem)
+		filesystem, err := nsp.GetFilesystem(ctx, c.filesystem)
 		if err != nil {
 			t.Error(err)
 			return
@@ -345,20 +369,20 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("CreateSnapshot()", func(t *testing.T) {
-		nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
-		nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: c.filesystem})
+		nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: c.filesystem})
 
-		err = nsp.CreateSnapshot(ns.CreateSnapshotParams{
+		err = nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{
 			Path: testSnapshotPath,
 		})
 		if err != nil {
 			t.Error(err)
 		}
 
-		snapshot, err := nsp.GetSnapshot(testSnapshotPath)
+		snapshot, err := nsp.GetSnapshot(ctx, testSnapshotPath)
 		if err != nil {
 			t.Error(err)
 			return
@@ -391,7 +415,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		snapshots, err := nsp.GetSnapshots(c.filesystem, true)
+		snapshots, err := nsp.GetSnapshots(ctx, c.filesystem, true)
 		if err != nil {
 			t.Errorf("Cannot get '%s' snapshot list: %v", c.filesystem, err)
 			return
@@ -415,24 +439,24 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("CloneSnapshot()", func(t *testing.T) {
-		nsp.DestroySnapshot(testSnapshotPath)
-		nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		nsp.DestroySnapshot(ctx, testSnapshotPath)
+		nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
-		nsp.DestroyFilesystem(testSnapshotCloneTargetPath, ns.DestroyFilesystemParams{
+		nsp.DestroyFilesystem(ctx, testSnapshotCloneTargetPath, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
-		nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: c.filesystem})
+		nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: c.filesystem})
 
-		err := nsp.CreateSnapshot(ns.CreateSnapshotParams{Path: testSnapshotPath})
+		err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: testSnapshotPath})
 		if err != nil {
 			t.Error(err)
 			return
 		}
 
-		err = nsp.CloneSnapshot(testSnapshotPath, ns.CloneSnapshotParams{
+		err = nsp.CloneSnapshot(ctx, testSnapshotPath, ns.CloneSnapshotParams{
 			TargetPath: testSnapshotCloneTargetPath,
 		})
 		if err != nil {
@@ -440,7 +464,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		_, err = nsp.GetFilesystem(testSnapshotCloneTargetPath)
+		_, err = nsp.GetFilesystem(ctx, testSnapshotCloneTargetPath)
 		if err != nil {
 			t.Errorf("Cannot get created filesystem '%s': %v", testSnapshotCloneTargetPath, err)
 			return
@@ -448,7 +472,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("PromoteFilesystem()", func(t *testing.T) {
-		err := nsp.PromoteFilesystem(testSnapshotCloneTargetPath)
+		err := nsp.PromoteFilesystem(ctx, testSnapshotCloneTargetPath)
 		if err != nil {
 			t.Error(err)
 		}
@@ -457,42 +481,42 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("DestroySnapshot()", func(t *testing.T) {
-		nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
-		nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: c.filesystem})
-		nsp.CreateSnapshot(ns.CreateSnapshotParams{Path: testSnapshotPath})
+		nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: c.filesystem})
+		nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: testSnapshotPath})
 
-		err := nsp.DestroySnapshot(testSnapshotPath)
+		err := nsp.DestroySnapshot(ctx, testSnapshotPath)
 		if err != nil {
 			t.Error(err)
 		}
 	})
 
 	t.Run("DestroyFilesystem() for filesystem with snapshots", func(t *testing.T) {
-		nsp.DestroySnapshot(testSnapshotPath)
-		nsp.DestroyFilesystem(testSnapshotCloneTargetPath, ns.DestroyFilesystemParams{
+		nsp.DestroySnapshot(ctx, testSnapshotPath)
+		nsp.DestroyFilesystem(ctx, testSnapshotCloneTargetPath, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
-		nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
 
-		err := nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: c.filesystem})
+		err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: c.filesystem})
 		if err != nil {
 			t.Errorf("Failed to create preconditions: Create filesystem '%s' failed: %v", c.filesystem, err)
 			return
 		}
-		err = nsp.CreateSnapshot(ns.CreateSnapshotParams{Path: testSnapshotPath})
+		err = nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: testSnapshotPath})
 		if err != nil {
 			t.Errorf("Failed to create preconditions: Create snapshot '%s' failed: %v", testSnapshotPath, err)
 			return
 		}
 
-		err = nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{DestroySnapshots: false})
+		err = nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{DestroySnapshots: false})
 		if !ns.IsBusyNefError(err) {
 			t.Errorf(
 				`Filesystem delete request is supposed to return EBUSY error in case of deleting
@@ -502,13 +526,13 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		err = nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{DestroySnapshots: true})
+		err = nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{DestroySnapshots: true})
 		if err != nil {
 			t.Errorf("Cannot destroy filesystem, even with snapshots=true option: %v", err)
 			return
 		}
 
-		filesystem, err := nsp.GetFilesystem(c.filesystem)
+		filesystem, err := nsp.GetFilesystem(ctx, c.filesystem)
 		if !ns.IsNotExistNefError(err) {
 			t.Errorf(
 				"Get filesystem request should return ENOENT error, but it returns filesystem: %v, error: %v",
@@ -519,27 +543,27 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("DestroyFilesystem() for filesystem with clones", func(t *testing.T) {
-		nsp.DestroySnapshot(testSnapshotPath)
-		nsp.DestroyFilesystem(testSnapshotCloneTargetPath, ns.DestroyFilesystemParams{
+		nsp.DestroySnapshot(ctx, testSnapshotPath)
+		nsp.DestroyFilesystem(ctx, testSnapshotCloneTargetPath, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
-		nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
 
-		err := nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: c.filesystem})
+		err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: c.filesystem})
 		if err != nil {
 			t.Errorf("Failed to create preconditions: Create filesystem '%s' failed: %v", c.filesystem, err)
 			return
 		}
-		err = nsp.CreateSnapshot(ns.CreateSnapshotParams{Path: testSnapshotPath})
+		err = nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: testSnapshotPath})
 		if err != nil {
 			t.Errorf("Failed to create preconditions: Create snapshot '%s' failed: %v", testSnapshotPath, err)
 			return
 		}
-		err = nsp.CloneSnapshot(testSnapshotPath, ns.CloneSnapshotParams{
+		err = nsp.CloneSnapshot(ctx, testSnapshotPath, ns.CloneSnapshotParams{
 			TargetPath: testSnapshotCloneTargetPath,
 		})
 		if err != nil {
@@ -552,7 +576,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		err = nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		err = nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: false,
 		})
@@ -565,7 +589,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		err = nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		err = nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
@@ -574,7 +598,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		filesystem, err := nsp.GetFilesystem(c.filesystem)
+		filesystem, err := nsp.GetFilesystem(ctx, c.filesystem)
 		if !ns.IsNotExistNefError(err) {
 			t.Errorf(
 				"Get filesystem request should return ENOENT error, but it returns filesystem: %v, error: %v",
@@ -583,7 +607,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			)
 		}
 
-		filesystem, err = nsp.GetFilesystem(testSnapshotCloneTargetPath)
+		filesystem, err = nsp.GetFilesystem(ctx, testSnapshotCloneTargetPath)
 		if err != nil {
 			t.Errorf(
 				"Cloned filesystem '%s' should be presented, but there is an error while getting it: %v",
@@ -594,14 +618,14 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	t.Run("GetFilesystemAvailableCapacity()", func(t *testing.T) {
-		nsp.DestroyFilesystem(c.filesystem, ns.DestroyFilesystemParams{
+		nsp.DestroyFilesystem(ctx, c.filesystem, ns.DestroyFilesystemParams{
 			DestroySnapshots:               true,
 			PromoteMostRecentCloneIfExists: true,
 		})
 
 		var referencedQuotaSize int64 = 3 * 1024 * 1024 * 1024
 
-		err = nsp.CreateFilesystem(ns.CreateFilesystemParams{
+		err = nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{
 			Path:                c.filesystem,
 			ReferencedQuotaSize: referencedQuotaSize,
 		})
@@ -610,7 +634,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		availableCapacity, err := nsp.GetFilesystemAvailableCapacity(c.filesystem)
+		availableCapacity, err := nsp.GetFilesystemAvailableCapacity(ctx, c.filesystem)
 		if err != nil {
 			t.Error(err)
 			return
@@ -630,7 +654,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	t.Run("GetRSFClusters()", func(t *testing.T) {
 		expectedToBeACluster := c.cluster
 
-		clusters, err := nsp.GetRSFClusters()
+		clusters, err := nsp.GetRSFClusters(ctx)
 		if err != nil {
 			t.Error(err)
 			return
@@ -653,7 +677,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	t.Run("GetFilesystemsSlice()", func(t *testing.T) {
 		destroyFilesystemWithDependents(nsp, c.filesystem)
 
-		err = nsp.CreateFilesystem(ns.CreateFilesystemParams{
+		err = nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{
 			Path: c.filesystem,
 		})
 		if err != nil {
@@ -668,13 +692,13 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		filesystems, err := nsp.GetFilesystemsSlice(c.filesystem, 0, 0)
+		filesystems, err := nsp.GetFilesystemsSlice(ctx, c.filesystem, 0, 0)
 		if err == nil {
 			t.Errorf("Should return an error when limit is equal 0, but got: %v", err)
 			return
 		}
 
-		filesystems, err = nsp.GetFilesystemsSlice(c.filesystem, 2, 0)
+		filesystems, err = nsp.GetFilesystemsSlice(ctx, c.filesystem, 2, 0)
 		if err != nil {
 			t.Error(err)
 			return
@@ -697,7 +721,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			return
 		}
 
-		filesystems, err = nsp.GetFilesystemsSlice(c.filesystem, 4, 3)
+		filesystems, err = nsp.GetFilesystemsSlice(ctx, c.filesystem, 4, 3)
 		if err != nil {
 			t.Error(err)
 			return
@@ -737,7 +761,7 @@ func TestProvider_NewProvider(t *testing.T) {
 		destroyFilesystemWithDependents(nsp, c.filesystem)
 
 		t.Log("create parent filesystem")
-		err = nsp.CreateFilesystem(ns.CreateFilesystemParams{
+		err = nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{
 			Path: c.filesystem,
 		})
 		if err != nil {
@@ -754,7 +778,7 @@ func TestProvider_NewProvider(t *testing.T) {
 		}
 
 		t.Log("get all filesystems")
-		filesystems, err := nsp.GetFilesystems(c.filesystem)
+		filesystems, err := nsp.GetFilesystems(ctx, c.filesystem)
 		if err != nil {
 			t.Error(err)
 			return
@@ -780,7 +804,7 @@ func TestProvider_NewProvider(t *testing.T) {
 		destroyFilesystemWithDependents(nsp, c.filesystem)
 
 		t.Log("create parent filesystem")
-		if err = nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: c.filesystem}); err != nil {
+		if err = nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: c.filesystem}); err != nil {
 			t.Error(err)
 			return
 		}
@@ -811,7 +835,7 @@ func TestProvider_NewProvider(t *testing.T) {
 			f := fmt.Sprintf("startingToken: '%s', limit: '%d'", v.StartingToken, v.Limit)
 			t.Logf("...check %s", f)
 
-			filesystems, nextToken, err := nsp.GetFilesystemsWithStartingToken(c.filesystem, v.StartingToken, v.Limit)
+			filesystems, nextToken, err := nsp.GetFilesystemsWithStartingToken(ctx, c.filesystem, v.StartingToken, v.Limit)
 			if err != nil {
 				t.Error(err)
 				return
@@ -827,7 +851,7 @@ func TestProvider_NewProvider(t *testing.T) {
 		nextToken := ""
 		filesystems := []ns.Filesystem{}
 		for {
-			filesystemsSlice, nt, err := nsp.GetFilesystemsWithStartingToken(c.filesystem, nextToken, 25)
+			filesystemsSlice, nt, err := nsp.GetFilesystemsWithStartingToken(ctx, c.filesystem, nextToken, 25)
 			if err != nil {
 				t.Error(err)
 				return
@@ -865,7 +889,7 @@ func TestProvider_NewProvider(t *testing.T) {
 	})
 
 	// clean up
-	nsp.DestroySnapshot(testSnapshotPath)
+	nsp.DestroySnapshot(ctx, testSnapshotPath)
 	destroyFilesystemWithDependents(nsp, testSnapshotCloneTargetPath)
 	destroyFilesystemWithDependents(nsp, c.filesystem)
 }
@@ -880,7 +904,7 @@ func createFilesystemChildren(nsp ns.ProviderInterface, parent string, count int
 	for i := 0; i < count; i++ {
 		i := i
 		jobs[i] = func() error {
-			return nsp.CreateFilesystem(ns.CreateFilesystemParams{Path: getFilesystemChildName(parent, i+1)})
+			return nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: getFilesystemChildName(parent, i+1)})
 		}
 	}
 
@@ -888,7 +912,7 @@ func createFilesystemChildren(nsp ns.ProviderInterface, parent string, count int
 }
 
 func destroyFilesystemWithDependents(nsp ns.ProviderInterface, filesystem string) error {
-	children, err := nsp.GetFilesystems(filesystem)
+	children, err := nsp.GetFilesystems(ctx, filesystem)
 	if err != nil {
 		return fmt.Errorf("destroyFilesystemWithDependents(%s): failed to get children: %v", filesystem, err)
 	}
@@ -911,7 +935,7 @@ func destroyFilesystemWithDependents(nsp ns.ProviderInterface, filesystem string
 		}
 	}
 
-	err = nsp.DestroyFilesystem(filesystem, ns.DestroyFilesystemParams{DestroySnapshots: true})
+	err = nsp.DestroyFilesystem(ctx, filesystem, ns.DestroyFilesystemParams{DestroySnapshots: true})
 	if err != nil {
 		return fmt.Errorf("destroyFilesystemWithDependents(%s): failed to destroy filesystem: %v", filesystem, err)
 	}
@@ -1,6 +1,7 @@
 package resolver_test
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -8,7 +9,9 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
 	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
 )
 
 const (
@@ -26,10 +29,12 @@ type config struct {
 	pool       string
 	dataset    string
 	filesystem string
+	fake       bool
 }
 
 var c *config
 var l *logrus.Entry
+var ctx = context.Background()
 
 func filesystemArrayContains(array []ns.Filesystem, value string) bool {
 	for _, v := range array {
@@ -59,8 +64,18 @@ func TestMain(m *testing.M) {
 		l.Logger.SetLevel(logrus.DebugLevel)
 	}
 
+	// run tests against in-process fake NexentaStor if no address provided
+	var fakeServer *nstest.Server
 	if *address == "" {
-		l.Fatal("--address=[schema://host:port,...] flag cannot be empty")
+		fakeServer = nstest.NewServer(nstest.ServerArgs{
+			Username:    *username,
+			Password:    *password,
+			Pools:       []string{*pool},
+			Filesystems: []string{fmt.Sprintf("%s/%s", *pool, *dataset)},
+			TLS:         true,
+		})
+		*address = fakeServer.URL
+		l = l.WithField("ns", *address)
 	}
 
 	c = &config{
@@ -70,9 +85,14 @@ func TestMain(m *testing.M) {
 		pool:       *pool,
 		dataset:    fmt.Sprintf("%s/%s", *pool, *dataset),
 		filesystem: fmt.Sprintf("%s/%s/%s", *pool, *dataset, *filesystem),
+		fake:       fakeServer != nil,
 	}
 
-	os.Exit(m.Run())
+	code := m.Run()
+	if fakeServer != nil {
+		fakeServer.Close()
+	}
+	os.Exit(code)
 }
 
 func TestResolver_NewResolverMulti(t *testing.T) {
@@ -82,7 +102,7 @@ func TestResolver_NewResolverMulti(t *testing.T) {
 		Address:            c.address,
 		Username:           c.username,
 		Password:           c.password,
-		Log:                l,
+		Log:                logger.NewLogrus(l),
 		InsecureSkipVerify: true,
 	})
 	if err != nil {
@@ -91,7 +111,7 @@ func TestResolver_NewResolverMulti(t *testing.T) {
 	}
 
 	t.Run("Resolve() should return NS with requested dataset", func(t *testing.T) {
-		nsProvider, err := nsr.Resolve(c.dataset)
+		nsProvider, err := nsr.Resolve(ctx, c.dataset)
 		if err != nil {
 			t.Error(err)
 			return
@@ -100,7 +120,7 @@ func TestResolver_NewResolverMulti(t *testing.T) {
 			return
 		}
 
-		filesystems, err := nsProvider.GetFilesystems(c.pool)
+		filesystems, err := nsProvider.GetFilesystems(ctx, c.pool)
 		if err != nil {
 			t.Errorf("NS Error: %s", err)
 			return
@@ -111,7 +131,7 @@ func TestResolver_NewResolverMulti(t *testing.T) {
 	})
 
 	t.Run("Resolve() should return error if dataset not exists", func(t *testing.T) {
-		nsProvider, err := nsr.Resolve("not/exists")
+		nsProvider, err := nsr.Resolve(ctx, "not/exists")
 		if err == nil {
 			t.Errorf("Resolver return NS for non-existing datastore: %s", nsProvider)
 			return
@@ -121,7 +141,7 @@ func TestResolver_NewResolverMulti(t *testing.T) {
 	t.Run("IsCluster()", func(t *testing.T) {
 		expectedIsCluster := len(nsr.Nodes) > 1
 
-		isCluster, err := nsr.IsCluster()
+		isCluster, err := nsr.IsCluster(ctx)
 		if err != nil {
 			t.Error(err)
 		} else if isCluster != expectedIsCluster {
@@ -0,0 +1,116 @@
+package logger_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+)
+
+func TestNoop(t *testing.T) {
+	l := logger.Noop().WithField("key", "value").WithFields(logger.Fields{"a": 1})
+	l.Debugf("debug %d", 1)
+	l.Errorf("error %d", 1)
+
+	if logger.IsDebugEnabled(l) {
+		t.Error("debug should be disabled for no-op logger")
+	}
+}
+
+func TestNewLogrus(t *testing.T) {
+	logs := &bytes.Buffer{}
+	logrusLogger := logrus.New()
+	logrusLogger.SetOutput(logs)
+	logrusLogger.SetLevel(logrus.InfoLevel)
+
+	l := logger.NewLogrus(logrusLogger).WithField("cmp", "test").WithFields(logger.Fields{"reqID": 7})
+
+	if logger.IsDebugEnabled(l) {
+		t.Error("debug should be disabled for logrus logger with info level")
+	}
+
+	l.Debugf("debug message")
+	l.Warnf("warning %s", "message")
+
+	output := logs.String()
+	if strings.Contains(output, "debug message") {
+		t.Errorf("debug message should not be written:\n%s", output)
+	}
+	for _, expected := range []string{"warning message", "cmp=test", "reqID=7", "level=warning"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("logs should contain '%s':\n%s", expected, output)
+		}
+	}
+}
+
+type zapRecord struct {
+	level         string
+	msg           string
+	keysAndValues []interface{}
+}
+
+// fakeZapLevel - zapcore.Level analog
+type fakeZapLevel int8
+
+// fakeZap implements the subset of *zap.SugaredLogger used by the adapter
+type fakeZap struct {
+	records []zapRecord
+	level   fakeZapLevel
+}
+
+func (z *fakeZap) Level() fakeZapLevel { return z.level }
+
+func (z *fakeZap) Debugw(msg string, kv ...interface{}) { z.add("debug", msg, kv) }
+func (z *fakeZap) Infow(msg string, kv ...interface{})  { z.add("info", msg, kv) }
+func (z *fakeZap) Warnw(msg string, kv ...interface{})  { z.add("warn", msg, kv) }
+func (z *fakeZap) Errorw(msg string, kv ...interface{}) { z.add("error", msg, kv) }
+
+func (z *fakeZap) add(level, msg string, kv []interface{}) {
+	z.records = append(z.records, zapRecord{level, msg, kv})
+}
+
+func TestNewZap(t *testing.T) {
+	z := &fakeZap{}
+	base := logger.NewZap(z).WithField("cmp", "test")
+	base.WithFields(logger.Fields{"b": 2, "a": 1}).Errorf("failed: %s", "reason")
+	base.Infof("info")
+
+	if len(z.records) != 2 {
+		t.Fatalf("expected 2 records, got: %+v", z.records)
+	}
+
+	record := z.records[0]
+	if record.level != "error" || record.msg != "failed: reason" {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	if kv := fmt.Sprint(record.keysAndValues); kv != "[cmp test a 1 b 2]" {
+		t.Errorf("unexpected fields: %s", kv)
+	}
+
+	// fields added to derived logger should not change the parent one
+	if kv := fmt.Sprint(z.records[1].keysAndValues); kv != "[cmp test]" {
+		t.Errorf("unexpected parent logger fields: %s", kv)
+	}
+
+	if logger.IsDebugEnabled(base) {
+		t.Error("debug should be disabled for zap logger with info level")
+	}
+	z.level = -1
+	if !logger.IsDebugEnabled(base) {
+		t.Error("debug should be enabled for zap logger with debug level")
+	}
+}
+
+func TestNewZap_Nil(t *testing.T) {
+	var z *fakeZap
+	l := logger.NewZap(z)
+	l.WithField("cmp", "test").Errorf("should not panic")
+
+	if logger.IsDebugEnabled(l) {
+		t.Error("debug should be disabled for nil zap logger")
+	}
+}
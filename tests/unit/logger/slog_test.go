@@ -0,0 +1,37 @@
+//go:build go1.21
+// +build go1.21
+
+package logger_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+)
+
+func TestNewSlog(t *testing.T) {
+	logs := &bytes.Buffer{}
+	handler := slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelInfo})
+
+	l := logger.NewSlog(slog.New(handler)).WithField("cmp", "test").WithFields(logger.Fields{"reqID": 7})
+
+	if logger.IsDebugEnabled(l) {
+		t.Error("debug should be disabled for slog logger with info level")
+	}
+
+	l.Debugf("debug message")
+	l.Infof("info %s", "message")
+
+	output := logs.String()
+	if strings.Contains(output, "debug message") {
+		t.Errorf("debug message should not be written:\n%s", output)
+	}
+	for _, expected := range []string{`msg="info message"`, "cmp=test", "reqID=7", "level=INFO"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("logs should contain '%s':\n%s", expected, output)
+		}
+	}
+}
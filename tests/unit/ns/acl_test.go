@@ -0,0 +1,255 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+var (
+	aceOwner = ns.ACE{
+		Type:        ns.ACETypeAllow,
+		Principal:   ns.ACLPrincipalOwner,
+		Permissions: []ns.ACLPermission{ns.ACLPermissionFullSet},
+	}
+	aceEveryone = ns.ACE{
+		Type:        ns.ACETypeAllow,
+		Principal:   ns.ACLPrincipalEveryone,
+		Permissions: []ns.ACLPermission{ns.ACLPermissionReadSet},
+	}
+	aceUser = ns.ACE{
+		Type:        ns.ACETypeAllow,
+		Principal:   ns.ACLUser("alice"),
+		Permissions: []ns.ACLPermission{ns.ACLPermissionModifySet},
+		Flags:       []ns.ACLFlag{ns.ACLFlagFileInherit, ns.ACLFlagDirInherit},
+	}
+	aceGroup = ns.ACE{
+		Type:        ns.ACETypeDeny,
+		Principal:   ns.ACLGroup("guests"),
+		Permissions: []ns.ACLPermission{ns.ACLPermissionWriteData, ns.ACLPermissionAppendData},
+	}
+)
+
+// checkACL compares ACL entries ignoring their indexes
+func checkACL(t *testing.T, acl, expected []ns.ACE) {
+	t.Helper()
+	if len(acl) != len(expected) {
+		t.Fatalf("expected ACL:\n%v\nbut got:\n%v", expected, acl)
+	}
+	for i := range acl {
+		if acl[i].Index != i || !acl[i].Equal(expected[i]) {
+			t.Fatalf("expected ACL:\n%v\nbut got:\n%v", expected, acl)
+		}
+	}
+}
+
+func TestACE_Equal(t *testing.T) {
+	reordered := aceUser
+	reordered.Index = 5
+	reordered.Flags = []ns.ACLFlag{ns.ACLFlagDirInherit, ns.ACLFlagFileInherit}
+	if !aceUser.Equal(reordered) {
+		t.Errorf("entries should be equal: %v, %v", aceUser, reordered)
+	}
+
+	denied := aceUser
+	denied.Type = ns.ACETypeDeny
+	if aceUser.Equal(denied) {
+		t.Errorf("entries should differ by type: %v, %v", aceUser, denied)
+	}
+
+	if s := aceUser.String(); s != "user:alice:modify_set:file_inherit/dir_inherit:allow" {
+		t.Errorf("unexpected string: '%s'", s)
+	}
+}
+
+func TestDiffACL(t *testing.T) {
+	t.Run("DiffACL() should return empty diff for equal ACLs", func(t *testing.T) {
+		diff := ns.DiffACL([]ns.ACE{aceOwner, aceEveryone}, []ns.ACE{aceOwner, aceEveryone})
+		if !diff.IsEmpty() {
+			t.Errorf("expected empty diff, but got: %+v", diff)
+		}
+	})
+
+	t.Run("DiffACL() should keep common entries in order", func(t *testing.T) {
+		actual := []ns.ACE{aceOwner, aceGroup, aceEveryone}
+		desired := []ns.ACE{aceGroup, aceUser, aceOwner, aceEveryone}
+
+		diff := ns.DiffACL(actual, desired)
+		if len(diff.Remove) != 1 || !diff.Remove[0].Equal(aceOwner) || diff.Remove[0].Index != 0 {
+			t.Errorf("expected owner@ entry 0 to be removed, but got: %+v", diff.Remove)
+		}
+		if len(diff.Add) != 2 || !diff.Add[0].Equal(aceUser) || diff.Add[0].Index != 1 ||
+			!diff.Add[1].Equal(aceOwner) || diff.Add[1].Index != 2 {
+			t.Errorf("expected user and owner@ entries to be inserted at 1 and 2, but got: %+v", diff.Add)
+		}
+	})
+
+	t.Run("DiffACL() should handle empty ACLs", func(t *testing.T) {
+		diff := ns.DiffACL(nil, []ns.ACE{aceOwner})
+		if len(diff.Remove) != 0 || len(diff.Add) != 1 || diff.Add[0].Index != 0 {
+			t.Errorf("expected one entry to be added, but got: %+v", diff)
+		}
+
+		diff = ns.DiffACL([]ns.ACE{aceOwner, aceUser}, nil)
+		if len(diff.Remove) != 2 || len(diff.Add) != 0 || diff.Remove[1].Index != 1 {
+			t.Errorf("expected all entries to be removed, but got: %+v", diff)
+		}
+	})
+}
+
+func TestProvider_FilesystemACL(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	path := dataset + "/fs"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	if err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path}); err != nil {
+		t.Fatal(err)
+	}
+
+	aceGroupOwner := ns.ACE{
+		Type:        ns.ACETypeAllow,
+		Principal:   ns.ACLPrincipalGroup,
+		Permissions: []ns.ACLPermission{ns.ACLPermissionReadSet},
+	}
+
+	t.Run("GetFilesystemACL() should return default ACL", func(t *testing.T) {
+		acl, err := nsp.GetFilesystemACL(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkACL(t, acl, []ns.ACE{aceOwner, aceGroupOwner, aceEveryone})
+
+		if _, err := nsp.GetFilesystemACL(ctx, dataset+"/missing"); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+	})
+
+	t.Run("Add, insert, replace and remove ACL entries", func(t *testing.T) {
+		if err := nsp.AddFilesystemACE(ctx, path, aceUser); err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.InsertFilesystemACE(ctx, path, 0, aceGroup); err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.RemoveFilesystemACE(ctx, path, 2); err != nil {
+			t.Fatal(err)
+		}
+		replaced := aceEveryone
+		replaced.Type = ns.ACETypeDeny
+		if err := nsp.ReplaceFilesystemACE(ctx, path, 2, replaced); err != nil {
+			t.Fatal(err)
+		}
+
+		acl, err := nsp.GetFilesystemACL(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkACL(t, acl, []ns.ACE{aceGroup, aceOwner, replaced, aceUser})
+
+		if err := nsp.RemoveFilesystemACE(ctx, path, 10); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error for missing entry, but got: %v", err)
+		}
+	})
+
+	t.Run("ACL entries should be validated before sending request", func(t *testing.T) {
+		invalidEntries := map[string]ns.ACE{
+			"type":       {Type: "audit", Principal: ns.ACLPrincipalOwner, Permissions: aceOwner.Permissions},
+			"principal":  {Type: ns.ACETypeAllow, Principal: "alice", Permissions: aceOwner.Permissions},
+			"user":       {Type: ns.ACETypeAllow, Principal: ns.ACLUser(""), Permissions: aceOwner.Permissions},
+			"empty":      {Type: ns.ACETypeAllow, Principal: ns.ACLPrincipalOwner},
+			"permission": {Type: ns.ACETypeAllow, Principal: ns.ACLPrincipalOwner, Permissions: []ns.ACLPermission{"rw"}},
+			"flag": {
+				Type:        ns.ACETypeAllow,
+				Principal:   ns.ACLPrincipalOwner,
+				Permissions: aceOwner.Permissions,
+				Flags:       []ns.ACLFlag{"recursive"},
+			},
+		}
+		requests := server.CountRequests(http.MethodPost, "storage/filesystems/"+path+"/acl")
+		for name, ace := range invalidEntries {
+			if err := nsp.AddFilesystemACE(ctx, path, ace); !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("%s: expected EBADARG error, but got: %v", name, err)
+			}
+			if err := nsp.ReplaceFilesystemACE(ctx, path, 0, ace); !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("%s: expected EBADARG error on replace, but got: %v", name, err)
+			}
+		}
+		if err := nsp.InsertFilesystemACE(ctx, path, -1, aceOwner); !errors.Is(err, ns.ErrBadArg) {
+			t.Errorf("expected EBADARG error for negative index, but got: %v", err)
+		}
+		if count := server.CountRequests(http.MethodPost, "storage/filesystems/"+path+"/acl"); count != requests {
+			t.Errorf("expected invalid entries to be rejected w/o requests, but got %d requests", count-requests)
+		}
+	})
+
+	t.Run("ReconcileFilesystemACL() should change ACL to match desired one", func(t *testing.T) {
+		desired := []ns.ACE{aceOwner, aceUser, aceGroup, aceEveryone}
+
+		diff, err := nsp.ReconcileFilesystemACL(ctx, path, desired)
+		if err != nil {
+			t.Fatal(err)
+		} else if diff.IsEmpty() {
+			t.Fatal("expected ACL to be changed")
+		}
+
+		acl, err := nsp.GetFilesystemACL(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkACL(t, acl, desired)
+
+		diff, err = nsp.ReconcileFilesystemACL(ctx, path, desired)
+		if err != nil {
+			t.Fatal(err)
+		} else if !diff.IsEmpty() {
+			t.Errorf("expected no changes for the second run, but got: %+v", diff)
+		}
+	})
+
+	t.Run("ReconcileFilesystemACL() should replace changed entries and keep inherited ones", func(t *testing.T) {
+		aceInherited := ns.ACE{
+			Type:        ns.ACETypeAllow,
+			Principal:   ns.ACLGroup("staff"),
+			Permissions: []ns.ACLPermission{ns.ACLPermissionReadSet},
+			Flags:       []ns.ACLFlag{ns.ACLFlagInherited},
+		}
+		if err := nsp.InsertFilesystemACE(ctx, path, 1, aceInherited); err != nil {
+			t.Fatal(err)
+		}
+
+		changedUser := aceUser
+		changedUser.Permissions = []ns.ACLPermission{ns.ACLPermissionReadSet}
+		desired := []ns.ACE{aceOwner, changedUser, aceGroup, aceEveryone, aceGroupOwner}
+
+		aclPath := "storage/filesystems/" + path + "/acl"
+		replaces := server.CountRequests(http.MethodPut, aclPath+"/2")
+		removes := server.CountRequests(http.MethodDelete, aclPath+"/2")
+
+		diff, err := nsp.ReconcileFilesystemACL(ctx, path, desired)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(diff.Remove) != 1 || diff.Remove[0].Index != 2 || len(diff.Add) != 2 {
+			t.Errorf("expected user entry 2 to be removed and 2 entries to be added, but got: %+v", diff)
+		}
+
+		acl, err := nsp.GetFilesystemACL(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkACL(t, acl, []ns.ACE{aceOwner, aceInherited, changedUser, aceGroup, aceEveryone, aceGroupOwner})
+
+		if count := server.CountRequests(http.MethodPut, aclPath+"/2") - replaces; count != 1 {
+			t.Errorf("expected user entry to be replaced in place, but got %d requests", count)
+		}
+		if count := server.CountRequests(http.MethodDelete, aclPath+"/2") - removes; count != 0 {
+			t.Errorf("expected user entry not to be removed, but got %d requests", count)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package provider_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_Auth(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+
+	t.Run("concurrent requests with expired token should share one login", func(t *testing.T) {
+		nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+		defer server.Close()
+
+		if err := nsp.LogIn(ctx); err != nil {
+			t.Fatal(err)
+		}
+		server.ExpireTokens()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := nsp.GetFilesystem(ctx, dataset)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		if count := server.CountRequests(http.MethodPost, "auth/login"); count != 2 {
+			t.Errorf("expected 2 logins (initial and after token expiration), but got %d", count)
+		}
+	})
+
+	t.Run("cancelled request should not fail shared login of other requests", func(t *testing.T) {
+		server := nstest.NewServer(nstest.ServerArgs{Filesystems: []string{dataset}})
+		defer server.Close()
+
+		// slow down logins, so the second request joins the login started by the first one
+		serverURL, err := url.Parse(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proxy := httputil.NewSingleHostReverseProxy(serverURL)
+		slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "auth/login") {
+				time.Sleep(200 * time.Millisecond)
+			}
+			proxy.ServeHTTP(w, r)
+		}))
+		defer slowServer.Close()
+
+		nsp, err := ns.NewProvider(ns.ProviderArgs{
+			Address:  slowServer.URL,
+			Username: server.Username(),
+			Password: server.Password(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cancelledCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		cancelledErr := make(chan error, 1)
+		go func() {
+			_, err := nsp.GetFilesystem(cancelledCtx, dataset)
+			cancelledErr <- err
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		if _, err := nsp.GetFilesystem(ctx, dataset); err != nil {
+			t.Errorf("expected request to succeed after shared login, but got: %v", err)
+		}
+		if err := <-cancelledErr; err == nil {
+			t.Error("expected cancelled request to fail")
+		}
+		if count := server.CountRequests(http.MethodPost, "auth/login"); count != 1 {
+			t.Errorf("expected 1 shared login, but got %d", count)
+		}
+	})
+
+	t.Run("token should be refreshed before expiration", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, TokenTTL: time.Hour},
+			ns.ProviderArgs{TokenRefreshWindow: 2 * time.Hour},
+		)
+		defer server.Close()
+
+		if err := nsp.LogIn(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nsp.GetFilesystem(ctx, dataset); err != nil {
+			t.Fatal(err)
+		}
+
+		if count := server.CountRequests(http.MethodPost, "auth/login"); count != 2 {
+			t.Errorf("expected token refresh before the request, but got %d logins", count)
+		}
+		if count := server.CountRequests(http.MethodGet, "storage/filesystems"); count != 1 {
+			t.Errorf("expected 1 filesystem request w/o EAUTH errors, but got %d", count)
+		}
+	})
+
+	t.Run("token refresh can be disabled", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, TokenTTL: time.Hour},
+			ns.ProviderArgs{TokenRefreshWindow: -1},
+		)
+		defer server.Close()
+
+		if err := nsp.LogIn(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nsp.GetFilesystem(ctx, dataset); err != nil {
+			t.Fatal(err)
+		}
+
+		if count := server.CountRequests(http.MethodPost, "auth/login"); count != 1 {
+			t.Errorf("expected 1 login, but got %d", count)
+		}
+	})
+
+	t.Run("wrong password should fail with EAUTH w/o retries", func(t *testing.T) {
+		nsp, server := newTestProvider(t, nstest.ServerArgs{})
+		defer server.Close()
+		server.AddFault(nstest.Fault{Path: "auth/login", StatusCode: http.StatusUnauthorized, Code: ns.NefCodeAuth})
+
+		if err := nsp.LogIn(ctx); !ns.IsAuthNefError(err) {
+			t.Errorf("expected EAUTH error, but got: %v", err)
+		}
+	})
+}
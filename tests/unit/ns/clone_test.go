@@ -0,0 +1,152 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_CloneVolume(t *testing.T) {
+	ctx := context.Background()
+	volumeGroup := "testPool/testVolumeGroup"
+	source := volumeGroup + "/source"
+	size := int64(64 * 1024 * 1024)
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{
+		Filesystems:  []string{"testPool/testDataset"},
+		VolumeGroups: []string{volumeGroup},
+	})
+	defer server.Close()
+
+	err := nsp.CreateVolume(ctx, ns.CreateVolumeParams{Path: source, VolumeSize: size, VolumeBlockSize: 16 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: source + "@snap"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("CloneVolumeSnapshot() should create a volume with source size and provided properties", func(t *testing.T) {
+		clone := volumeGroup + "/clone1"
+		err := nsp.CloneVolumeSnapshot(ctx, source+"@snap", ns.CloneVolumeSnapshotParams{
+			TargetPath:      clone,
+			CompressionMode: ns.CompressionModeOff,
+			UserProperties:  map[string]string{"csi:source": source},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		volume, err := nsp.GetVolume(ctx, clone)
+		if err != nil {
+			t.Fatal(err)
+		} else if volume.VolumeSize != size || volume.VolumeBlockSize != 16*1024 {
+			t.Errorf("clone should inherit size and block size of the source, but got: %+v", volume)
+		} else if volume.CompressionMode != ns.CompressionModeOff || volume.UserProperties["csi:source"] != source {
+			t.Errorf("clone properties were not set: %+v", volume)
+		}
+	})
+
+	t.Run("CloneVolumeSnapshot() should expand the clone to the provided size", func(t *testing.T) {
+		clone := volumeGroup + "/clone2"
+		err := nsp.CloneVolumeSnapshot(ctx, source+"@snap", ns.CloneVolumeSnapshotParams{
+			TargetPath: clone,
+			VolumeSize: 2 * size,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		volume, err := nsp.GetVolume(ctx, clone)
+		if err != nil {
+			t.Fatal(err)
+		} else if volume.VolumeSize != 2*size {
+			t.Errorf("expected volume size %d, but got: %d", 2*size, volume.VolumeSize)
+		}
+	})
+
+	t.Run("CloneVolumeSnapshot() should reject invalid size before cloning", func(t *testing.T) {
+		before := server.CountRequests(http.MethodPost, "storage/snapshots/"+source+"@snap/clone")
+
+		invalidSizes := []int64{size / 2, size + 512}
+		for _, volumeSize := range invalidSizes {
+			err := nsp.CloneVolumeSnapshot(ctx, source+"@snap", ns.CloneVolumeSnapshotParams{
+				TargetPath: volumeGroup + "/invalid",
+				VolumeSize: volumeSize,
+			})
+			if !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("expected EBADARG error for size %d, but got: %v", volumeSize, err)
+			}
+		}
+
+		if count := server.CountRequests(http.MethodPost, "storage/snapshots/"+source+"@snap/clone") - before; count != 0 {
+			t.Errorf("expected no clone requests, but got %d", count)
+		}
+	})
+
+	t.Run("CloneVolumeSnapshot() should fail for filesystem snapshot", func(t *testing.T) {
+		if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: "testPool/testDataset@snap"}); err != nil {
+			t.Fatal(err)
+		}
+		err := nsp.CloneVolumeSnapshot(ctx, "testPool/testDataset@snap", ns.CloneVolumeSnapshotParams{
+			TargetPath: volumeGroup + "/invalid",
+		})
+		if !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+	})
+
+	t.Run("CloneVolume() should clone volume via implicit snapshot and reuse it on retry", func(t *testing.T) {
+		clone := volumeGroup + "/clone3"
+		params := ns.CloneVolumeSnapshotParams{TargetPath: clone}
+		if err := nsp.CloneVolume(ctx, source, params); err != nil {
+			t.Fatal(err)
+		}
+
+		snapshot, err := nsp.GetSnapshot(ctx, source+"@clone-clone3")
+		if err != nil {
+			t.Fatal(err)
+		} else if len(snapshot.Clones) != 1 || snapshot.Clones[0] != clone {
+			t.Errorf("expected implicit snapshot to have clone '%s', but got: %+v", clone, snapshot)
+		}
+
+		if err := nsp.CloneVolume(ctx, source, params); !errors.Is(err, ns.ErrAlreadyExist) {
+			t.Errorf("expected EEXIST error on the existing clone, but got: %v", err)
+		}
+		if _, err := nsp.GetSnapshot(ctx, source+"@clone-clone3"); err != nil {
+			t.Errorf("implicit snapshot of the existing clone should be kept, but got: %v", err)
+		}
+	})
+
+	t.Run("CloneVolume() should destroy implicit snapshot if clone fails", func(t *testing.T) {
+		err := nsp.CloneVolume(ctx, source, ns.CloneVolumeSnapshotParams{TargetPath: "testPool/missing/clone4"})
+		if !errors.Is(err, ns.ErrNotExist) {
+			t.Fatalf("expected ENOENT error, but got: %v", err)
+		}
+		if _, err := nsp.GetSnapshot(ctx, source+"@clone-clone4"); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("implicit snapshot should be destroyed, but got: %v", err)
+		}
+	})
+
+	t.Run("CloneVolume() should return both clone and cleanup errors", func(t *testing.T) {
+		server.AddFault(nstest.Fault{
+			Method: http.MethodDelete,
+			Path:   "storage/snapshots/" + source + "@clone-clone5",
+			Code:   ns.NefCodeBusy,
+			Count:  1,
+		})
+		defer server.ClearFaults()
+
+		err := nsp.CloneVolume(ctx, source, ns.CloneVolumeSnapshotParams{TargetPath: "testPool/missing/clone5"})
+		if !errors.Is(err, ns.ErrNotExist) {
+			t.Fatalf("expected ENOENT error of the clone, but got: %v", err)
+		} else if !strings.Contains(err.Error(), "failed to destroy implicit snapshot") {
+			t.Errorf("expected error to include cleanup error, but got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package provider_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_Iterators(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	count := 25
+
+	filesystems := []string{dataset}
+	for i := 0; i < count; i++ {
+		filesystems = append(filesystems, fmt.Sprintf("%s/fs%02d", dataset, i))
+	}
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: filesystems})
+	defer server.Close()
+
+	for i := 0; i < count; i++ {
+		err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: fmt.Sprintf("%s@snap%02d", dataset, i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("IterateFilesystems() should load all pages", func(t *testing.T) {
+		requestsBefore := server.CountRequests(http.MethodGet, "storage/filesystems")
+
+		paths := []string{}
+		it := nsp.IterateFilesystems(ctx, dataset, ns.IteratorParams{PageSize: 10})
+		for it.Next() {
+			paths = append(paths, it.Filesystem().Path)
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(paths) != count {
+			t.Errorf("expected %d filesystems, but got %d: %v", count, len(paths), paths)
+		} else if paths[0] != filesystems[1] || paths[count-1] != filesystems[count] {
+			t.Errorf("unexpected filesystems order: %v", paths)
+		}
+		if requests := server.CountRequests(http.MethodGet, "storage/filesystems") - requestsBefore; requests != 3 {
+			t.Errorf("expected 3 page requests, but got %d", requests)
+		}
+	})
+
+	t.Run("IterateFilesystems() should load pages lazily", func(t *testing.T) {
+		requestsBefore := server.CountRequests(http.MethodGet, "storage/filesystems")
+
+		it := nsp.IterateFilesystems(ctx, dataset, ns.IteratorParams{PageSize: 10})
+		for i := 0; i < 5 && it.Next(); i++ {
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		if requests := server.CountRequests(http.MethodGet, "storage/filesystems") - requestsBefore; requests != 1 {
+			t.Errorf("expected 1 page request, but got %d", requests)
+		}
+	})
+
+	t.Run("IterateFilesystems() should start after starting token", func(t *testing.T) {
+		it := nsp.IterateFilesystems(ctx, dataset, ns.IteratorParams{PageSize: 7, StartingToken: filesystems[10]})
+		if !it.Next() {
+			t.Fatalf("expected next filesystem, error: %v", it.Err())
+		} else if it.Filesystem().Path != filesystems[11] {
+			t.Errorf("expected '%s' filesystem, but got: %+v", filesystems[11], it.Filesystem())
+		} else if it.NextToken() != filesystems[11] {
+			t.Errorf("expected '%s' next token, but got '%s'", filesystems[11], it.NextToken())
+		}
+	})
+
+	t.Run("GetFilesystemsWithStartingToken() should return next token", func(t *testing.T) {
+		list, nextToken, err := nsp.GetFilesystemsWithStartingToken(ctx, dataset, filesystems[5], 3)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(list) != 3 || list[0].Path != filesystems[6] {
+			t.Errorf("unexpected filesystems: %v", list)
+		} else if nextToken != filesystems[8] {
+			t.Errorf("expected '%s' next token, but got '%s'", filesystems[8], nextToken)
+		}
+	})
+
+	t.Run("IterateSnapshots() should load all pages", func(t *testing.T) {
+		snapshots := 0
+		it := nsp.IterateSnapshots(ctx, dataset, false, ns.IteratorParams{PageSize: 4})
+		for it.Next() {
+			snapshots++
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		} else if snapshots != count {
+			t.Errorf("expected %d snapshots, but got %d", count, snapshots)
+		}
+	})
+
+	t.Run("iterator should handle missing parent and invalid params", func(t *testing.T) {
+		it := nsp.IterateFilesystems(ctx, "testPool/notExists", ns.IteratorParams{})
+		if it.Next() {
+			t.Errorf("expected no filesystems, but got: %+v", it.Filesystem())
+		}
+
+		it = nsp.IterateFilesystems(ctx, dataset, ns.IteratorParams{PageSize: -1})
+		if it.Next() || it.Err() == nil {
+			t.Error("expected an error for negative page size")
+		}
+	})
+}
+
+func TestProvider_IteratorsPageSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	count := 150
+
+	filesystems := []string{dataset}
+	for i := 0; i < count; i++ {
+		filesystems = append(filesystems, fmt.Sprintf("%s/fs%03d", dataset, i))
+	}
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: filesystems})
+	defer server.Close()
+
+	t.Run("IterateFilesystems() should not stop on NS page size limit", func(t *testing.T) {
+		paths := []string{}
+		it := nsp.IterateFilesystems(ctx, dataset, ns.IteratorParams{PageSize: 500})
+		for it.Next() {
+			paths = append(paths, it.Filesystem().Path)
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		} else if len(paths) != count {
+			t.Errorf("expected %d filesystems, but got %d", count, len(paths))
+		}
+	})
+
+	t.Run("GetFilesystems() should return all filesystems", func(t *testing.T) {
+		list, err := nsp.GetFilesystems(ctx, dataset)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(list) != count {
+			t.Errorf("expected %d filesystems, but got %d", count, len(list))
+		}
+	})
+}
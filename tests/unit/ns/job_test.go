@@ -0,0 +1,269 @@
+package provider_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_Job(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	providerArgs := ns.ProviderArgs{JobPollInterval: time.Millisecond}
+
+	t.Run("StartJob() should return a handle of running job", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, AsyncJobs: true, JobPolls: 3},
+			providerArgs,
+		)
+		defer server.Close()
+
+		path := dataset + "/fs"
+		job, err := nsp.StartJob(ctx, func(ctx context.Context) error {
+			return nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path})
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if job.ID == "" {
+			t.Fatalf("expected running job, but got: %s", job)
+		} else if count := server.CountRequests(http.MethodGet, "jobStatus/"+job.ID); count != 0 {
+			t.Errorf("job status should not be requested in non-blocking mode, but got %d requests", count)
+		}
+
+		status, err := job.Status(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if status.State != ns.JobStateRunning {
+			t.Errorf("expected '%s' job state, but got: %+v", ns.JobStateRunning, status)
+		} else if status.Description == "" {
+			t.Errorf("expected job description, but got: %+v", status)
+		}
+
+		progress, err := job.Progress(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if progress <= status.Progress || progress >= 100 {
+			t.Errorf("expected progress between %d and 100, but got %d", status.Progress, progress)
+		}
+
+		if err := job.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nsp.GetFilesystem(ctx, path); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("job status should be polled after token expiration", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, AsyncJobs: true, JobPolls: 3},
+			providerArgs,
+		)
+		defer server.Close()
+
+		job, err := nsp.StartJob(ctx, func(ctx context.Context) error {
+			return nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: dataset + "/fs"})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		server.ExpireTokens()
+		if err := job.Wait(ctx); err != nil {
+			t.Fatalf("job should be waited with a new token, but got: %v", err)
+		} else if count := server.CountRequests(http.MethodPost, "auth/login"); count != 2 {
+			t.Errorf("expected login after token expiration, but got %d login requests", count)
+		}
+	})
+
+	t.Run("StartJob() should capture one job of concurrent calls", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, AsyncJobs: true, JobPolls: 3},
+			providerArgs,
+		)
+		defer server.Close()
+
+		paths := []string{dataset + "/fs1", dataset + "/fs2", dataset + "/fs3"}
+		job, err := nsp.StartJob(ctx, func(ctx context.Context) error {
+			errs := make(chan error, len(paths))
+			for _, path := range paths {
+				go func(path string) {
+					errs <- nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path})
+				}(path)
+			}
+			for range paths {
+				if err := <-errs; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if job.ID == "" {
+			t.Fatalf("expected running job, but got: %s", job)
+		} else if err := job.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, path := range paths {
+			if _, err := nsp.GetFilesystem(ctx, path); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("StartJob() should return a handle of the final step of composite calls", func(t *testing.T) {
+		volumeGroup := "testPool/testVolumeGroup"
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{
+				Filesystems:  []string{dataset},
+				VolumeGroups: []string{volumeGroup},
+				AsyncJobs:    true,
+				JobPolls:     2,
+			},
+			providerArgs,
+		)
+		defer server.Close()
+
+		source := volumeGroup + "/source"
+		size := int64(64 * 1024 * 1024)
+		err := nsp.CreateVolume(ctx, ns.CreateVolumeParams{Path: source, VolumeSize: size, VolumeBlockSize: 16 * 1024})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		startJob := func(t *testing.T, fn func(ctx context.Context) error, method, pathPrefix string) {
+			t.Helper()
+			job, err := nsp.StartJob(ctx, fn)
+			if err != nil {
+				t.Fatal(err)
+			} else if job.ID == "" {
+				t.Fatalf("expected running job, but got: %s", job)
+			} else if job.Method != method || !strings.HasPrefix(job.Path, pathPrefix) {
+				t.Errorf("expected job of '%s %s...' request, but got: %s %s", method, pathPrefix, job.Method, job.Path)
+			}
+			if err := job.Wait(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// implicit snapshot is created before the clone
+		startJob(t, func(ctx context.Context) error {
+			return nsp.CloneVolume(ctx, source, ns.CloneVolumeSnapshotParams{TargetPath: volumeGroup + "/clone1"})
+		}, http.MethodPost, "storage/snapshots/")
+
+		// the clone is resized after its creation
+		startJob(t, func(ctx context.Context) error {
+			return nsp.CloneVolume(ctx, source, ns.CloneVolumeSnapshotParams{
+				TargetPath: volumeGroup + "/clone2",
+				VolumeSize: 2 * size,
+			})
+		}, http.MethodPut, "storage/volumes/")
+		if volume, err := nsp.GetVolume(ctx, volumeGroup+"/clone2"); err != nil {
+			t.Fatal(err)
+		} else if volume.VolumeSize != 2*size {
+			t.Errorf("expected clone to be resized to %d, but got: %d", 2*size, volume.VolumeSize)
+		}
+
+		// the clone is promoted before the filesystem is destroyed
+		path := dataset + "/fs"
+		if err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path}); err != nil {
+			t.Fatal(err)
+		} else if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: path + "@snap"}); err != nil {
+			t.Fatal(err)
+		}
+		err = nsp.CloneSnapshot(ctx, path+"@snap", ns.CloneSnapshotParams{TargetPath: dataset + "/fsClone"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		startJob(t, func(ctx context.Context) error {
+			return nsp.DestroyFilesystem(ctx, path, ns.DestroyFilesystemParams{
+				DestroySnapshots:               true,
+				PromoteMostRecentCloneIfExists: true,
+			})
+		}, http.MethodDelete, "storage/filesystems/")
+		if _, err := nsp.GetFilesystem(ctx, path); !ns.IsNotExistNefError(err) {
+			t.Errorf("expected filesystem to be destroyed, but got: %v", err)
+		}
+	})
+
+	t.Run("StartJob() should return completed job for synchronous requests", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(t, nstest.ServerArgs{Filesystems: []string{dataset}}, providerArgs)
+		defer server.Close()
+
+		job, err := nsp.StartJob(ctx, func(ctx context.Context) error {
+			return nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: dataset + "/fs"})
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if status, err := job.Status(ctx); err != nil || status.State != ns.JobStateDone {
+			t.Errorf("expected completed job, but got: %+v, %v", status, err)
+		} else if err := job.Wait(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("cancelled job should fail with NefError", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, AsyncJobs: true, JobPolls: 10},
+			providerArgs,
+		)
+		defer server.Close()
+
+		job, err := nsp.StartJob(ctx, func(ctx context.Context) error {
+			return nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: dataset + "/fs"})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := job.Cancel(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := job.Wait(ctx); ns.GetNefErrorCode(err) != "ECANCELED" {
+			t.Errorf("expected ECANCELED error, but got: %v", err)
+		}
+		if status, err := job.Status(ctx); err != nil || status.State != ns.JobStateFailed || status.Err == nil {
+			t.Errorf("expected failed job status, but got: %+v, %v", status, err)
+		}
+	})
+
+	t.Run("job timeout should be returned as NefError", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, AsyncJobs: true, JobPolls: 1000},
+			ns.ProviderArgs{JobPollInterval: time.Millisecond, JobTimeout: 20 * time.Millisecond},
+		)
+		defer server.Close()
+
+		err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: dataset + "/fs"})
+		if ns.GetNefErrorCode(err) != "ETIMEDOUT" {
+			t.Errorf("expected ETIMEDOUT error, but got: %v", err)
+		}
+	})
+
+	t.Run("failed job should be returned as NefError", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, AsyncJobs: true, JobPolls: 2},
+			providerArgs,
+		)
+		defer server.Close()
+
+		err := nsp.DestroyFilesystem(ctx, dataset+"/notExists", ns.DestroyFilesystemParams{})
+		if !ns.IsNotExistNefError(err) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestNefError(t *testing.T) {
+	t.Run("errors.Is() should match wrapped errors by code", func(t *testing.T) {
+		err := fmt.Errorf("cannot create volume: %w", &ns.NefError{Code: ns.NefCodeAlreadyExist})
+
+		if !errors.Is(err, ns.ErrAlreadyExist) {
+			t.Error("wrapped EEXIST error should match ns.ErrAlreadyExist")
+		}
+		if errors.Is(err, ns.ErrNotExist) {
+			t.Error("wrapped EEXIST error should not match ns.ErrNotExist")
+		}
+		if !ns.IsAlreadyExistNefError(err) || !ns.IsNefError(err) {
+			t.Error("helpers should unwrap errors")
+		}
+		if code := ns.GetNefErrorCode(err); code != ns.NefCodeAlreadyExist {
+			t.Errorf("expected '%s' code, but got '%s'", ns.NefCodeAlreadyExist, code)
+		}
+	})
+
+	t.Run("errors.Is() should match underlying error", func(t *testing.T) {
+		err := &ns.NefError{Code: ns.NefCodeTimeout, Err: context.DeadlineExceeded}
+		if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ns.ErrTimeout) {
+			t.Errorf("error should match both underlying error and its code: %s", err)
+		}
+	})
+
+	t.Run("provider errors should contain request details", func(t *testing.T) {
+		nsp, server := newTestProvider(t, nstest.ServerArgs{})
+		defer server.Close()
+
+		err := nsp.DestroySnapshot(context.Background(), "testPool/notExists@snap")
+
+		var nefErr *ns.NefError
+		if !errors.As(err, &nefErr) {
+			t.Fatalf("expected NefError, but got: %v", err)
+		}
+		if nefErr.Code != ns.NefCodeNotExist || nefErr.StatusCode != http.StatusNotFound {
+			t.Errorf("expected ENOENT code and 404 status, but got: %+v", nefErr)
+		}
+		if nefErr.Method != http.MethodDelete || !strings.HasPrefix(nefErr.Path, "storage/snapshots/") {
+			t.Errorf("expected DELETE request to snapshots, but got: %s %s", nefErr.Method, nefErr.Path)
+		}
+		if nefErr.Name == "" || nefErr.Message == "" {
+			t.Errorf("expected NEF error name and message, but got: %+v", nefErr)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestNfsRules_Constructors(t *testing.T) {
+	rule, err := ns.NewNfsNetworkRule("10.1.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ns.NfsRuleList{Etype: ns.NfsRuleEtypeNetwork, Entity: "10.1.0.0", Mask: 16}
+	if rule != expected {
+		t.Errorf("expected rule %+v, but got: %+v", expected, rule)
+	} else if rule.String() != "10.1.0.0/16" {
+		t.Errorf("expected '10.1.0.0/16' string, but got: '%s'", rule.String())
+	}
+
+	for _, cidr := range []string{"10.1.0.1/16", "10.1.0.0", "10.1.0.0/33", "fd00::1/64"} {
+		if _, err := ns.NewNfsNetworkRule(cidr); !errors.Is(err, ns.ErrBadArg) {
+			t.Errorf("expected EBADARG error for '%s', but got: %v", cidr, err)
+		}
+	}
+
+	rules := map[string]ns.NfsRuleList{
+		"admin.example.com": ns.NewNfsHostRule("admin.example.com"),
+		".example.com":      ns.NewNfsDomainRule(".example.com"),
+		"@admins":           ns.NewNfsNetgroupRule("admins"),
+	}
+	for s, rule := range rules {
+		if rule.String() != s {
+			t.Errorf("expected '%s' string, but got: '%s' for %+v", s, rule.String(), rule)
+		}
+	}
+	if rule := ns.NewNfsDomainRule("example.com"); rule.Entity != "example.com" {
+		t.Errorf("expected domain 'example.com', but got: %+v", rule)
+	}
+}
+
+func TestNfsRules_Parse(t *testing.T) {
+	t.Run("ParseNfsRules() should parse rules of all types", func(t *testing.T) {
+		securityContext, err := ns.ParseNfsRules(
+			"sec=krb5:krb5i, rw=10.0.0.0/8,ro=@netgroup,ro=.example.com,root=admin.example.com,none=10.0.0.13",
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := ns.NfsSecurityContext{
+			SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeKrb5, ns.NfsSecurityModeKrb5i},
+			ReadWriteList: []ns.NfsRuleList{{Etype: "network", Entity: "10.0.0.0", Mask: 8}},
+			ReadOnlyList: []ns.NfsRuleList{
+				{Etype: "netgroup", Entity: "netgroup"},
+				{Etype: "domain", Entity: "example.com"},
+			},
+			RootList: []ns.NfsRuleList{{Etype: "fqdn", Entity: "admin.example.com"}},
+			NoneList: []ns.NfsRuleList{{Etype: "fqdn", Entity: "10.0.0.13"}},
+		}
+		if !reflect.DeepEqual(securityContext, expected) {
+			t.Errorf("expected security context:\n%+v\nbut got:\n%+v", expected, securityContext)
+		}
+	})
+
+	t.Run("ParseNfsRules() should use 'sys' security mode by default", func(t *testing.T) {
+		securityContext, err := ns.ParseNfsRules("rw=*")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := ns.NfsSecurityContext{
+			SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeSys},
+			ReadWriteList: []ns.NfsRuleList{{Etype: "fqdn", Entity: "*"}},
+			ReadOnlyList:  []ns.NfsRuleList{},
+		}
+		if !reflect.DeepEqual(securityContext, expected) {
+			t.Errorf("expected security context:\n%+v\nbut got:\n%+v", expected, securityContext)
+		}
+	})
+
+	t.Run("ParseNfsRules() should return EBADARG error for invalid rules", func(t *testing.T) {
+		invalidRules := []string{
+			"rw",
+			"rw=",
+			"admin=10.0.0.1",
+			"sec=krb4",
+			"rw=10.0.0.1/8",
+			"ro=bad host",
+			"ro=@bad,group",
+			"ro=@",
+			"root=.",
+		}
+		for _, s := range invalidRules {
+			if _, err := ns.ParseNfsRules(s); !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("expected EBADARG error for '%s', but got: %v", s, err)
+			}
+		}
+	})
+}
+
+func TestProvider_CreateNfsShareRules(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	path := dataset + "/fs"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	if err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("CreateNfsShare() should validate rules before sending request", func(t *testing.T) {
+		invalidRules := map[string]ns.NfsRuleList{
+			"host":           {Etype: "fqdn", Entity: "bad host"},
+			"host with mask": {Etype: "fqdn", Entity: "10.0.0.1", Mask: 8},
+			"network mask":   {Etype: "network", Entity: "10.0.0.0", Mask: 40},
+			"network bits":   {Etype: "network", Entity: "10.0.0.1", Mask: 8},
+			"domain":         {Etype: "domain", Entity: "-example.com"},
+			"netgroup":       {Etype: "netgroup", Entity: "bad group"},
+			"type":           {Etype: "ip", Entity: "10.0.0.1"},
+		}
+		for name, rule := range invalidRules {
+			err := nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{
+				Filesystem:    path,
+				ReadWriteList: []ns.NfsRuleList{rule},
+			})
+			if !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("%s: expected EBADARG error, but got: %v", name, err)
+			}
+
+			err = nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{
+				Filesystem: path,
+				SecurityContexts: []ns.NfsSecurityContext{{
+					SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeSys},
+					NoneList:      []ns.NfsRuleList{rule},
+				}},
+			})
+			if !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("%s: expected EBADARG error for security context, but got: %v", name, err)
+			}
+		}
+
+		if count := server.CountRequests(http.MethodPost, "nas/nfs"); count != 0 {
+			t.Errorf("expected no requests for invalid rules, but got %d", count)
+		}
+	})
+
+	t.Run("CreateNfsShare() should accept parsed rules", func(t *testing.T) {
+		securityContext, err := ns.ParseNfsRules("rw=10.0.0.0/8,ro=@netgroup,root=admin.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{
+			Filesystem:       path,
+			SecurityContexts: []ns.NfsSecurityContext{securityContext},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		share, err := nsp.GetNfsShare(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(share.SecurityContexts, []ns.NfsSecurityContext{securityContext}) {
+			t.Errorf("expected security context:\n%+v\nbut got:\n%+v", securityContext, share.SecurityContexts)
+		}
+	})
+
+	t.Run("CreateNfsShare() should send rules w/o Etype as is", func(t *testing.T) {
+		if err := nsp.DeleteNfsShare(ctx, path); err != nil {
+			t.Fatal(err)
+		}
+
+		rules := []ns.NfsRuleList{{Entity: "*"}}
+		err := nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{Filesystem: path, ReadWriteList: rules})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		share, err := nsp.GetNfsShare(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(share.SecurityContexts) != 1 || !reflect.DeepEqual(share.SecurityContexts[0].ReadWriteList, rules) {
+			t.Errorf("expected read-write list %+v, but got: %+v", rules, share.SecurityContexts)
+		}
+	})
+}
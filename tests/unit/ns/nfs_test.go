@@ -0,0 +1,207 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_NfsShares(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	path := dataset + "/fs"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	for _, p := range []string{path, path + "/child", dataset + "/other"} {
+		if err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: p}); err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{Filesystem: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("GetNfsShare() should return share with default rules", func(t *testing.T) {
+		share, err := nsp.GetNfsShare(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := ns.NfsShare{
+			Filesystem: path,
+			MountPoint: "/" + path,
+			ShareState: "online",
+			Anon:       "root",
+			SecurityContexts: []ns.NfsSecurityContext{
+				{
+					SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeSys},
+					ReadWriteList: []ns.NfsRuleList{{Etype: "fqdn", Entity: "*"}},
+					ReadOnlyList:  []ns.NfsRuleList{{Etype: "fqdn", Entity: "none"}},
+				},
+			},
+		}
+		if !reflect.DeepEqual(share, expected) {
+			t.Errorf("expected share:\n%+v\nbut got:\n%+v", expected, share)
+		}
+
+		if _, err := nsp.GetNfsShare(ctx, dataset); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+	})
+
+	t.Run("ListNfsShares() should return shares of the parent and its descendants", func(t *testing.T) {
+		shares, err := nsp.ListNfsShares(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(shares) != 2 || shares[0].Filesystem != path || shares[1].Filesystem != path+"/child" {
+			t.Errorf("expected shares of '%s' and its child, but got: %+v", path, shares)
+		}
+
+		shares, err = nsp.ListNfsShares(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		} else if len(shares) != 3 {
+			t.Errorf("expected 3 shares, but got: %+v", shares)
+		}
+	})
+
+	t.Run("UpdateNfsShare() should change rules in place", func(t *testing.T) {
+		contexts := []ns.NfsSecurityContext{
+			{
+				SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeSys},
+				ReadWriteList: []ns.NfsRuleList{{Etype: "network", Entity: "10.0.0.0", Mask: 8}},
+				ReadOnlyList:  []ns.NfsRuleList{{Etype: "fqdn", Entity: "backup.example.com"}},
+				RootList:      []ns.NfsRuleList{{Etype: "fqdn", Entity: "admin.example.com"}},
+			},
+		}
+		err := nsp.UpdateNfsShare(ctx, path, ns.UpdateNfsShareParams{
+			RootMapping:      "nobody",
+			SecurityContexts: contexts,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		share, err := nsp.GetNfsShare(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(share.SecurityContexts, contexts) {
+			t.Errorf("expected security contexts:\n%+v\nbut got:\n%+v", contexts, share.SecurityContexts)
+		} else if share.Anon != "root" || share.RootMapping != "nobody" {
+			t.Errorf("expected anon 'root' and root mapping 'nobody', but got: %+v", share)
+		}
+
+		err = nsp.UpdateNfsShare(ctx, path, ns.UpdateNfsShareParams{
+			SecurityContexts: []ns.NfsSecurityContext{{ReadWriteList: contexts[0].ReadWriteList}},
+		})
+		if !errors.Is(err, ns.ErrBadArg) {
+			t.Errorf("expected EBADARG error for context w/o security modes, but got: %v", err)
+		}
+	})
+}
+
+func TestProvider_CreateNfsShareOptions(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	for _, p := range []string{"/krb", "/ro", "/invalid"} {
+		if err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: dataset + p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("CreateNfsShare() should set security contexts and options", func(t *testing.T) {
+		contexts := []ns.NfsSecurityContext{
+			{
+				SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeKrb5p},
+				ReadWriteList: []ns.NfsRuleList{{Etype: "domain", Entity: "example.com"}},
+				ReadOnlyList:  []ns.NfsRuleList{},
+			},
+			{
+				SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeKrb5, ns.NfsSecurityModeKrb5i},
+				ReadWriteList: []ns.NfsRuleList{},
+				ReadOnlyList:  []ns.NfsRuleList{{Etype: "fqdn", Entity: "*"}},
+			},
+		}
+		err := nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{
+			Filesystem:       dataset + "/krb",
+			SecurityContexts: contexts,
+			Anon:             "nobody",
+			RootMapping:      "nobody",
+			Nosuid:           true,
+			Nohide:           true,
+			Versions:         []ns.NfsVersion{ns.NfsVersion4},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		share, err := nsp.GetNfsShare(ctx, dataset+"/krb")
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(share.SecurityContexts, contexts) {
+			t.Errorf("expected security contexts:\n%+v\nbut got:\n%+v", contexts, share.SecurityContexts)
+		} else if share.Anon != "nobody" || share.RootMapping != "nobody" || !share.Nosuid || !share.Nohide {
+			t.Errorf("share options were not set: %+v", share)
+		} else if !reflect.DeepEqual(share.Versions, []ns.NfsVersion{ns.NfsVersion4}) {
+			t.Errorf("expected NFSv4 only, but got: %v", share.Versions)
+		}
+	})
+
+	t.Run("CreateNfsShare() should keep default rules for the only security context", func(t *testing.T) {
+		err := nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{
+			Filesystem:    dataset + "/ro",
+			ReadOnlyList:  []ns.NfsRuleList{{Etype: "fqdn", Entity: "*"}},
+			RootList:      []ns.NfsRuleList{{Etype: "fqdn", Entity: "admin.example.com"}},
+			SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeSys, ns.NfsSecurityModeKrb5},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		share, err := nsp.GetNfsShare(ctx, dataset+"/ro")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []ns.NfsSecurityContext{
+			{
+				SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeSys, ns.NfsSecurityModeKrb5},
+				ReadWriteList: []ns.NfsRuleList{{Etype: "fqdn", Entity: "none"}},
+				ReadOnlyList:  []ns.NfsRuleList{{Etype: "fqdn", Entity: "*"}},
+				RootList:      []ns.NfsRuleList{{Etype: "fqdn", Entity: "admin.example.com"}},
+			},
+		}
+		if !reflect.DeepEqual(share.SecurityContexts, expected) {
+			t.Errorf("expected security contexts:\n%+v\nbut got:\n%+v", expected, share.SecurityContexts)
+		} else if share.Anon != "root" {
+			t.Errorf("expected default anon user 'root', but got: '%s'", share.Anon)
+		}
+	})
+
+	t.Run("CreateNfsShare() should validate params", func(t *testing.T) {
+		path := dataset + "/invalid"
+		krb5 := ns.NfsSecurityContext{SecurityModes: []ns.NfsSecurityMode{ns.NfsSecurityModeKrb5}}
+
+		invalidParams := map[string]ns.CreateNfsShareParams{
+			"security mode": {Filesystem: path, SecurityModes: []ns.NfsSecurityMode{"krb4"}},
+			"empty modes":   {Filesystem: path, SecurityContexts: []ns.NfsSecurityContext{{}}},
+			"duplicate":     {Filesystem: path, SecurityContexts: []ns.NfsSecurityContext{krb5, krb5}},
+			"mixed":         {Filesystem: path, SecurityContexts: []ns.NfsSecurityContext{krb5}, SecurityModes: krb5.SecurityModes},
+			"version":       {Filesystem: path, Versions: []ns.NfsVersion{"2"}},
+		}
+		for name, params := range invalidParams {
+			if err := nsp.CreateNfsShare(ctx, params); !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("%s: expected EBADARG error, but got: %v", name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package provider_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func newTestProvider(t *testing.T, args nstest.ServerArgs) (ns.ProviderInterface, *nstest.Server) {
+	return newTestProviderWithArgs(t, args, ns.ProviderArgs{})
+}
+
+// newTestProviderWithArgs creates provider for fake server, address and credentials are set from the server
+func newTestProviderWithArgs(t *testing.T, args nstest.ServerArgs, providerArgs ns.ProviderArgs) (
+	ns.ProviderInterface,
+	*nstest.Server,
+) {
+	server := nstest.NewServer(args)
+
+	l := logrus.New().WithField("ns", server.URL)
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	providerArgs.Address = server.URL
+	providerArgs.Username = server.Username()
+	providerArgs.Password = server.Password()
+	providerArgs.Log = logger.NewLogrus(l)
+
+	nsp, err := ns.NewProvider(providerArgs)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	return nsp, server
+}
+
+func TestNsTest_Server(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+
+	t.Run("provider should log in and get NEF errors from the fake server", func(t *testing.T) {
+		nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+		defer server.Close()
+
+		err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: dataset})
+		if !ns.IsAlreadyExistNefError(err) {
+			t.Errorf("expected EEXIST error, but got: %v", err)
+		}
+		if count := server.CountRequests(http.MethodPost, "auth/login"); count != 1 {
+			t.Errorf("expected 1 login request, but got %d", count)
+		}
+
+		_, err = nsp.GetFilesystem(ctx, "testPool/notExists")
+		if !ns.IsNotExistNefError(err) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+	})
+
+	t.Run("provider should log in again when token is expired", func(t *testing.T) {
+		nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+		defer server.Close()
+
+		if _, err := nsp.GetFilesystem(ctx, dataset); err != nil {
+			t.Fatal(err)
+		}
+
+		server.ExpireTokens()
+
+		if _, err := nsp.GetFilesystem(ctx, dataset); err != nil {
+			t.Fatal(err)
+		}
+		if count := server.CountRequests(http.MethodPost, "auth/login"); count != 2 {
+			t.Errorf("expected 2 login requests, but got %d", count)
+		}
+	})
+
+	t.Run("provider should wait for async jobs", func(t *testing.T) {
+		nsp, server := newTestProvider(t, nstest.ServerArgs{
+			Filesystems: []string{dataset},
+			AsyncJobs:   true,
+		})
+		defer server.Close()
+
+		path := dataset + "/fs"
+		if err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nsp.GetFilesystem(ctx, path); err != nil {
+			t.Errorf("filesystem created by async job not found: %v", err)
+		}
+
+		err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path})
+		if !ns.IsAlreadyExistNefError(err) {
+			t.Errorf("expected EEXIST error from failed async job, but got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,267 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_FilesystemProperties(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	path := dataset + "/fs"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	t.Run("CreateFilesystem() should set ZFS properties", func(t *testing.T) {
+		err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{
+			Path:                      path,
+			QuotaSize:                 20 * 1024 * 1024,
+			ReservationSize:           10 * 1024 * 1024,
+			ReferencedReservationSize: 5 * 1024 * 1024,
+			CompressionMode:           ns.CompressionModeGzip + "-9",
+			RecordSize:                32 * 1024,
+			AccessTime:                ns.Bool(false),
+			SyncMode:                  ns.SyncModeAlways,
+			LogBias:                   ns.LogBiasThroughput,
+			NonBlockingMandatoryMode:  ns.Bool(true),
+			CaseSensitivity:           ns.CaseSensitivityMixed,
+			UserProperties:            map[string]string{"csi:owner": "test"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fs, err := nsp.GetFilesystem(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := ns.Filesystem{
+			QuotaSize:                 20 * 1024 * 1024,
+			ReservationSize:           10 * 1024 * 1024,
+			ReferencedReservationSize: 5 * 1024 * 1024,
+			CompressionMode:           "gzip-9",
+			RecordSize:                32 * 1024,
+			AccessTime:                false,
+			SyncMode:                  ns.SyncModeAlways,
+			LogBias:                   ns.LogBiasThroughput,
+			ReadOnly:                  false,
+			NonBlockingMandatoryMode:  true,
+			CaseSensitivity:           ns.CaseSensitivityMixed,
+			UserProperties:            map[string]string{"csi:owner": "test"},
+		}
+		fs.Path, fs.MountPoint, fs.BytesAvailable, fs.BytesUsed = "", "", 0, 0
+		if !reflect.DeepEqual(fs, expected) {
+			t.Errorf("expected properties:\n%+v\nbut got:\n%+v", expected, fs)
+		}
+	})
+
+	t.Run("UpdateFilesystem() should change only provided properties", func(t *testing.T) {
+		err := nsp.UpdateFilesystem(ctx, path, ns.UpdateFilesystemParams{
+			CompressionMode: ns.CompressionModeOff,
+			ReadOnly:        ns.Bool(true),
+			UserProperties:  map[string]string{"csi:volume": "pvc-1"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fs, err := nsp.GetFilesystem(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if fs.CompressionMode != ns.CompressionModeOff || !fs.ReadOnly {
+			t.Errorf("properties were not updated: %+v", fs)
+		} else if fs.SyncMode != ns.SyncModeAlways || fs.RecordSize != 32*1024 {
+			t.Errorf("properties which were not provided should not change: %+v", fs)
+		} else if fs.UserProperties["csi:owner"] != "test" || fs.UserProperties["csi:volume"] != "pvc-1" {
+			t.Errorf("user properties should be merged, but got: %v", fs.UserProperties)
+		}
+	})
+
+	t.Run("UpdateFilesystem() should remove quota and reservations set to 0", func(t *testing.T) {
+		err := nsp.UpdateFilesystem(ctx, path, ns.UpdateFilesystemParams{
+			QuotaSize:                 ns.Int64(0),
+			ReservationSize:           ns.Int64(0),
+			ReferencedReservationSize: ns.Int64(0),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fs, err := nsp.GetFilesystem(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if fs.QuotaSize != 0 || fs.ReservationSize != 0 || fs.ReferencedReservationSize != 0 {
+			t.Errorf("expected quota and reservations to be removed, but got: %+v", fs)
+		} else if fs.CompressionMode != ns.CompressionModeOff {
+			t.Errorf("properties which were not provided should not change: %+v", fs)
+		}
+	})
+
+	t.Run("CreateFilesystem() should inherit properties of the parent filesystem", func(t *testing.T) {
+		child := path + "/child"
+		if err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: child}); err != nil {
+			t.Fatal(err)
+		}
+
+		fs, err := nsp.GetFilesystem(ctx, child)
+		if err != nil {
+			t.Fatal(err)
+		} else if fs.SyncMode != ns.SyncModeAlways || fs.CompressionMode != ns.CompressionModeOff {
+			t.Errorf("properties should be inherited from '%s', but got: %+v", path, fs)
+		}
+	})
+
+	t.Run("invalid properties should be rejected before sending a request", func(t *testing.T) {
+		before := server.CountRequests(http.MethodPost, "storage/filesystems")
+
+		invalidParams := map[string]ns.CreateFilesystemParams{
+			"compression":    {Path: dataset + "/invalid", CompressionMode: "gzip-10"},
+			"record size":    {Path: dataset + "/invalid", RecordSize: 3000},
+			"sync mode":      {Path: dataset + "/invalid", SyncMode: "sometimes"},
+			"log bias":       {Path: dataset + "/invalid", LogBias: "fast"},
+			"reservation":    {Path: dataset + "/invalid", QuotaSize: 1024, ReservationSize: 2048},
+			"user property":  {Path: dataset + "/invalid", UserProperties: map[string]string{"owner": "test"}},
+			"case sensitive": {Path: dataset + "/invalid", CaseSensitivity: "upper"},
+		}
+		for name, params := range invalidParams {
+			if err := nsp.CreateFilesystem(ctx, params); !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("%s: expected EBADARG error, but got: %v", name, err)
+			}
+		}
+
+		err := nsp.UpdateFilesystem(ctx, path, ns.UpdateFilesystemParams{RecordSize: 2 * 1024 * 1024})
+		if !errors.Is(err, ns.ErrBadArg) {
+			t.Errorf("expected EBADARG error, but got: %v", err)
+		}
+
+		if count := server.CountRequests(http.MethodPost, "storage/filesystems") - before; count != 0 {
+			t.Errorf("expected no requests to be sent, but got %d", count)
+		}
+	})
+}
+
+func TestProvider_VolumeProperties(t *testing.T) {
+	ctx := context.Background()
+	volumeGroup := "testPool/testVolumeGroup"
+	path := volumeGroup + "/volume"
+	size := int64(64 * 1024 * 1024)
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{VolumeGroups: []string{volumeGroup}})
+	defer server.Close()
+
+	t.Run("CreateVolume() should set ZFS properties", func(t *testing.T) {
+		err := nsp.CreateVolume(ctx, ns.CreateVolumeParams{
+			Path:            path,
+			VolumeSize:      size,
+			VolumeBlockSize: 32 * 1024,
+			CompressionMode: ns.CompressionModeOff,
+			SyncMode:        ns.SyncModeDisabled,
+			WriteBackCache:  ns.Bool(true),
+			DedupMode:       ns.DedupModeVerify,
+			ReservationSize: size,
+			UserProperties:  map[string]string{"csi:owner": "test"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		volume, err := nsp.GetVolume(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := ns.Volume{
+			Path:            path,
+			VolumeSize:      size,
+			VolumeBlockSize: 32 * 1024,
+			CompressionMode: ns.CompressionModeOff,
+			SyncMode:        ns.SyncModeDisabled,
+			WriteBackCache:  true,
+			DedupMode:       ns.DedupModeVerify,
+			ReservationSize: size,
+			UserProperties:  map[string]string{"csi:owner": "test"},
+		}
+		volume.BytesAvailable, volume.BytesUsed = 0, 0
+		if !reflect.DeepEqual(volume, expected) {
+			t.Errorf("expected properties:\n%+v\nbut got:\n%+v", expected, volume)
+		}
+	})
+
+	t.Run("UpdateVolume() should change only provided properties", func(t *testing.T) {
+		err := nsp.UpdateVolume(ctx, path, ns.UpdateVolumeParams{
+			VolumeSize:     2 * size,
+			WriteBackCache: ns.Bool(false),
+			ReadOnly:       ns.Bool(true),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		volume, err := nsp.GetVolume(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if volume.VolumeSize != 2*size || volume.WriteBackCache || !volume.ReadOnly {
+			t.Errorf("properties were not updated: %+v", volume)
+		} else if volume.DedupMode != ns.DedupModeVerify || volume.UserProperties["csi:owner"] != "test" {
+			t.Errorf("properties which were not provided should not change: %+v", volume)
+		}
+	})
+
+	t.Run("UpdateVolume() should remove reservation set to 0", func(t *testing.T) {
+		if err := nsp.UpdateVolume(ctx, path, ns.UpdateVolumeParams{ReservationSize: ns.Int64(0)}); err != nil {
+			t.Fatal(err)
+		}
+
+		volume, err := nsp.GetVolume(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if volume.ReservationSize != 0 {
+			t.Errorf("expected reservation to be removed, but got: %d", volume.ReservationSize)
+		}
+	})
+
+	t.Run("UpdateVolume() should not shrink volume unless it's allowed", func(t *testing.T) {
+		before := server.CountRequests(http.MethodPut, "storage/volumes/"+path)
+
+		err := nsp.UpdateVolume(ctx, path, ns.UpdateVolumeParams{VolumeSize: size})
+		if !errors.Is(err, ns.ErrBadArg) {
+			t.Fatalf("expected EBADARG error, but got: %v", err)
+		} else if count := server.CountRequests(http.MethodPut, "storage/volumes/"+path) - before; count != 0 {
+			t.Errorf("expected no update requests, but got %d", count)
+		}
+
+		err = nsp.UpdateVolume(ctx, path, ns.UpdateVolumeParams{VolumeSize: size, AllowShrink: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		volume, err := nsp.GetVolume(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if volume.VolumeSize != size {
+			t.Errorf("expected volume size %d, but got: %d", size, volume.VolumeSize)
+		}
+	})
+
+	t.Run("invalid properties should be rejected before sending a request", func(t *testing.T) {
+		invalidParams := map[string]ns.CreateVolumeParams{
+			"block size":    {Path: path + "1", VolumeSize: size, VolumeBlockSize: 256 * 1024},
+			"size multiple": {Path: path + "1", VolumeSize: size + 512, VolumeBlockSize: 4096},
+			"sparse":        {Path: path + "1", VolumeSize: size, SparseVolume: true, ReservationSize: size},
+			"reservation":   {Path: path + "1", VolumeSize: size, ReservationSize: 2 * size},
+			"dedup":         {Path: path + "1", VolumeSize: size, DedupMode: "sometimes"},
+		}
+		for name, params := range invalidParams {
+			if err := nsp.CreateVolume(ctx, params); !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("%s: expected EBADARG error, but got: %v", name, err)
+			}
+		}
+	})
+}
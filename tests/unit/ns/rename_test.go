@@ -0,0 +1,201 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_RenameFilesystem(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	path := dataset + "/fs"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	for _, p := range []string{path, path + "/child", dataset + "/other"} {
+		if err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: path + "/child@snap"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("RenameFilesystem() should move filesystem with children and snapshots", func(t *testing.T) {
+		newPath := "testPool/archive/2020/fs"
+		err := nsp.RenameFilesystem(ctx, path, newPath, ns.RenameFilesystemParams{CreateParents: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := nsp.GetFilesystem(ctx, path); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error for old path, but got: %v", err)
+		}
+		fs, err := nsp.GetFilesystem(ctx, newPath+"/child")
+		if err != nil {
+			t.Fatal(err)
+		} else if fs.MountPoint != "/"+newPath+"/child" {
+			t.Errorf("expected mount point to be changed, but got: %+v", fs)
+		}
+		if _, err := nsp.GetSnapshot(ctx, newPath+"/child@snap"); err != nil {
+			t.Errorf("snapshot should be moved with its filesystem: %v", err)
+		}
+
+		// moves it back for the next tests
+		if err := nsp.RenameFilesystem(ctx, newPath, path, ns.RenameFilesystemParams{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("RenameFilesystem() should re-create NFS and SMB shares", func(t *testing.T) {
+		newPath := dataset + "/renamed"
+		err := nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{
+			Filesystem:    path,
+			ReadWriteList: []ns.NfsRuleList{{Entity: "10.0.0.1", Etype: "fqdn"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.CreateSmbShare(ctx, ns.CreateSmbShareParams{Filesystem: path}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := nsp.RenameFilesystem(ctx, path, newPath, ns.RenameFilesystemParams{}); err != nil {
+			t.Fatal(err)
+		}
+
+		fs, err := nsp.GetFilesystem(ctx, newPath)
+		if err != nil {
+			t.Fatal(err)
+		} else if !fs.SharedOverNfs || !fs.SharedOverSmb {
+			t.Fatalf("expected filesystem to be shared over NFS and SMB, but got: %+v", fs)
+		}
+		shareName, err := nsp.GetSmbShareName(ctx, newPath)
+		if err != nil {
+			t.Fatal(err)
+		} else if shareName != fs.GetDefaultSmbShareName() {
+			t.Errorf("expected default SMB share name '%s', but got: '%s'", fs.GetDefaultSmbShareName(), shareName)
+		}
+		if count := server.CountRequests(http.MethodPost, "nas/nfs"); count != 2 {
+			t.Errorf("expected NFS share to be created twice, but got %d requests", count)
+		}
+
+		if err := nsp.RenameFilesystem(ctx, newPath, path, ns.RenameFilesystemParams{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("RenameFilesystem() should restore shares if rename fails", func(t *testing.T) {
+		err := nsp.RenameFilesystem(ctx, path, dataset+"/other", ns.RenameFilesystemParams{})
+		if !errors.Is(err, ns.ErrAlreadyExist) {
+			t.Fatalf("expected EEXIST error, but got: %v", err)
+		}
+
+		fs, err := nsp.GetFilesystem(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if !fs.SharedOverNfs || !fs.SharedOverSmb {
+			t.Errorf("shares should be restored, but got: %+v", fs)
+		}
+	})
+
+	t.Run("RenameFilesystem() should return RenameShareError if shares are not restored", func(t *testing.T) {
+		newPath := dataset + "/renamed"
+		server.AddFault(nstest.Fault{Method: http.MethodPost, Path: "nas/smb", Code: ns.NefCodeBusy, Count: 1})
+
+		err := nsp.RenameFilesystem(ctx, path, newPath, ns.RenameFilesystemParams{})
+		var shareErr *ns.RenameShareError
+		if !errors.As(err, &shareErr) {
+			t.Fatalf("expected RenameShareError, but got: %v", err)
+		} else if shareErr.NewPath != newPath || !errors.Is(err, ns.ErrBusy) {
+			t.Errorf("expected EBUSY error of SMB share for '%s', but got: %v", newPath, err)
+		}
+
+		fs, err := nsp.GetFilesystem(ctx, newPath)
+		if err != nil {
+			t.Fatal(err)
+		} else if !fs.SharedOverNfs || fs.SharedOverSmb {
+			t.Errorf("expected only NFS share to be restored, but got: %+v", fs)
+		}
+
+		if err := nsp.CreateSmbShare(ctx, ns.CreateSmbShareParams{Filesystem: newPath}); err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.RenameFilesystem(ctx, newPath, path, ns.RenameFilesystemParams{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("RenameFilesystem() should return restore error if rename fails", func(t *testing.T) {
+		server.AddFault(nstest.Fault{Method: http.MethodPost, Path: "nas/nfs", Code: ns.NefCodeBusy, Count: 1})
+
+		err := nsp.RenameFilesystem(ctx, path, dataset+"/other", ns.RenameFilesystemParams{})
+		if !errors.Is(err, ns.ErrAlreadyExist) {
+			t.Fatalf("expected EEXIST error, but got: %v", err)
+		} else if !strings.Contains(err.Error(), "were not restored") {
+			t.Errorf("expected restore error in the error, but got: %v", err)
+		}
+
+		if err := nsp.CreateNfsShare(ctx, ns.CreateNfsShareParams{Filesystem: path}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("RenameFilesystem() should report errors", func(t *testing.T) {
+		err := nsp.RenameFilesystem(ctx, dataset+"/missing", dataset+"/new", ns.RenameFilesystemParams{})
+		if !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+
+		err = nsp.RenameFilesystem(ctx, path, dataset+"/missing/new", ns.RenameFilesystemParams{})
+		if !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error for missing parent, but got: %v", err)
+		}
+
+		invalidPaths := []string{path, path + "/child/fs", "otherPool/fs", dataset + "/fs@snap"}
+		for _, newPath := range invalidPaths {
+			err := nsp.RenameFilesystem(ctx, path, newPath, ns.RenameFilesystemParams{})
+			if !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("expected EBADARG error for '%s', but got: %v", newPath, err)
+			}
+		}
+	})
+}
+
+func TestProvider_RenameVolume(t *testing.T) {
+	ctx := context.Background()
+	volumeGroup := "testPool/testVolumeGroup"
+	path := volumeGroup + "/volume"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{VolumeGroups: []string{volumeGroup, "testPool/archive"}})
+	defer server.Close()
+
+	if err := nsp.CreateVolume(ctx, ns.CreateVolumeParams{Path: path, VolumeSize: 1024 * 1024}); err != nil {
+		t.Fatal(err)
+	}
+	if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: path + "@snap"}); err != nil {
+		t.Fatal(err)
+	}
+
+	newPath := "testPool/archive/volume"
+	if err := nsp.RenameVolume(ctx, path, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nsp.GetVolume(ctx, newPath); err != nil {
+		t.Error(err)
+	}
+	if _, err := nsp.GetSnapshot(ctx, newPath+"@snap"); err != nil {
+		t.Errorf("snapshot should be moved with its volume: %v", err)
+	}
+
+	if err := nsp.RenameVolume(ctx, path, newPath); !errors.Is(err, ns.ErrNotExist) {
+		t.Errorf("expected ENOENT error, but got: %v", err)
+	}
+}
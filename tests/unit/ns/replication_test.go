@@ -0,0 +1,193 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_ReplicationServices(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	name := "dr"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	t.Run("CreateReplicationService() should create a stopped service", func(t *testing.T) {
+		err := nsp.CreateReplicationService(ctx, ns.CreateReplicationServiceParams{
+			Name:               name,
+			SourceDataset:      dataset,
+			DestinationDataset: "drPool/testDataset",
+			RemoteHost:         "10.3.199.253",
+			Interval:           15 * time.Minute,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		service, err := nsp.GetReplicationService(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := ns.ReplicationService{
+			Name:               name,
+			SourceDataset:      dataset,
+			DestinationDataset: "drPool/testDataset",
+			RemoteHost:         "10.3.199.253",
+			Mode:               ns.ReplicationModeIncremental,
+			Interval:           15 * time.Minute,
+		}
+		if service != expected {
+			t.Errorf("expected service:\n%+v\nbut got:\n%+v", expected, service)
+		}
+
+		status, err := nsp.GetReplicationServiceStatus(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		} else if status.State != ns.ReplicationStateDisabled || !status.LastSyncTime.IsZero() {
+			t.Errorf("expected disabled service w/o syncs, but got: %+v", status)
+		}
+	})
+
+	t.Run("CreateReplicationService() should validate params", func(t *testing.T) {
+		valid := ns.CreateReplicationServiceParams{
+			Name:               "invalid",
+			SourceDataset:      dataset,
+			DestinationDataset: "drPool/testDataset",
+			RemoteHost:         "10.3.199.253",
+		}
+
+		invalidParams := map[string]ns.CreateReplicationServiceParams{}
+		params := valid
+		params.Mode = "differential"
+		invalidParams["mode"] = params
+		params = valid
+		params.RemotePort = 70000
+		invalidParams["port"] = params
+		params = valid
+		params.Interval = time.Second
+		invalidParams["interval"] = params
+
+		for name, params := range invalidParams {
+			if err := nsp.CreateReplicationService(ctx, params); !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("%s: expected EBADARG error, but got: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("StartReplicationService() and StopReplicationService() should change service state", func(t *testing.T) {
+		if err := nsp.StartReplicationService(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+		status, err := nsp.GetReplicationServiceStatus(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		} else if status.State != ns.ReplicationStateIdle {
+			t.Errorf("expected '%s' state, but got: %+v", ns.ReplicationStateIdle, status)
+		}
+
+		if err := nsp.StopReplicationService(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+		service, err := nsp.GetReplicationService(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		} else if service.Enabled {
+			t.Errorf("service should be stopped, but got: %+v", service)
+		}
+	})
+
+	t.Run("SendReplicationNow() should send full stream first and then incremental ones", func(t *testing.T) {
+		if err := nsp.SendReplicationNow(ctx, name, ns.SendReplicationParams{}); err != nil {
+			t.Fatal(err)
+		}
+		first, err := nsp.GetReplicationServiceStatus(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		} else if first.LastMode != ns.ReplicationModeFull || first.LastSnapshot == "" || first.LastSyncTime.IsZero() {
+			t.Errorf("expected full send, but got: %+v", first)
+		}
+
+		if err := nsp.SendReplicationNow(ctx, name, ns.SendReplicationParams{}); err != nil {
+			t.Fatal(err)
+		}
+		second, err := nsp.GetReplicationServiceStatus(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		} else if second.LastMode != ns.ReplicationModeIncremental || second.LastSnapshot == first.LastSnapshot {
+			t.Errorf("expected incremental send of a new snapshot, but got: %+v", second)
+		} else if second.State != ns.ReplicationStateDisabled {
+			t.Errorf("stopped service should stay disabled after one-shot send, but got: %+v", second)
+		}
+	})
+
+	t.Run("SendReplicationNow() should require full send if the last snapshot is lost", func(t *testing.T) {
+		status, err := nsp.GetReplicationServiceStatus(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.DestroySnapshot(ctx, status.LastSnapshot); err != nil {
+			t.Fatal(err)
+		}
+
+		err = nsp.SendReplicationNow(ctx, name, ns.SendReplicationParams{})
+		if !errors.Is(err, ns.ErrNotExist) {
+			t.Fatalf("expected ENOENT error, but got: %v", err)
+		}
+		status, err = nsp.GetReplicationServiceStatus(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		} else if status.State != ns.ReplicationStateFaulted || status.LastError == "" {
+			t.Errorf("expected faulted service, but got: %+v", status)
+		}
+
+		err = nsp.SendReplicationNow(ctx, name, ns.SendReplicationParams{Mode: ns.ReplicationModeFull})
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, err = nsp.GetReplicationServiceStatus(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		} else if status.LastMode != ns.ReplicationModeFull || status.LastError != "" {
+			t.Errorf("expected successful full send, but got: %+v", status)
+		}
+	})
+
+	t.Run("GetReplicationServices() should filter services by source dataset", func(t *testing.T) {
+		services, err := nsp.GetReplicationServices(ctx, dataset)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(services) != 1 || services[0].Name != name {
+			t.Errorf("expected service '%s', but got: %+v", name, services)
+		}
+
+		services, err = nsp.GetReplicationServices(ctx, "testPool/other")
+		if err != nil {
+			t.Fatal(err)
+		} else if len(services) != 0 {
+			t.Errorf("expected no services, but got: %+v", services)
+		}
+	})
+
+	t.Run("DestroyReplicationService() should destroy service and its snapshots", func(t *testing.T) {
+		err := nsp.DestroyReplicationService(ctx, name, ns.DestroyReplicationServiceParams{DestroySnapshots: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := nsp.GetReplicationService(ctx, name); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+		snapshots, err := nsp.GetSnapshots(ctx, dataset, false)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(snapshots) != 0 {
+			t.Errorf("expected service snapshots to be destroyed, but got: %+v", snapshots)
+		}
+	})
+}
@@ -0,0 +1,345 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+
+	l := logrus.New().WithField("ns", "resolver")
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	emptyServer := nstest.NewServer(nstest.ServerArgs{})
+	defer emptyServer.Close()
+
+	server := nstest.NewServer(nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	// node that doesn't respond until request is cancelled
+	hangingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer hangingServer.Close()
+
+	newResolver := func(timeout time.Duration, addresses ...string) *ns.Resolver {
+		nsr, err := ns.NewResolver(ns.ResolverArgs{
+			Address:        strings.Join(addresses, ","),
+			Username:       server.Username(),
+			Password:       server.Password(),
+			Log:            logger.NewLogrus(l),
+			ResolveTimeout: timeout,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return nsr
+	}
+
+	t.Run("Resolve() should not wait for hanging nodes", func(t *testing.T) {
+		nsr := newResolver(0, hangingServer.URL, emptyServer.URL, server.URL)
+
+		startTime := time.Now()
+		nsProvider, err := nsr.Resolve(ctx, dataset)
+		if err != nil {
+			t.Fatal(err)
+		} else if nsProvider == nil || nsProvider.(*ns.Provider).Address != server.URL {
+			t.Errorf("expected '%s' node, but got: %v", server.URL, nsProvider)
+		}
+		if duration := time.Since(startTime); duration > 5*time.Second {
+			t.Errorf("resolve took too long: %s", duration)
+		}
+	})
+
+	t.Run("Resolve() should return errors of all nodes", func(t *testing.T) {
+		nsr := newResolver(0, emptyServer.URL, server.URL)
+
+		nsProvider, err := nsr.Resolve(ctx, "testPool/notExists")
+		if err == nil {
+			t.Fatalf("expected an error, but got: %v", nsProvider)
+		}
+
+		var resolveErr *ns.ResolveError
+		if !errors.As(err, &resolveErr) {
+			t.Fatalf("expected ResolveError, but got: %v", err)
+		} else if len(resolveErr.NodeErrors) != 2 || resolveErr.NodeErrors[1].Node != server.URL {
+			t.Errorf("expected errors of 2 nodes in order, but got: %+v", resolveErr.NodeErrors)
+		}
+		if !ns.IsNotExistNefError(err) {
+			t.Errorf("expected ENOENT on all nodes, but got: %v", err)
+		}
+		if code := ns.GetNefErrorCode(err); code != "ENOENT" {
+			t.Errorf("expected ENOENT code of all nodes, but got: '%s'", code)
+		}
+	})
+
+	t.Run("Resolve() should respect resolve timeout", func(t *testing.T) {
+		nsr := newResolver(100*time.Millisecond, hangingServer.URL, emptyServer.URL)
+
+		_, err := nsr.Resolve(ctx, dataset)
+		if err == nil {
+			t.Fatal("expected an error")
+		} else if !strings.Contains(err.Error(), hangingServer.URL) {
+			t.Errorf("expected hanging node in the error, but got: %v", err)
+		}
+		if ns.IsNotExistNefError(err) {
+			t.Errorf("hanging node error should not be treated as ENOENT: %v", err)
+		}
+		if code := ns.GetNefErrorCode(err); code != "" {
+			t.Errorf("expected no common code for different node errors, but got: '%s'", code)
+		}
+	})
+}
+
+func TestResolver_Cache(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+
+	l := logrus.New().WithField("ns", "resolver")
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	emptyServer := nstest.NewServer(nstest.ServerArgs{})
+	defer emptyServer.Close()
+
+	server := nstest.NewServer(nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	nsr, err := ns.NewResolver(ns.ResolverArgs{
+		Address:  strings.Join([]string{emptyServer.URL, server.URL}, ","),
+		Username: server.Username(),
+		Password: server.Password(),
+		Log:      logger.NewLogrus(l),
+		CacheTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := func(t *testing.T) ns.ProviderInterface {
+		nsProvider, err := nsr.Resolve(ctx, dataset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return nsProvider
+	}
+
+	t.Run("Resolve() should return cached node", func(t *testing.T) {
+		resolve(t)
+		resolve(t)
+
+		stats := nsr.CacheStats()
+		if stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+			t.Errorf("expected 1 hit, 1 miss and 1 entry, but got: %+v", stats)
+		}
+		// the first request is rejected with EAUTH and sent again after login
+		if count := server.CountRequests(http.MethodGet, "storage/filesystems"); count != 2 {
+			t.Errorf("expected 2 filesystem requests to the node, but got %d", count)
+		}
+	})
+
+	t.Run("ENOENT during resolving should not invalidate cache", func(t *testing.T) {
+		nsr.Resolve(ctx, "testPool/notExists")
+
+		if stats := nsr.CacheStats(); stats.Entries != 1 || stats.Invalidations != 0 {
+			t.Errorf("expected 1 entry and no invalidations, but got: %+v", stats)
+		}
+	})
+
+	t.Run("ENOENT from cached node should invalidate entries of the missing path", func(t *testing.T) {
+		nsProvider := resolve(t)
+
+		path := dataset + "/fs"
+		if err := nsProvider.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nsr.Resolve(ctx, path); err != nil {
+			t.Fatal(err)
+		}
+		if err := nsProvider.DestroyFilesystem(ctx, path, ns.DestroyFilesystemParams{}); err != nil {
+			t.Fatal(err)
+		}
+
+		// errors for other missing resources should keep cached entries
+		if _, err := nsProvider.GetSnapshot(ctx, dataset+"@notExists"); !ns.IsNotExistNefError(err) {
+			t.Fatalf("expected ENOENT error for snapshot, but got: %v", err)
+		}
+		if err := nsProvider.DeleteNfsShare(ctx, dataset); !ns.IsNotExistNefError(err) {
+			t.Fatalf("expected ENOENT error for NFS share, but got: %v", err)
+		}
+		if stats := nsr.CacheStats(); stats.Entries != 2 || stats.Invalidations != 0 {
+			t.Errorf("expected 2 entries and no invalidations, but got: %+v", stats)
+		}
+
+		if _, err := nsProvider.GetFilesystem(ctx, path); !ns.IsNotExistNefError(err) {
+			t.Fatalf("expected ENOENT error, but got: %v", err)
+		}
+		if stats := nsr.CacheStats(); stats.Entries != 1 || stats.Invalidations != 1 {
+			t.Errorf("expected 1 entry and 1 invalidation, but got: %+v", stats)
+		}
+	})
+
+	t.Run("transport error from cached node should invalidate its entries", func(t *testing.T) {
+		nsProvider := resolve(t)
+
+		server.AddFault(nstest.Fault{Method: http.MethodGet, Path: "storage/filesystems", CloseConnection: true})
+		defer server.ClearFaults()
+
+		if _, err := nsProvider.GetFilesystem(ctx, dataset); err == nil {
+			t.Fatal("expected transport error")
+		}
+
+		if stats := nsr.CacheStats(); stats.Entries != 0 || stats.Invalidations != 2 {
+			t.Errorf("expected no entries and 2 invalidations, but got: %+v", stats)
+		}
+	})
+
+	t.Run("ENOENT of dataset request to cached node should invalidate the dataset", func(t *testing.T) {
+		nsProvider := resolve(t)
+
+		for _, name := range []string{"updated", "destroyed"} {
+			path := dataset + "/" + name
+			if err := nsProvider.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := nsr.Resolve(ctx, path); err != nil {
+				t.Fatal(err)
+			}
+			// the filesystem is moved to another node, e.g. by RSF failover
+			if err := nsProvider.DestroyFilesystem(ctx, path, ns.DestroyFilesystemParams{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		stats := nsr.CacheStats()
+
+		err := nsProvider.UpdateFilesystem(ctx, dataset+"/updated", ns.UpdateFilesystemParams{ReadOnly: ns.Bool(true)})
+		if !ns.IsNotExistNefError(err) {
+			t.Fatalf("expected ENOENT error, but got: %v", err)
+		}
+		err = nsProvider.DestroyFilesystem(ctx, dataset+"/destroyed", ns.DestroyFilesystemParams{})
+		if !ns.IsNotExistNefError(err) {
+			t.Fatalf("expected ENOENT error, but got: %v", err)
+		}
+
+		newStats := nsr.CacheStats()
+		if newStats.Entries != stats.Entries-2 || newStats.Invalidations != stats.Invalidations+2 {
+			t.Errorf("expected 2 entries to be invalidated, but got: %+v (was: %+v)", newStats, stats)
+		}
+	})
+
+	t.Run("login transport error of cached node should invalidate its entries", func(t *testing.T) {
+		nsProvider := resolve(t)
+
+		server.ExpireTokens()
+		server.AddFault(nstest.Fault{Method: http.MethodPost, Path: "auth/login", CloseConnection: true})
+		defer server.ClearFaults()
+
+		if _, err := nsProvider.GetFilesystem(ctx, dataset); err == nil {
+			t.Fatal("expected login error")
+		}
+		if stats := nsr.CacheStats(); stats.Entries != 0 {
+			t.Errorf("expected no entries, but got: %+v", stats)
+		}
+	})
+
+	t.Run("InvalidateCache() should remove the path", func(t *testing.T) {
+		resolve(t)
+		nsr.InvalidateCache(dataset)
+
+		if stats := nsr.CacheStats(); stats.Entries != 0 {
+			t.Errorf("expected no entries, but got: %+v", stats)
+		}
+	})
+}
+
+func TestResolver_ResolveResource(t *testing.T) {
+	ctx := context.Background()
+
+	l := logrus.New().WithField("ns", "resolver")
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	otherServer := nstest.NewServer(nstest.ServerArgs{Pools: []string{"otherPool"}})
+	defer otherServer.Close()
+
+	server := nstest.NewServer(nstest.ServerArgs{
+		Filesystems:  []string{"testPool/testDataset"},
+		VolumeGroups: []string{"testPool/testVolumeGroup"},
+	})
+	defer server.Close()
+
+	nsr, err := ns.NewResolver(ns.ResolverArgs{
+		Address:  strings.Join([]string{otherServer.URL, server.URL}, ","),
+		Username: server.Username(),
+		Password: server.Password(),
+		Log:      logger.NewLogrus(l),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nsp := nsr.Nodes[1]
+	if err := nsp.CreateVolume(ctx, ns.CreateVolumeParams{
+		Path:       "testPool/testVolumeGroup/testVolume",
+		VolumeSize: 1024 * 1024,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: "testPool/testDataset@testSnapshot"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := nsp.CreateISCSITarget(ctx, ns.CreateISCSITargetParams{Name: "iqn.2005-07.com.nexenta:01:test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		kind ns.ResourceKind
+		path string
+		node string
+	}{
+		{ns.ResourceKindPool, "testPool", server.URL},
+		{ns.ResourceKindPool, "otherPool", otherServer.URL},
+		{ns.ResourceKindFilesystem, "testPool/testDataset", server.URL},
+		{ns.ResourceKindVolumeGroup, "testPool/testVolumeGroup", server.URL},
+		{ns.ResourceKindVolume, "testPool/testVolumeGroup/testVolume", server.URL},
+		{ns.ResourceKindSnapshot, "testPool/testDataset@testSnapshot", server.URL},
+		{ns.ResourceKindTarget, "iqn.2005-07.com.nexenta:01:test", server.URL},
+	} {
+		t.Run(fmt.Sprintf("%s '%s' should be resolved", test.kind, test.path), func(t *testing.T) {
+			nsProvider, err := nsr.ResolveResource(ctx, test.kind, test.path)
+			if err != nil {
+				t.Fatal(err)
+			} else if nsProvider.(*ns.Provider).Address != test.node {
+				t.Errorf("expected '%s' node, but got '%s'", test.node, nsProvider)
+			}
+		})
+	}
+
+	t.Run("ResolveVolume() should return ENOENT for not existing volume", func(t *testing.T) {
+		_, err := nsr.ResolveVolume(ctx, "testPool/testVolumeGroup/notExists")
+		if !ns.IsNotExistNefError(err) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+	})
+
+	t.Run("ResolveResource() should fail for unknown kind", func(t *testing.T) {
+		if _, err := nsr.ResolveResource(ctx, "unknown", "testPool"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package provider_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
+)
+
+func TestProvider_Retry(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	retryPolicy := &rest.RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+		RetryableErrorCodes:  []string{"EBUSY"},
+	}
+
+	t.Run("network errors and retryable status codes should be retried", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}},
+			ns.ProviderArgs{RetryPolicy: retryPolicy},
+		)
+		defer server.Close()
+
+		server.AddFault(nstest.Fault{Path: "auth/login", CloseConnection: true, Count: 1})
+		server.AddFault(nstest.Fault{Path: "storage/filesystems", StatusCode: http.StatusServiceUnavailable, Count: 1})
+
+		if _, err := nsp.GetFilesystem(ctx, dataset); err != nil {
+			t.Fatal(err)
+		}
+		if count := server.CountRequests(http.MethodPost, "auth/login"); count != 2 {
+			t.Errorf("expected 2 login requests, but got %d", count)
+		}
+		if count := server.CountRequests(http.MethodGet, "storage/filesystems"); count != 3 {
+			t.Errorf("expected 3 filesystem requests (EAUTH, 503, 200), but got %d", count)
+		}
+	})
+
+	t.Run("failed async jobs with retryable code should be retried", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, AsyncJobs: true},
+			ns.ProviderArgs{RetryPolicy: retryPolicy},
+		)
+		defer server.Close()
+
+		path := dataset + "/fs"
+		nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: path})
+
+		uri := "storage/filesystems/" + path
+		server.AddFault(nstest.Fault{Method: http.MethodDelete, Path: uri, Code: "EBUSY", Count: 2})
+
+		err := nsp.DestroyFilesystem(ctx, path, ns.DestroyFilesystemParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count := server.CountRequests(http.MethodDelete, uri); count != 3 {
+			t.Errorf("expected 3 delete requests, but got %d", count)
+		}
+	})
+
+	t.Run("non-idempotent requests should not be retried by default", func(t *testing.T) {
+		nsp, server := newTestProviderWithArgs(
+			t,
+			nstest.ServerArgs{Filesystems: []string{dataset}, AsyncJobs: true},
+			ns.ProviderArgs{RetryPolicy: retryPolicy},
+		)
+		defer server.Close()
+
+		// log in first, so the only POST request is the create one
+		if _, err := nsp.GetFilesystem(ctx, dataset); err != nil {
+			t.Fatal(err)
+		}
+
+		server.AddFault(nstest.Fault{Method: http.MethodPost, Path: "storage/filesystems", Code: "EBUSY", Count: 1})
+
+		err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: dataset + "/fs"})
+		if !ns.IsBusyNefError(err) {
+			t.Errorf("expected EBUSY error, but got: %v", err)
+		}
+		if count := server.CountRequests(http.MethodPost, "storage/filesystems"); count != 1 {
+			t.Errorf("expected 1 create request, but got %d", count)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_SnapshotSchedules(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	t.Run("CreateSnapshotSchedule() should create a schedule", func(t *testing.T) {
+		err := nsp.CreateSnapshotSchedule(ctx, ns.CreateSnapshotScheduleParams{
+			Name:      "hourly",
+			Dataset:   dataset,
+			Interval:  time.Hour,
+			KeepCount: 24,
+			Recursive: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		schedule, err := nsp.GetSnapshotSchedule(ctx, "hourly")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := ns.SnapshotSchedule{
+			Name:      "hourly",
+			Dataset:   dataset,
+			Interval:  time.Hour,
+			KeepCount: 24,
+			Recursive: true,
+			Prefix:    "hourly",
+			Enabled:   true,
+		}
+		if schedule != expected {
+			t.Errorf("expected schedule:\n%+v\nbut got:\n%+v", expected, schedule)
+		}
+	})
+
+	t.Run("CreateSnapshotSchedule() should validate params", func(t *testing.T) {
+		invalidParams := []ns.CreateSnapshotScheduleParams{
+			{Name: "s", Dataset: dataset, Interval: time.Second, KeepCount: 1},
+			{Name: "s", Dataset: dataset, Interval: time.Hour, KeepCount: 0},
+			{Name: "s", Dataset: dataset, Interval: time.Hour, KeepCount: 1, Prefix: "a@b"},
+		}
+		for _, params := range invalidParams {
+			if err := nsp.CreateSnapshotSchedule(ctx, params); !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("expected EBADARG error for %+v, but got: %v", params, err)
+			}
+		}
+	})
+
+	t.Run("UpdateSnapshotSchedule() should change only provided fields", func(t *testing.T) {
+		err := nsp.UpdateSnapshotSchedule(ctx, "hourly", ns.UpdateSnapshotScheduleParams{
+			KeepCount: 48,
+			Enabled:   ns.Bool(false),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		schedules, err := nsp.GetSnapshotSchedules(ctx, dataset)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(schedules) != 1 {
+			t.Fatalf("expected 1 schedule, but got: %+v", schedules)
+		} else if s := schedules[0]; s.KeepCount != 48 || s.Enabled || s.Interval != time.Hour || !s.Recursive {
+			t.Errorf("unexpected schedule after update: %+v", s)
+		}
+	})
+
+	t.Run("DestroySnapshotSchedule() should destroy a schedule", func(t *testing.T) {
+		if err := nsp.DestroySnapshotSchedule(ctx, "hourly"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nsp.GetSnapshotSchedule(ctx, "hourly"); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+	})
+}
+
+func TestRetentionPolicy_ExpiredSnapshots(t *testing.T) {
+	now := time.Date(2020, time.March, 10, 12, 30, 0, 0, time.UTC)
+
+	// a snapshot every 6 hours for 15 days, the newest goes first
+	snapshots := []ns.Snapshot{}
+	for i := 0; i < 60; i++ {
+		snapshots = append(snapshots, ns.Snapshot{
+			Path:         fmt.Sprintf("pool/fs@auto-%02d", i),
+			Name:         fmt.Sprintf("auto-%02d", i),
+			CreationTime: now.Add(-time.Duration(i) * 6 * time.Hour),
+		})
+	}
+	snapshots = append(snapshots, ns.Snapshot{Path: "pool/fs@manual", Name: "manual", CreationTime: now.Add(-time.Hour * 1000)})
+
+	expiredNames := func(policy ns.RetentionPolicy, snapshots []ns.Snapshot) map[string]bool {
+		names := map[string]bool{}
+		for _, snapshot := range policy.ExpiredSnapshots(snapshots) {
+			names[snapshot.Name] = true
+		}
+		return names
+	}
+
+	t.Run("should keep last snapshots and one per period", func(t *testing.T) {
+		expired := expiredNames(ns.RetentionPolicy{KeepLast: 2, KeepDaily: 3, KeepWeekly: 2, Prefix: "auto-"}, snapshots)
+
+		// last: 00, 01; daily (newest of March 10, 9, 8): 00, 03, 07; weekly (newest of weeks 11 and 10): 00, 07
+		for _, name := range []string{"auto-00", "auto-01", "auto-03", "auto-07"} {
+			if expired[name] {
+				t.Errorf("snapshot '%s' should be kept", name)
+			}
+		}
+		if len(expired) != 60-4 {
+			t.Errorf("expected %d expired snapshots, but got %d: %v", 60-4, len(expired), expired)
+		}
+		if expired["manual"] {
+			t.Error("snapshot w/o policy prefix should not be expired")
+		}
+	})
+
+	t.Run("should return the oldest snapshots first and skip snapshots with clones", func(t *testing.T) {
+		withClone := append([]ns.Snapshot{}, snapshots...)
+		withClone[59].Clones = []string{"pool/clone"}
+
+		expired := ns.RetentionPolicy{KeepLast: 10, Prefix: "auto-"}.ExpiredSnapshots(withClone)
+		if len(expired) != 49 {
+			t.Fatalf("expected 49 expired snapshots, but got %d", len(expired))
+		} else if expired[0].Name != "auto-58" || expired[len(expired)-1].Name != "auto-10" {
+			t.Errorf("expected snapshots from 'auto-58' to 'auto-10', but got from '%s' to '%s'",
+				expired[0].Name, expired[len(expired)-1].Name)
+		}
+	})
+
+	t.Run("should not expire anything if policy keeps nothing", func(t *testing.T) {
+		if expired := (ns.RetentionPolicy{}).ExpiredSnapshots(snapshots); len(expired) != 0 {
+			t.Errorf("expected no expired snapshots, but got %d", len(expired))
+		}
+	})
+}
+
+func TestProvider_ApplyRetentionPolicy(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	for i := 1; i <= 5; i++ {
+		err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: fmt.Sprintf("%s@s%d", dataset, i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := nsp.CloneSnapshot(ctx, dataset+"@s1", ns.CloneSnapshotParams{TargetPath: dataset + "/clone"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := nsp.HoldSnapshot(ctx, dataset+"@s2", "backup"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := nsp.ApplyRetentionPolicy(ctx, dataset, ns.RetentionPolicy{}); !errors.Is(err, ns.ErrBadArg) {
+		t.Errorf("expected EBADARG error for empty policy, but got: %v", err)
+	}
+
+	// snapshots already destroyed by someone else should not be reported as destroyed
+	for i := 3; i <= 5; i++ {
+		server.AddFault(nstest.Fault{
+			Method:     http.MethodDelete,
+			Path:       fmt.Sprintf("storage/snapshots/%s@s%d", dataset, i),
+			StatusCode: http.StatusNotFound,
+			Code:       ns.NefCodeNotExist,
+			Count:      1,
+		})
+	}
+	if destroyed, err := nsp.ApplyRetentionPolicy(ctx, dataset, ns.RetentionPolicy{KeepLast: 1}); err != nil {
+		t.Fatal(err)
+	} else if len(destroyed) != 0 {
+		t.Errorf("expected no destroyed snapshots on ENOENT, but got: %+v", destroyed)
+	}
+	server.ClearFaults()
+
+	// snapshots are created at the same time, so the order is not defined and only counts are checked
+	destroyed, err := nsp.ApplyRetentionPolicy(ctx, dataset, ns.RetentionPolicy{KeepLast: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	left, err := nsp.GetSnapshots(ctx, dataset, false)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(left)+len(destroyed) != 5 {
+		t.Errorf("expected %d snapshots left, but got: %+v", 5-len(destroyed), left)
+	}
+
+	names := map[string]bool{}
+	for _, snapshot := range left {
+		names[snapshot.Name] = true
+	}
+	if !names["s1"] || !names["s2"] {
+		t.Errorf("snapshot with clone 's1' and held snapshot 's2' should not be destroyed, left: %v", names)
+	}
+	if len(left) > 3 {
+		t.Errorf("expected at most 3 snapshots left (kept, cloned and held ones), but got: %v", names)
+	}
+}
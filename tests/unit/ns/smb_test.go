@@ -0,0 +1,207 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_SmbShares(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	path := dataset + "/fs"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	for _, p := range []string{path, path + "/child", dataset + "/other"} {
+		if err := nsp.CreateFilesystem(ctx, ns.CreateFilesystemParams{Path: p}); err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.CreateSmbShare(ctx, ns.CreateSmbShareParams{Filesystem: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("GetSmbShare() should return share with default properties", func(t *testing.T) {
+		share, err := nsp.GetSmbShare(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := ns.SmbShare{
+			Filesystem: path,
+			MountPoint: "/" + path,
+			ShareName:  "testPool_testDataset_fs",
+			ShareState: ns.SmbShareStateOnline,
+			ShareACL: []ns.SmbShareACE{
+				{Principal: "everyone@", Type: ns.SmbShareACETypeAllow, Permissions: ns.SmbSharePermissionFull},
+			},
+		}
+		if !reflect.DeepEqual(share, expected) {
+			t.Errorf("expected share:\n%+v\nbut got:\n%+v", expected, share)
+		}
+
+		if _, err := nsp.GetSmbShare(ctx, dataset); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+	})
+
+	t.Run("ListSmbShares() should return shares of the parent and its descendants", func(t *testing.T) {
+		shares, err := nsp.ListSmbShares(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(shares) != 2 || shares[0].Filesystem != path || shares[1].Filesystem != path+"/child" {
+			t.Errorf("expected shares of '%s' and its child, but got: %+v", path, shares)
+		}
+
+		shares, err = nsp.ListSmbShares(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		} else if len(shares) != 3 {
+			t.Errorf("expected 3 shares, but got: %+v", shares)
+		}
+	})
+
+	t.Run("UpdateSmbShare() should change only set properties", func(t *testing.T) {
+		acl := []ns.SmbShareACE{
+			{Principal: "group:staff", Type: ns.SmbShareACETypeAllow, Permissions: ns.SmbSharePermissionChange},
+			{Principal: "user:guest", Type: ns.SmbShareACETypeDeny, Permissions: ns.SmbSharePermissionFull},
+		}
+		description := "team share"
+		quota := int64(10 << 30)
+		err := nsp.UpdateSmbShare(ctx, path, ns.UpdateSmbShareParams{
+			ShareName:              "team",
+			Description:            &description,
+			AccessBasedEnumeration: ns.Bool(true),
+			EncryptData:            ns.Bool(true),
+			ShareQuota:             &quota,
+			ShareACL:               acl,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		share, err := nsp.GetSmbShare(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := ns.SmbShare{
+			Filesystem:             path,
+			MountPoint:             "/" + path,
+			ShareName:              "team",
+			ShareState:             ns.SmbShareStateOnline,
+			Description:            description,
+			AccessBasedEnumeration: true,
+			EncryptData:            true,
+			ShareQuota:             quota,
+			ShareACL:               acl,
+		}
+		if !reflect.DeepEqual(share, expected) {
+			t.Errorf("expected share:\n%+v\nbut got:\n%+v", expected, share)
+		}
+
+		description = ""
+		quota = 0
+		err = nsp.UpdateSmbShare(ctx, path, ns.UpdateSmbShareParams{
+			Description: &description,
+			GuestOk:     ns.Bool(true),
+			ShareQuota:  &quota,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		share, err = nsp.GetSmbShare(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if share.Description != "" || share.ShareQuota != 0 || !share.GuestOk || !share.EncryptData {
+			t.Errorf("expected description and quota to be removed, guest access to be set, but got: %+v", share)
+		}
+	})
+
+	t.Run("UpdateSmbShare() should report errors", func(t *testing.T) {
+		err := nsp.UpdateSmbShare(ctx, dataset+"/other", ns.UpdateSmbShareParams{ShareName: "team"})
+		if !errors.Is(err, ns.ErrAlreadyExist) {
+			t.Errorf("expected EEXIST error for share name in use, but got: %v", err)
+		}
+
+		err = nsp.UpdateSmbShare(ctx, dataset, ns.UpdateSmbShareParams{GuestOk: ns.Bool(true)})
+		if !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+
+		quota := int64(-1)
+		invalidParams := map[string]ns.UpdateSmbShareParams{
+			"quota":      {ShareQuota: &quota},
+			"principal":  {ShareACL: []ns.SmbShareACE{{Type: "allow", Permissions: "read"}}},
+			"type":       {ShareACL: []ns.SmbShareACE{{Principal: "everyone@", Type: "audit", Permissions: "read"}}},
+			"permission": {ShareACL: []ns.SmbShareACE{{Principal: "everyone@", Type: "allow", Permissions: "write"}}},
+		}
+		for name, params := range invalidParams {
+			if err := nsp.UpdateSmbShare(ctx, path, params); !errors.Is(err, ns.ErrBadArg) {
+				t.Errorf("%s: expected EBADARG error, but got: %v", name, err)
+			}
+		}
+		if count := server.CountRequests(http.MethodPut, "nas/smb/"+path); count != 2 {
+			t.Errorf("expected invalid params to be rejected w/o requests, but got %d requests", count)
+		}
+	})
+
+	t.Run("RenameFilesystem() should keep SMB share properties", func(t *testing.T) {
+		newPath := dataset + "/renamed"
+		err := nsp.UpdateSmbShare(ctx, path+"/child", ns.UpdateSmbShareParams{EncryptData: ns.Bool(true)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.RenameFilesystem(ctx, path+"/child", newPath, ns.RenameFilesystemParams{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.DeleteSmbShare(ctx, path+"/child"); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected share of the old path to be removed, but got: %v", err)
+		}
+
+		share, err := nsp.GetSmbShare(ctx, newPath)
+		if err != nil {
+			t.Fatal(err)
+		} else if share.ShareName != "testPool_testDataset_renamed" {
+			t.Errorf("expected default share name for the new path, but got: '%s'", share.ShareName)
+		} else if !share.EncryptData {
+			t.Errorf("expected share properties to be kept, but got: %+v", share)
+		}
+	})
+}
+
+func TestProvider_GetSmbShareName(t *testing.T) {
+	ctx := context.Background()
+
+	shareState := "offline"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"shareName": "pool_fs", "shareState": "` + shareState + `"}`))
+	}))
+	defer server.Close()
+
+	nsp, err := ns.NewProvider(ns.ProviderArgs{Address: server.URL, Username: "admin", Password: "pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := nsp.GetSmbShareName(ctx, "pool/fs"); !errors.Is(err, ns.ErrBusy) {
+		t.Errorf("expected EBUSY error for offline share, but got: %v", err)
+	}
+
+	// NexentaStor versions w/o share state
+	shareState = ""
+	if shareName, err := nsp.GetSmbShareName(ctx, "pool/fs"); err != nil {
+		t.Errorf("expected share w/o state to be treated as online, but got: %v", err)
+	} else if shareName != "pool_fs" {
+		t.Errorf("expected 'pool_fs' share name, but got: '%s'", shareName)
+	}
+}
@@ -0,0 +1,302 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Nexenta/go-nexentastor/pkg/ns"
+	"github.com/Nexenta/go-nexentastor/pkg/ns/nstest"
+)
+
+func TestProvider_Rollback(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	volumeGroup := "testPool/testVolumeGroup"
+
+	newProvider := func(t *testing.T) (ns.ProviderInterface, *nstest.Server) {
+		nsp, server := newTestProvider(t, nstest.ServerArgs{
+			Filesystems:  []string{dataset},
+			VolumeGroups: []string{volumeGroup},
+		})
+		for _, name := range []string{"s1", "s2", "s3"} {
+			err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: dataset + "@" + name})
+			if err != nil {
+				server.Close()
+				t.Fatal(err)
+			}
+		}
+		return nsp, server
+	}
+
+	snapshotNames := func(t *testing.T, nsp ns.ProviderInterface, path string) []string {
+		snapshots, err := nsp.GetSnapshots(ctx, path, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, snapshot := range snapshots {
+			names = append(names, snapshot.Name)
+		}
+		return names
+	}
+
+	t.Run("RollbackFilesystem() should fail if there are more recent snapshots", func(t *testing.T) {
+		nsp, server := newProvider(t)
+		defer server.Close()
+
+		err := nsp.RollbackFilesystem(ctx, dataset+"@s1", ns.RollbackSnapshotParams{})
+		if !errors.Is(err, ns.ErrBusy) {
+			t.Errorf("expected EBUSY error, but got: %v", err)
+		}
+
+		if err := nsp.RollbackFilesystem(ctx, dataset+"@s3", ns.RollbackSnapshotParams{}); err != nil {
+			t.Errorf("rollback to the most recent snapshot should succeed, but got: %v", err)
+		}
+	})
+
+	t.Run("RollbackFilesystem() should destroy more recent snapshots", func(t *testing.T) {
+		nsp, server := newProvider(t)
+		defer server.Close()
+
+		err := nsp.RollbackFilesystem(ctx, dataset+"@s1", ns.RollbackSnapshotParams{DestroyRecentSnapshots: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := snapshotNames(t, nsp, dataset); len(names) != 1 || names[0] != "s1" {
+			t.Errorf("expected only 's1' snapshot to be left, but got: %v", names)
+		}
+	})
+
+	t.Run("RollbackFilesystem() should destroy clones of more recent snapshots only if requested", func(t *testing.T) {
+		nsp, server := newProvider(t)
+		defer server.Close()
+
+		clone := dataset + "/clone"
+		if err := nsp.CloneSnapshot(ctx, dataset+"@s2", ns.CloneSnapshotParams{TargetPath: clone}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := nsp.RollbackFilesystem(ctx, dataset+"@s1", ns.RollbackSnapshotParams{DestroyRecentSnapshots: true})
+		if !errors.Is(err, ns.ErrBusy) {
+			t.Fatalf("expected EBUSY error, but got: %v", err)
+		}
+
+		err = nsp.RollbackFilesystem(ctx, dataset+"@s1", ns.RollbackSnapshotParams{DestroyRecentClones: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nsp.GetFilesystem(ctx, clone); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("clone '%s' should be destroyed, but got: %v", clone, err)
+		}
+		if names := snapshotNames(t, nsp, dataset); len(names) != 1 || names[0] != "s1" {
+			t.Errorf("expected only 's1' snapshot to be left, but got: %v", names)
+		}
+	})
+
+	t.Run("RollbackVolume() should roll volume back", func(t *testing.T) {
+		nsp, server := newProvider(t)
+		defer server.Close()
+
+		volume := volumeGroup + "/volume"
+		if err := nsp.CreateVolume(ctx, ns.CreateVolumeParams{Path: volume, VolumeSize: 1024 * 1024}); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"v1", "v2"} {
+			if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: volume + "@" + name}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := nsp.RollbackVolume(ctx, volume+"@v1", ns.RollbackSnapshotParams{DestroyRecentSnapshots: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := snapshotNames(t, nsp, volume); len(names) != 1 || names[0] != "v1" {
+			t.Errorf("expected only 'v1' snapshot to be left, but got: %v", names)
+		}
+	})
+
+	t.Run("RollbackFilesystem() should validate snapshot path", func(t *testing.T) {
+		nsp, server := newProvider(t)
+		defer server.Close()
+
+		if err := nsp.RollbackFilesystem(ctx, dataset, ns.RollbackSnapshotParams{}); !errors.Is(err, ns.ErrBadArg) {
+			t.Errorf("expected EBADARG error, but got: %v", err)
+		}
+		err := nsp.RollbackFilesystem(ctx, dataset+"@missing", ns.RollbackSnapshotParams{})
+		if !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+		err = nsp.RollbackFilesystem(ctx, dataset+"/missing@s1", ns.RollbackSnapshotParams{})
+		if !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error for missing parent, but got: %v", err)
+		}
+	})
+
+	t.Run("Rollback should fail if snapshot parent type doesn't match", func(t *testing.T) {
+		nsp, server := newProvider(t)
+		defer server.Close()
+
+		volume := volumeGroup + "/volume"
+		if err := nsp.CreateVolume(ctx, ns.CreateVolumeParams{Path: volume, VolumeSize: 1024 * 1024}); err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: volume + "@v1"}); err != nil {
+			t.Fatal(err)
+		}
+
+		err := nsp.RollbackFilesystem(ctx, volume+"@v1", ns.RollbackSnapshotParams{})
+		if !errors.Is(err, ns.ErrBadArg) {
+			t.Errorf("expected EBADARG error for volume snapshot, but got: %v", err)
+		}
+		err = nsp.RollbackVolume(ctx, dataset+"@s3", ns.RollbackSnapshotParams{})
+		if !errors.Is(err, ns.ErrBadArg) {
+			t.Errorf("expected EBADARG error for filesystem snapshot, but got: %v", err)
+		}
+		if names := snapshotNames(t, nsp, dataset); len(names) != 3 {
+			t.Errorf("expected filesystem snapshots to be kept, but got: %v", names)
+		}
+	})
+}
+
+func TestProvider_SnapshotHolds(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	path := dataset + "@snapshot"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: path}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("held snapshot should not be destroyed", func(t *testing.T) {
+		for _, tag := range []string{"backup", "retention"} {
+			if err := nsp.HoldSnapshot(ctx, path, tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		holds, err := nsp.GetSnapshotHolds(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(holds) != 2 || holds[0].Tag != "backup" || holds[1].Tag != "retention" {
+			t.Errorf("expected 'backup' and 'retention' holds, but got: %+v", holds)
+		} else if holds[0].CreationTime.IsZero() {
+			t.Errorf("expected hold creation time to be set, but got: %+v", holds[0])
+		}
+
+		if err := nsp.DestroySnapshot(ctx, path); !errors.Is(err, ns.ErrBusy) {
+			t.Errorf("expected EBUSY error, but got: %v", err)
+		}
+	})
+
+	t.Run("snapshot should be destroyed when all holds are released", func(t *testing.T) {
+		if err := nsp.ReleaseSnapshot(ctx, path, "unknown"); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error, but got: %v", err)
+		}
+
+		for _, tag := range []string{"backup", "retention"} {
+			if err := nsp.ReleaseSnapshot(ctx, path, tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := nsp.DestroySnapshot(ctx, path); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestProvider_SnapshotProperties(t *testing.T) {
+	ctx := context.Background()
+	dataset := "testPool/testDataset"
+	path := dataset + "@snapshot"
+
+	nsp, server := newTestProvider(t, nstest.ServerArgs{Filesystems: []string{dataset}})
+	defer server.Close()
+
+	err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{
+		Path:           path,
+		UserProperties: map[string]string{"csi:owner": "test"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetSnapshot() should return space accounting and user properties", func(t *testing.T) {
+		snapshot, err := nsp.GetSnapshot(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		} else if snapshot.BytesReferenced <= 0 {
+			t.Errorf("expected bytesReferenced to be set, but got: %+v", snapshot)
+		} else if snapshot.UserProperties["csi:owner"] != "test" {
+			t.Errorf("expected user property 'csi:owner', but got: %+v", snapshot.UserProperties)
+		}
+	})
+
+	t.Run("UpdateSnapshot() should merge user properties", func(t *testing.T) {
+		err := nsp.UpdateSnapshot(ctx, path, ns.UpdateSnapshotParams{
+			UserProperties: map[string]string{"csi:retention": "daily"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		snapshots, err := nsp.GetSnapshots(ctx, dataset, false)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(snapshots) != 1 {
+			t.Fatalf("expected 1 snapshot, but got: %+v", snapshots)
+		}
+		if props := snapshots[0].UserProperties; props["csi:owner"] != "test" || props["csi:retention"] != "daily" {
+			t.Errorf("expected merged user properties, but got: %+v", props)
+		}
+
+		err = nsp.UpdateSnapshot(ctx, path, ns.UpdateSnapshotParams{UserProperties: map[string]string{"bad": "1"}})
+		if !errors.Is(err, ns.ErrBadArg) {
+			t.Errorf("expected EBADARG error, but got: %v", err)
+		}
+	})
+
+	t.Run("RenameSnapshot() should keep clones linked", func(t *testing.T) {
+		clone := dataset + "/clone"
+		if err := nsp.CloneSnapshot(ctx, path, ns.CloneSnapshotParams{TargetPath: clone}); err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.CreateSnapshot(ctx, ns.CreateSnapshotParams{Path: dataset + "@other"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := nsp.RenameSnapshot(ctx, path, "other"); !errors.Is(err, ns.ErrAlreadyExist) {
+			t.Errorf("expected EEXIST error, but got: %v", err)
+		}
+		if err := nsp.RenameSnapshot(ctx, path, "fs@name"); !errors.Is(err, ns.ErrBadArg) {
+			t.Errorf("expected EBADARG error, but got: %v", err)
+		}
+
+		newPath := dataset + "@renamed"
+		if err := nsp.RenameSnapshot(ctx, path, "renamed"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nsp.GetSnapshot(ctx, path); !errors.Is(err, ns.ErrNotExist) {
+			t.Errorf("expected ENOENT error for old path, but got: %v", err)
+		}
+		snapshot, err := nsp.GetSnapshot(ctx, newPath)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(snapshot.Clones) != 1 || snapshot.Clones[0] != clone {
+			t.Errorf("expected renamed snapshot to have clone '%s', but got: %v", clone, snapshot.Clones)
+		}
+
+		// destroyed clone should be unlinked from the renamed snapshot, so the snapshot can be destroyed
+		err = nsp.DestroyFilesystem(ctx, clone, ns.DestroyFilesystemParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := nsp.DestroySnapshot(ctx, newPath); err != nil {
+			t.Error(err)
+		}
+	})
+}
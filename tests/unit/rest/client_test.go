@@ -1,8 +1,16 @@
 package rest_test
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
 
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
 	"github.com/Nexenta/go-nexentastor/pkg/rest"
 )
 
@@ -18,3 +26,35 @@ func ExampleClient_BuildURI() {
 	// /root?a=1
 	// /root?a=1&b=2
 }
+
+func TestClient_SendContext(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	l := logrus.New().WithField("test", "rest")
+	l.Logger.SetLevel(logrus.PanicLevel)
+	client := rest.NewClient(rest.ClientArgs{Address: server.URL, Log: logger.NewLogrus(l)})
+
+	t.Run("SendContext() should return an error when context deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		startTime := time.Now()
+		_, _, err := client.SendContext(ctx, http.MethodGet, "slow", nil)
+		if err == nil {
+			t.Fatal("expected an error, but got nil")
+		} else if ctx.Err() != context.DeadlineExceeded {
+			t.Errorf("expected context to be expired, but got: %v", ctx.Err())
+		} else if time.Since(startTime) > 5*time.Second {
+			t.Errorf("request wasn't canceled in time: %s", time.Since(startTime))
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
+)
+
+func TestClient_SendContextRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token":"response-token-value","data":[{"name":"i1","customSecret":"custom-value"}]}`))
+	}))
+	defer server.Close()
+
+	logs := &bytes.Buffer{}
+	logrusLogger := logrus.New()
+	logrusLogger.SetOutput(logs)
+	logrusLogger.SetLevel(logrus.DebugLevel)
+
+	client := rest.NewClient(rest.ClientArgs{
+		Address:         server.URL,
+		Log:             logger.NewLogrus(logrusLogger.WithField("test", "rest")),
+		SensitiveFields: []string{"customSecret"},
+	})
+
+	data := map[string]interface{}{
+		"username": "admin",
+		"Password": "password-value",
+		"initiators": []map[string]string{
+			{"name": "i1", "chapSecret": "chap-secret-value"},
+		},
+	}
+	if _, _, err := client.SendContext(context.Background(), http.MethodPost, "auth/login", data); err != nil {
+		t.Fatal(err)
+	}
+
+	output := logs.String()
+	for _, secret := range []string{"password-value", "chap-secret-value", "response-token-value", "custom-value"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("logs contain sensitive value '%s':\n%s", secret, output)
+		}
+	}
+	for _, value := range []string{"admin", rest.RedactedValue} {
+		if !strings.Contains(output, value) {
+			t.Errorf("logs should contain '%s':\n%s", value, output)
+		}
+	}
+}
+
+func TestDefaultSensitiveFields(t *testing.T) {
+	fields := rest.DefaultSensitiveFields()
+	if len(fields) == 0 {
+		t.Fatal("expected default sensitive fields, but got none")
+	}
+
+	fields[0] = "changed"
+	if rest.DefaultSensitiveFields()[0] == "changed" {
+		t.Error("default sensitive fields should not be changed through returned slice")
+	}
+}
@@ -0,0 +1,116 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Nexenta/go-nexentastor/pkg/logger"
+	"github.com/Nexenta/go-nexentastor/pkg/rest"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &rest.RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+	}
+
+	for attempt, expected := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+	} {
+		if backoff := policy.Backoff(attempt); backoff != expected {
+			t.Errorf("attempt %d: expected backoff %s, but got %s", attempt, expected, backoff)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if backoff := policy.Backoff(2); backoff < time.Second || backoff > 3*time.Second {
+			t.Fatalf("backoff with jitter is out of range: %s", backoff)
+		}
+	}
+}
+
+func TestRetryPolicy_Attempts(t *testing.T) {
+	var nilPolicy *rest.RetryPolicy
+	if attempts := nilPolicy.Attempts(http.MethodGet); attempts != 1 {
+		t.Errorf("nil policy should make 1 attempt, but got %d", attempts)
+	}
+
+	policy := &rest.RetryPolicy{MaxAttempts: 3}
+	if attempts := policy.Attempts(http.MethodDelete); attempts != 3 {
+		t.Errorf("DELETE should be retried, expected 3 attempts, but got %d", attempts)
+	}
+	if attempts := policy.Attempts(http.MethodPost); attempts != 1 {
+		t.Errorf("POST should not be retried by default, but got %d attempts", attempts)
+	}
+
+	policy.RetryNonIdempotent = true
+	if attempts := policy.Attempts(http.MethodPost); attempts != 3 {
+		t.Errorf("POST should be retried with RetryNonIdempotent, but got %d attempts", attempts)
+	}
+}
+
+func TestClient_SendContextRetry(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requestCount, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"code":"EBUSY","message":"dataset is busy"}`))
+		default:
+			w.Write([]byte(`{"data":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	l := logrus.New().WithField("test", "rest")
+	l.Logger.SetLevel(logrus.PanicLevel)
+
+	newClient := func() rest.ClientInterface {
+		atomic.StoreInt32(&requestCount, 0)
+		return rest.NewClient(rest.ClientArgs{
+			Address: server.URL,
+			Log:     logger.NewLogrus(l),
+			RetryPolicy: &rest.RetryPolicy{
+				MaxAttempts:          3,
+				InitialBackoff:       time.Millisecond,
+				RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+				RetryableErrorCodes:  []string{"EBUSY"},
+			},
+		})
+	}
+
+	t.Run("idempotent request should be retried", func(t *testing.T) {
+		statusCode, _, err := newClient().SendContext(context.Background(), http.MethodGet, "items", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if statusCode != http.StatusOK {
+			t.Errorf("expected %d status code, but got %d", http.StatusOK, statusCode)
+		} else if count := atomic.LoadInt32(&requestCount); count != 3 {
+			t.Errorf("expected 3 attempts, but got %d", count)
+		}
+	})
+
+	t.Run("non-idempotent request should not be retried", func(t *testing.T) {
+		statusCode, _, err := newClient().SendContext(context.Background(), http.MethodPost, "items", nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if statusCode != http.StatusServiceUnavailable {
+			t.Errorf("expected %d status code, but got %d", http.StatusServiceUnavailable, statusCode)
+		} else if count := atomic.LoadInt32(&requestCount); count != 1 {
+			t.Errorf("expected 1 attempt, but got %d", count)
+		}
+	})
+}